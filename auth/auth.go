@@ -0,0 +1,218 @@
+/*
+Package auth answers the NTLM and Negotiate (SPNEGO) challenge-response
+authentication of intranet services.
+
+NTLM is implemented in this package with NTLMv2 responses.  Kerberos
+tokens are provided by a pluggable Negotiator, so that no Kerberos library
+is imposed.  Both flows authenticate the connection rather than the request,
+so the Next transport must keep connections alive.  Retry Transports should
+wrap the auth Transport, so that every retried attempt completes its own
+handshake.
+*/
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	now      = time.Now
+	randRead = rand.Read
+)
+
+// Credentials of a domain account.
+type Credentials struct {
+	Domain      string
+	Username    string
+	Password    string
+	Workstation string
+}
+
+// CredentialSource provides the Credentials to authenticate a request with.
+type CredentialSource func(*http.Request) (Credentials, error)
+
+// Static returns a CredentialSource for a single account.  A username like
+// "DOMAIN\user" or "user@DOMAIN" without a domain is split into both.
+func Static(c Credentials) CredentialSource {
+	if c.Domain == "" {
+		if i := strings.IndexByte(c.Username, '\\'); i >= 0 {
+			c.Domain, c.Username = c.Username[:i], c.Username[i+1:]
+		} else if i := strings.LastIndexByte(c.Username, '@'); i >= 0 {
+			c.Username, c.Domain = c.Username[:i], c.Username[i+1:]
+		}
+	}
+	return func(*http.Request) (Credentials, error) {
+		return c, nil
+	}
+}
+
+// Negotiator provides the initial SPNEGO token for a service principal like
+// "HTTP/intranet.example.com", typically from a Kerberos ticket cache or
+// keytab.
+type Negotiator interface {
+	Token(ctx context.Context, spn string) ([]byte, error)
+}
+
+// NegotiatorFunc adapts a function to a Negotiator.
+type NegotiatorFunc func(ctx context.Context, spn string) ([]byte, error)
+
+// Token implements Negotiator.
+func (f NegotiatorFunc) Token(ctx context.Context, spn string) ([]byte, error) {
+	return f(ctx, spn)
+}
+
+// ErrUnreplayable is returned when a request needs to be sent again to
+// authenticate but its body cannot be rewound.
+var ErrUnreplayable = errors.New("auth: request body cannot be replayed for authentication")
+
+// Transport is an http.RoundTripper answering 401 responses challenging
+// with the Negotiate or NTLM schemes.  Negotiate challenges are answered
+// with a Kerberos token from the Negotiator, or with NTLM when there is no
+// Negotiator.  Responses challenging with other schemes are returned as is.
+type Transport struct {
+	// Credentials authenticate NTLM handshakes.  If nil, NTLM challenges
+	// are not answered.
+	Credentials CredentialSource
+
+	// Negotiator provides Kerberos tokens for Negotiate challenges.
+	Negotiator Negotiator
+
+	// Next is the http.RoundTripper to which requests are forwarded.  If Next
+	// is nil, http.DefaultTransport is used.
+	Next http.RoundTripper
+}
+
+// RoundTrip implements the RoundTripper interface.
+func (t Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	resp, err := next.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	schemes := challenges(resp.Header)
+
+	switch {
+	case t.Negotiator != nil && schemes["negotiate"] != nil:
+		return t.kerberos(next, req, resp)
+	case t.Credentials != nil && schemes["negotiate"] != nil:
+		return t.ntlm(next, req, resp, "Negotiate")
+	case t.Credentials != nil && schemes["ntlm"] != nil:
+		return t.ntlm(next, req, resp, "NTLM")
+	}
+
+	return resp, nil
+}
+
+func (t Transport) kerberos(next http.RoundTripper, req *http.Request, challenge *http.Response) (*http.Response, error) {
+	host := req.URL.Hostname()
+	if h, _, err := net.SplitHostPort(req.Host); err == nil {
+		host = h
+	} else if req.Host != "" {
+		host = req.Host
+	}
+
+	token, err := t.Negotiator.Token(req.Context(), "HTTP/"+host)
+	if err != nil {
+		return challenge, nil
+	}
+
+	return t.send(next, req, challenge, "Negotiate "+base64.StdEncoding.EncodeToString(token))
+}
+
+func (t Transport) ntlm(next http.RoundTripper, req *http.Request, challenge *http.Response, scheme string) (*http.Response, error) {
+	creds, err := t.Credentials(req)
+	if err != nil {
+		return challenge, nil
+	}
+
+	resp, err := t.send(next, req, challenge, scheme+" "+base64.StdEncoding.EncodeToString(ntlmNegotiate()))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	token := challenges(resp.Header)[strings.ToLower(scheme)]
+	msg, err := base64.StdEncoding.DecodeString(strings.Join(token, ""))
+	if err != nil || len(token) == 0 {
+		return resp, nil
+	}
+
+	ch, err := parseChallenge(msg)
+	if err != nil {
+		drain(resp)
+		return nil, err
+	}
+
+	clientChallenge := make([]byte, 8)
+	if _, err := randRead(clientChallenge); err != nil {
+		drain(resp)
+		return nil, err
+	}
+
+	auth := ntlmAuthenticate(creds, ch, clientChallenge, now())
+	return t.send(next, req, resp, scheme+" "+base64.StdEncoding.EncodeToString(auth))
+}
+
+// send drains the previous response and sends req again with the
+// Authorization header.
+func (t Transport) send(next http.RoundTripper, req *http.Request, prev *http.Response, authorization string) (*http.Response, error) {
+	out := req.Clone(req.Context())
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			drain(prev)
+			return nil, ErrUnreplayable
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			drain(prev)
+			return nil, err
+		}
+		out.Body = body
+	}
+	out.Header.Set("Authorization", authorization)
+
+	// Drain to keep the authenticated connection alive
+	drain(prev)
+
+	return next.RoundTrip(out)
+}
+
+func drain(resp *http.Response) {
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+}
+
+// challenges returns the WWW-Authenticate challenges by lower case scheme
+// with their parameters, which are empty for schemes without parameters.
+func challenges(h http.Header) map[string][]string {
+	cs := make(map[string][]string)
+	for _, v := range h.Values("WWW-Authenticate") {
+		for _, c := range strings.Split(v, ",") {
+			c = strings.TrimSpace(c)
+			if c == "" {
+				continue
+			}
+			scheme, param, _ := strings.Cut(c, " ")
+			scheme = strings.ToLower(scheme)
+			if cs[scheme] == nil {
+				cs[scheme] = []string{}
+			}
+			if param = strings.TrimSpace(param); param != "" {
+				cs[scheme] = append(cs[scheme], param)
+			}
+		}
+	}
+	return cs
+}
@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// ntlmServer authenticates exampleCredentials with exampleChallenge.
+func ntlmServer(t *testing.T, scheme string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if body, _ := io.ReadAll(r.Body); string(body) != "payload" {
+			t.Errorf("expected the body on every request, got %q", body)
+		}
+
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), scheme+" ")
+		msg, _ := base64.StdEncoding.DecodeString(token)
+
+		switch {
+		case len(msg) < 12:
+			w.Header().Set("WWW-Authenticate", scheme)
+			w.WriteHeader(http.StatusUnauthorized)
+
+		case binary.LittleEndian.Uint32(msg[8:]) == 1:
+			ch := make([]byte, 48)
+			copy(ch, ntlmSignature)
+			binary.LittleEndian.PutUint32(ch[8:], 2)
+			binary.LittleEndian.PutUint32(ch[20:], exampleChallenge.flags)
+			copy(ch[24:], exampleChallenge.challenge)
+			binary.LittleEndian.PutUint16(ch[40:], uint16(len(exampleChallenge.targetInfo)))
+			binary.LittleEndian.PutUint32(ch[44:], 48)
+			ch = append(ch, exampleChallenge.targetInfo...)
+
+			w.Header().Set("WWW-Authenticate", scheme+" "+base64.StdEncoding.EncodeToString(ch))
+			w.WriteHeader(http.StatusUnauthorized)
+
+		case binary.LittleEndian.Uint32(msg[8:]) == 3:
+			nt, _ := field(msg, 20)
+			key := ntowfv2(exampleCredentials)
+			if !bytes.Equal(nt[:16], hmacMD5(key, exampleChallenge.challenge, nt[16:])) {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			io.WriteString(w, "welcome")
+
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+}
+
+func TestNTLMHandshake(t *testing.T) {
+	for _, scheme := range []string{"NTLM", "Negotiate"} {
+		t.Run(scheme, func(t *testing.T) {
+			s := httptest.NewServer(ntlmServer(t, scheme))
+			defer s.Close()
+
+			client := &http.Client{Transport: Transport{
+				Credentials: Static(Credentials{Username: `Domain\User`, Password: "Password"}),
+			}}
+
+			resp, err := client.Post(s.URL, "text/plain", strings.NewReader("payload"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if want, got := 200, resp.StatusCode; want != got {
+				t.Fatalf("expected %d after the handshake, got %d", want, got)
+			}
+		})
+	}
+}
+
+func TestNTLMWrongPassword(t *testing.T) {
+	s := httptest.NewServer(ntlmServer(t, "NTLM"))
+	defer s.Close()
+
+	client := &http.Client{Transport: Transport{
+		Credentials: Static(Credentials{Domain: "Domain", Username: "User", Password: "wrong"}),
+	}}
+
+	resp, err := client.Post(s.URL, "text/plain", strings.NewReader("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if want, got := http.StatusUnauthorized, resp.StatusCode; want != got {
+		t.Fatalf("expected %d for wrong credentials, got %d", want, got)
+	}
+}
+
+func TestNegotiateKerberos(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Negotiate "+base64.StdEncoding.EncodeToString([]byte("ticket")) {
+			w.Header().Set("WWW-Authenticate", "Negotiate")
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer s.Close()
+
+	var spn string
+	client := &http.Client{Transport: Transport{
+		Negotiator: NegotiatorFunc(func(ctx context.Context, principal string) ([]byte, error) {
+			spn = principal
+			return []byte("ticket"), nil
+		}),
+	}}
+
+	resp, err := client.Get(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if want, got := 200, resp.StatusCode; want != got {
+		t.Fatalf("expected %d after negotiating, got %d", want, got)
+	}
+	if want, got := "HTTP/127.0.0.1", spn; want != got {
+		t.Fatalf("expected the service principal %q, got %q", want, got)
+	}
+}
+
+func TestUnreplayableBody(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("WWW-Authenticate", "NTLM")
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer s.Close()
+
+	req, _ := http.NewRequest("POST", s.URL, io.NopCloser(strings.NewReader("payload")))
+	trans := Transport{Credentials: Static(exampleCredentials)}
+
+	if _, err := trans.RoundTrip(req); err != ErrUnreplayable {
+		t.Fatalf("expected ErrUnreplayable, got: %v", err)
+	}
+}
+
+func TestStaticSplitsUsername(t *testing.T) {
+	for username, want := range map[string]Credentials{
+		`CORP\alice`:     {Domain: "CORP", Username: "alice"},
+		"alice@corp.com": {Domain: "corp.com", Username: "alice"},
+		"alice":          {Username: "alice"},
+	} {
+		got, _ := Static(Credentials{Username: username})(nil)
+		if want != got {
+			t.Errorf("%s: want %+v, got %+v", username, want, got)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// NTLM negotiate flags of MS-NLMP section 2.2.2.5.
+const (
+	ntlmUnicode          = 0x00000001
+	ntlmOEM              = 0x00000002
+	ntlmRequestTarget    = 0x00000004
+	ntlmNTLM             = 0x00000200
+	ntlmAlwaysSign       = 0x00008000
+	ntlmExtendedSecurity = 0x00080000
+	ntlmTargetInfo       = 0x00800000
+	ntlm128              = 0x20000000
+	ntlm56               = 0x80000000
+)
+
+const ntlmNegotiateFlags = ntlmUnicode | ntlmOEM | ntlmRequestTarget | ntlmNTLM |
+	ntlmAlwaysSign | ntlmExtendedSecurity | ntlmTargetInfo | ntlm128 | ntlm56
+
+var ntlmSignature = []byte("NTLMSSP\x00")
+
+// errChallenge is returned for malformed NTLM challenge messages.
+var errChallenge = errors.New("auth: malformed NTLM challenge")
+
+// ntlmNegotiate returns the NEGOTIATE_MESSAGE opening the handshake.
+func ntlmNegotiate() []byte {
+	msg := make([]byte, 32)
+	copy(msg, ntlmSignature)
+	binary.LittleEndian.PutUint32(msg[8:], 1)
+	binary.LittleEndian.PutUint32(msg[12:], ntlmNegotiateFlags)
+	return msg
+}
+
+// ntlmChallenge holds the fields of a CHALLENGE_MESSAGE used to respond.
+type ntlmChallenge struct {
+	flags      uint32
+	challenge  []byte
+	targetInfo []byte
+}
+
+func parseChallenge(msg []byte) (ntlmChallenge, error) {
+	if len(msg) < 32 || !bytes.Equal(msg[:8], ntlmSignature) || binary.LittleEndian.Uint32(msg[8:]) != 2 {
+		return ntlmChallenge{}, errChallenge
+	}
+
+	c := ntlmChallenge{
+		flags:     binary.LittleEndian.Uint32(msg[20:]),
+		challenge: msg[24:32],
+	}
+
+	if len(msg) >= 48 {
+		info, ok := field(msg, 40)
+		if !ok {
+			return ntlmChallenge{}, errChallenge
+		}
+		c.targetInfo = info
+	}
+
+	return c, nil
+}
+
+// field returns the payload referenced by the field at offset of msg.
+func field(msg []byte, offset int) ([]byte, bool) {
+	length := int(binary.LittleEndian.Uint16(msg[offset:]))
+	start := int(binary.LittleEndian.Uint32(msg[offset+4:]))
+	if start+length > len(msg) || start < 0 {
+		return nil, false
+	}
+	return msg[start : start+length], true
+}
+
+// ntowfv2 derives the NTLMv2 response key from the credentials.
+func ntowfv2(c Credentials) []byte {
+	hash := md4(unicode(c.Password))
+	mac := hmac.New(md5.New, hash[:])
+	mac.Write(unicode(strings.ToUpper(c.Username) + c.Domain))
+	return mac.Sum(nil)
+}
+
+// ntlmAuthenticate returns the AUTHENTICATE_MESSAGE answering the challenge
+// with NTLMv2 responses from the client challenge at the time.
+func ntlmAuthenticate(c Credentials, ch ntlmChallenge, clientChallenge []byte, at time.Time) []byte {
+	key := ntowfv2(c)
+
+	// NTLMv2_CLIENT_CHALLENGE of MS-NLMP section 2.2.2.7
+	blob := []byte{1, 1, 0, 0, 0, 0, 0, 0}
+	blob = binary.LittleEndian.AppendUint64(blob, filetime(at))
+	blob = append(blob, clientChallenge...)
+	blob = append(blob, 0, 0, 0, 0)
+	blob = append(blob, ch.targetInfo...)
+	blob = append(blob, 0, 0, 0, 0)
+
+	ntProof := hmacMD5(key, ch.challenge, blob)
+	ntResponse := append(ntProof, blob...)
+	lmResponse := append(hmacMD5(key, ch.challenge, clientChallenge), clientChallenge...)
+
+	encode := unicode
+	flags := uint32(ntlmNegotiateFlags)
+	if ch.flags&ntlmUnicode == 0 {
+		encode = func(s string) []byte { return []byte(s) }
+		flags &^= ntlmUnicode
+	}
+
+	payloads := [][]byte{
+		lmResponse,
+		ntResponse,
+		encode(c.Domain),
+		encode(c.Username),
+		encode(c.Workstation),
+		nil, // no session key exchange
+	}
+
+	const header = 64
+	msg := make([]byte, header)
+	copy(msg, ntlmSignature)
+	binary.LittleEndian.PutUint32(msg[8:], 3)
+
+	for i, p := range payloads {
+		offset := 12 + i*8
+		binary.LittleEndian.PutUint16(msg[offset:], uint16(len(p)))
+		binary.LittleEndian.PutUint16(msg[offset+2:], uint16(len(p)))
+		binary.LittleEndian.PutUint32(msg[offset+4:], uint32(len(msg)))
+		msg = append(msg, p...)
+	}
+	binary.LittleEndian.PutUint32(msg[60:], flags)
+
+	return msg
+}
+
+func hmacMD5(key []byte, data ...[]byte) []byte {
+	mac := hmac.New(md5.New, key)
+	for _, d := range data {
+		mac.Write(d)
+	}
+	return mac.Sum(nil)
+}
+
+// unicode encodes s as UTF-16LE.
+func unicode(s string) []byte {
+	units := utf16.Encode([]rune(s))
+	b := make([]byte, 2*len(units))
+	for i, u := range units {
+		binary.LittleEndian.PutUint16(b[2*i:], u)
+	}
+	return b
+}
+
+// filetime returns t in 100ns intervals since January 1, 1601 UTC.
+func filetime(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint64(t.UnixNano()/100) + 116444736000000000
+}
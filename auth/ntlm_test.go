@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+	"time"
+)
+
+// Example of MS-NLMP section 4.2.4
+var (
+	exampleCredentials = Credentials{Domain: "Domain", Username: "User", Password: "Password", Workstation: "COMPUTER"}
+	exampleChallenge   = ntlmChallenge{
+		flags:     ntlmUnicode | ntlmTargetInfo,
+		challenge: unhex("0123456789abcdef"),
+		targetInfo: unhex("02000c0044006f006d00610069006e00" +
+			"01000c00530065007200760065007200" +
+			"00000000"),
+	}
+	exampleClientChallenge = unhex("aaaaaaaaaaaaaaaa")
+)
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestNTOWFv2(t *testing.T) {
+	if want, got := "0c868a403bfd7a93a3001ef22ef02e3f", hex.EncodeToString(ntowfv2(exampleCredentials)); want != got {
+		t.Fatalf("want %s, got %s", want, got)
+	}
+}
+
+func TestNTLMAuthenticateResponses(t *testing.T) {
+	msg := ntlmAuthenticate(exampleCredentials, exampleChallenge, exampleClientChallenge, time.Time{})
+
+	if !bytes.Equal(msg[:8], ntlmSignature) || binary.LittleEndian.Uint32(msg[8:]) != 3 {
+		t.Fatalf("expected an AUTHENTICATE_MESSAGE, got %x", msg[:12])
+	}
+
+	lm, _ := field(msg, 12)
+	if want, got := "86c35097ac9cec102554764a57cccc19aaaaaaaaaaaaaaaa", hex.EncodeToString(lm); want != got {
+		t.Errorf("expected LMv2 response %s, got %s", want, got)
+	}
+
+	nt, _ := field(msg, 20)
+	if want, got := "68cd0ab851e51c96aabc927bebef6a1c", hex.EncodeToString(nt[:16]); want != got {
+		t.Errorf("expected NTProofStr %s, got %s", want, got)
+	}
+
+	user, _ := field(msg, 36)
+	if want, got := unicode("User"), user; !bytes.Equal(want, got) {
+		t.Errorf("expected user %x, got %x", want, got)
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	msg := make([]byte, 48)
+	copy(msg, ntlmSignature)
+	binary.LittleEndian.PutUint32(msg[8:], 2)
+	binary.LittleEndian.PutUint32(msg[20:], ntlmUnicode|ntlmTargetInfo)
+	copy(msg[24:], exampleChallenge.challenge)
+	binary.LittleEndian.PutUint16(msg[40:], uint16(len(exampleChallenge.targetInfo)))
+	binary.LittleEndian.PutUint32(msg[44:], 48)
+	msg = append(msg, exampleChallenge.targetInfo...)
+
+	ch, err := parseChallenge(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ch.challenge, exampleChallenge.challenge) || !bytes.Equal(ch.targetInfo, exampleChallenge.targetInfo) {
+		t.Fatalf("expected the challenge and target info to be parsed, got %+v", ch)
+	}
+
+	binary.LittleEndian.PutUint32(msg[44:], 1000)
+	if _, err := parseChallenge(msg); err != errChallenge {
+		t.Fatalf("expected out of bounds fields to be rejected, got: %v", err)
+	}
+}
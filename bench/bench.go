@@ -0,0 +1,238 @@
+/*
+Package bench drives load through a transport chain against a target and
+reports the latencies, statuses and the behavior of the retries and circuit
+breakers in the chain, to validate configurations before production.
+*/
+package bench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/streadway/handy/breaker"
+	"github.com/streadway/handy/ratelimit"
+	"github.com/streadway/handy/retry"
+)
+
+// Config parameterizes Run.
+type Config struct {
+	// Request constructs each request sent.
+	Request func(context.Context) (*http.Request, error)
+
+	// Transport is the chain under test.  If nil, http.DefaultTransport is
+	// used.
+	Transport http.RoundTripper
+
+	// Rate limits the requests started per second.  If zero, requests are
+	// started as fast as the Concurrency allows.
+	Rate float64
+
+	// Concurrency is the number of requests in flight at most, 1 when zero.
+	Concurrency int
+
+	// Duration stops starting requests after this time.  If zero, Run stops
+	// after Requests or when its context is done.
+	Duration time.Duration
+
+	// Requests stops after this number of requests.  If zero, Run stops
+	// after Duration or when its context is done.
+	Requests int
+}
+
+// Get returns a Config.Request constructing GET requests to url.
+func Get(url string) func(context.Context) (*http.Request, error) {
+	return func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, "GET", url, nil)
+	}
+}
+
+// Report summarizes a Run.
+type Report struct {
+	// Requests is the number of requests completed.
+	Requests int
+
+	// Errors is the number of requests failing without a response.
+	Errors int
+
+	// Statuses counts the responses by status code.
+	Statuses map[int]int
+
+	// Attempts is the number of round trips issued by retry Transports.
+	Attempts int
+
+	// Retried is the number of requests taking more than one attempt.
+	Retried int
+
+	// CircuitOpen is the number of requests refused by an open circuit
+	// breaker.
+	CircuitOpen int
+
+	// Elapsed is the duration of the Run.
+	Elapsed time.Duration
+
+	// Latencies of the completed requests, sorted.
+	Latencies []time.Duration
+}
+
+// Rate returns the completed requests per second.
+func (r Report) Rate() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Requests) / r.Elapsed.Seconds()
+}
+
+// Percentile returns the latency below which p percent of the requests
+// completed.
+func (r Report) Percentile(p float64) time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+	i := int(p / 100 * float64(len(r.Latencies)))
+	if i >= len(r.Latencies) {
+		i = len(r.Latencies) - 1
+	}
+	if i < 0 {
+		i = 0
+	}
+	return r.Latencies[i]
+}
+
+// String formats the report as a summary.
+func (r Report) String() string {
+	codes := make([]int, 0, len(r.Statuses))
+	for code := range r.Statuses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+
+	statuses := make([]string, len(codes))
+	for i, code := range codes {
+		statuses[i] = fmt.Sprintf("%d=%d", code, r.Statuses[code])
+	}
+
+	return fmt.Sprintf(
+		"requests=%d rate=%.1f/s errors=%d statuses=[%s] attempts=%d retried=%d circuit_open=%d p50=%s p90=%s p99=%s max=%s",
+		r.Requests, r.Rate(), r.Errors, strings.Join(statuses, " "),
+		r.Attempts, r.Retried, r.CircuitOpen,
+		r.Percentile(50), r.Percentile(90), r.Percentile(99), r.Percentile(100),
+	)
+}
+
+// Run sends requests until the Duration passed, the Requests are done or ctx
+// is done, and reports the results.  Response bodies are drained.
+func Run(ctx context.Context, cfg Config) (Report, error) {
+	if cfg.Request == nil {
+		return Report{}, errors.New("bench: no request configured")
+	}
+	if cfg.Duration <= 0 && cfg.Requests <= 0 && ctx.Done() == nil {
+		return Report{}, errors.New("bench: no duration, number of requests or cancellable context configured")
+	}
+	if cfg.Transport == nil {
+		cfg.Transport = http.DefaultTransport
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+
+	if cfg.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Duration)
+		defer cancel()
+	}
+
+	var pace *ratelimit.Bucket
+	if cfg.Rate > 0 {
+		pace = ratelimit.NewBucket(cfg.Rate, 1)
+	}
+
+	var (
+		mu      sync.Mutex
+		report  = Report{Statuses: make(map[int]int)}
+		wg      sync.WaitGroup
+		slots   = make(chan struct{}, cfg.Concurrency)
+		begin   = time.Now()
+		started int
+		err     error
+	)
+
+	for cfg.Requests <= 0 || started < cfg.Requests {
+		if pace != nil {
+			if pace.Wait(ctx) != nil {
+				break
+			}
+		}
+
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		var req *http.Request
+		if req, err = cfg.Request(ctx); err != nil {
+			break
+		}
+		started++
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			var counter retry.Counter
+			req = req.WithContext(retry.WithCounter(req.Context(), &counter))
+
+			start := time.Now()
+			resp, err := cfg.Transport.RoundTrip(req)
+			if resp != nil {
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+			}
+			latency := time.Since(start)
+
+			// Requests cut short by the end of the run are not reported
+			if err != nil && ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			report.Requests++
+			report.Latencies = append(report.Latencies, latency)
+			report.Attempts += int(counter.Attempts())
+			if counter.Attempts() > 1 {
+				report.Retried++
+			}
+
+			switch {
+			case resp != nil:
+				report.Statuses[resp.StatusCode]++
+			case errors.Is(err, breaker.ErrCircuitOpen):
+				report.CircuitOpen++
+				report.Errors++
+			default:
+				report.Errors++
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	report.Elapsed = time.Since(begin)
+	sort.Slice(report.Latencies, func(i, j int) bool {
+		return report.Latencies[i] < report.Latencies[j]
+	})
+
+	return report, err
+}
@@ -0,0 +1,95 @@
+package bench
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/streadway/handy/breaker"
+	"github.com/streadway/handy/retry"
+)
+
+type closed bool
+
+func (c closed) Allow() bool         { return bool(c) }
+func (closed) Success(time.Duration) {}
+func (closed) Failure(time.Duration) {}
+
+func TestRunReportsRetries(t *testing.T) {
+	var served int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&served, 1)%2 == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer s.Close()
+
+	report, err := Run(context.Background(), Config{
+		Request:   Get(s.URL),
+		Transport: retry.New(retry.WithConstantBackoff(0)),
+		Requests:  20,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := 20, report.Requests; want != got {
+		t.Fatalf("expected %d requests, got %d", want, got)
+	}
+	if want, got := 20, report.Statuses[200]; want != got {
+		t.Fatalf("expected %d successful requests, got %d: %s", want, got, report)
+	}
+	if report.Retried == 0 || report.Attempts != int(atomic.LoadInt32(&served)) {
+		t.Fatalf("expected the retries to be reported, got: %s", report)
+	}
+	if report.Percentile(50) <= 0 || report.Percentile(50) > report.Percentile(100) {
+		t.Fatalf("expected ordered latency percentiles, got: %s", report)
+	}
+}
+
+func TestRunReportsOpenCircuits(t *testing.T) {
+	report, err := Run(context.Background(), Config{
+		Request:   Get("http://example.invalid/"),
+		Transport: breaker.Transport(closed(false), breaker.DefaultResponseValidator, http.DefaultTransport),
+		Requests:  5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := 5, report.CircuitOpen; want != got {
+		t.Fatalf("expected %d requests refused by the breaker, got %d", want, got)
+	}
+	if !strings.Contains(report.String(), "circuit_open=5") {
+		t.Fatalf("expected the summary to report the open circuit, got: %s", report)
+	}
+}
+
+func TestRunPacesRate(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer s.Close()
+
+	report, err := Run(context.Background(), Config{
+		Request:     Get(s.URL),
+		Rate:        100,
+		Concurrency: 8,
+		Duration:    100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Requests < 1 || report.Requests > 15 {
+		t.Fatalf("expected about 10 requests at 100/s for 100ms, got %d", report.Requests)
+	}
+}
+
+func TestRunRequiresEnd(t *testing.T) {
+	if _, err := Run(context.Background(), Config{Request: Get("http://example/")}); err == nil {
+		t.Fatalf("expected an unbounded run to be refused")
+	}
+}
@@ -118,6 +118,11 @@ func (b *breaker) WithCooldown(c time.Duration) {
 	b.config.Cooldown = c
 }
 
+// Cooldown returns the time the circuit remains open
+func (b breaker) Cooldown() time.Duration {
+	return b.config.Cooldown
+}
+
 func (b breaker) shouldOpen(m *metric) bool {
 	s := m.Summary()
 	return s.total > b.config.MinObservations && s.rate > b.config.FailureRatio
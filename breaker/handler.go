@@ -3,6 +3,8 @@ package breaker
 import (
 	"net/http"
 	"time"
+
+	"github.com/streadway/handy/problem"
 )
 
 // StatusCodeValidator is a function that determines if a status code written
@@ -21,7 +23,7 @@ func Middleware(breaker Breaker, validator StatusCodeValidator) func(http.Handle
 // StatusCodeValidator. Responses written by the next http.Handler whose
 // status codes fail the validator signal failures to the breaker. Once the
 // breaker opens, incoming requests are terminated before being forwarded with
-// HTTP 503.
+// HTTP 503 written by problem.Write, asking to retry after the cooldown.
 func Handler(breaker Breaker, validator StatusCodeValidator, next http.Handler) http.Handler {
 	return &handler{
 		breaker:   breaker,
@@ -59,7 +61,17 @@ func (h *handler) serveClosed(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *handler) serveOpened(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusServiceUnavailable)
+	cooldown := DefaultCooldown
+	if c, ok := h.breaker.(interface{ Cooldown() time.Duration }); ok {
+		cooldown = c.Cooldown()
+	}
+
+	problem.Write(w, r, problem.Problem{
+		Status:     http.StatusServiceUnavailable,
+		Detail:     "circuit breaker is open",
+		Reason:     problem.ReasonCircuitOpen,
+		RetryAfter: cooldown,
+	})
 }
 
 type codeWriter struct {
@@ -28,6 +28,7 @@ func TestHandlerCircuitStaysClosedWithSingleError(t *testing.T) {
 }
 
 func TestHandlerCircuitOpenWith5PercentError(t *testing.T) {
+	var resp *httptest.ResponseRecorder
 	lastResponse := 200
 	code := 200
 	backend := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
@@ -37,7 +38,7 @@ func TestHandlerCircuitOpenWith5PercentError(t *testing.T) {
 	h := Handler(NewBreaker(0.05), DefaultStatusCodeValidator, backend)
 
 	for i := 1; i <= 100; i++ {
-		resp := httptest.NewRecorder()
+		resp = httptest.NewRecorder()
 		req := &http.Request{Method: "GET"}
 
 		if i >= 95 {
@@ -51,4 +52,12 @@ func TestHandlerCircuitOpenWith5PercentError(t *testing.T) {
 	if lastResponse != 503 {
 		t.Fatalf("expected circuit to be open with 503 after 5%% error rate, got last response: %d", lastResponse)
 	}
+
+	if want, got := "application/problem+json", resp.Header().Get("Content-Type"); want != got {
+		t.Fatalf("expected open circuit to respond with %q, got %q", want, got)
+	}
+
+	if want, got := "1", resp.Header().Get("Retry-After"); want != got {
+		t.Fatalf("expected open circuit to retry after the cooldown %q, got %q", want, got)
+	}
 }
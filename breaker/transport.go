@@ -4,6 +4,8 @@ import (
 	"errors"
 	"net/http"
 	"time"
+
+	"github.com/streadway/handy/retry"
 )
 
 var (
@@ -20,7 +22,8 @@ type ResponseValidator func(*http.Response) bool
 // Transport produces an http.RoundTripper that's governed by the passed
 // Breaker and ResponseValidator. Responses that fail the validator signal
 // failures to the breaker. Once the breaker opens, outgoing requests are
-// terminated before being forwarded with ErrCircuitOpen.
+// terminated before being forwarded with ErrCircuitOpen, which is noted on
+// the retry.Trace of the request.
 func Transport(breaker Breaker, validator ResponseValidator, next http.RoundTripper) http.RoundTripper {
 	return &transport{
 		breaker:   breaker,
@@ -37,6 +40,7 @@ type transport struct {
 
 func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
 	if !t.breaker.Allow() {
+		retry.Annotate(req.Context(), "circuit open")
 		return nil, ErrCircuitOpen
 	}
 
@@ -6,6 +6,9 @@ import (
 	"net/url"
 	"reflect"
 	"testing"
+	"time"
+
+	"github.com/streadway/handy/retry"
 )
 
 func TestTransportCircuitStaysClosedWithSingleError(t *testing.T) {
@@ -58,3 +61,25 @@ func TestTransportCircuitOpenWith5PercentError(t *testing.T) {
 		t.Fatalf("expected %q after 5%% error rate, got %q", ErrCircuitOpen, err)
 	}
 }
+
+type opened struct{}
+
+func (opened) Allow() bool           { return false }
+func (opened) Success(time.Duration) {}
+func (opened) Failure(time.Duration) {}
+
+func TestTransportNotesOpenCircuitOnTrace(t *testing.T) {
+	trace := retry.NewTrace()
+	trans := retry.Transport{
+		Retry: retry.Limit(retry.Errors(), retry.Max(1)),
+		Next:  Transport(opened{}, DefaultResponseValidator, http.DefaultTransport),
+	}
+
+	req, _ := http.NewRequest("GET", "http://example.invalid/", nil)
+	trans.RoundTrip(req.WithContext(retry.WithTrace(req.Context(), trace)))
+
+	steps := trace.Steps()
+	if len(steps) != 1 || !reflect.DeepEqual(steps[0].Notes, []string{"circuit open"}) {
+		t.Fatalf("expected the open circuit to be noted, got: %+v", steps)
+	}
+}
@@ -0,0 +1,155 @@
+/*
+Package charset decodes bodies in the charset announced by their
+Content-Type or HTML meta tags to UTF-8, for clients of legacy services.
+
+Latin-1, Windows-1252, US-ASCII and UTF-16 are decoded by this package.
+Other charsets are supported by registering a Decoder, for example one
+built from golang.org/x/text/encoding.
+*/
+package charset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"mime"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Decoder returns a reader decoding r to UTF-8.
+type Decoder func(r io.Reader) io.Reader
+
+// UnsupportedError is returned for charsets without a Decoder.
+type UnsupportedError struct {
+	Charset string
+}
+
+func (e UnsupportedError) Error() string {
+	return fmt.Sprintf("unsupported charset %q", e.Charset)
+}
+
+var (
+	mu       sync.RWMutex
+	decoders = map[string]Decoder{}
+)
+
+// Register adds a Decoder for a charset label and its aliases.  Labels match
+// case insensitively.
+func Register(d Decoder, labels ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, l := range labels {
+		decoders[strings.ToLower(l)] = d
+	}
+}
+
+// Lookup returns the Decoder for a charset label.
+func Lookup(label string) (Decoder, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	d, ok := decoders[strings.ToLower(strings.TrimSpace(label))]
+	return d, ok
+}
+
+func init() {
+	Register(func(r io.Reader) io.Reader { return r }, "utf-8", "utf8", "unicode-1-1-utf-8")
+	Register(func(r io.Reader) io.Reader { return &byteDecoder{r: r, decode: ascii} }, "us-ascii", "ascii", "iso646-us")
+	Register(func(r io.Reader) io.Reader { return &byteDecoder{r: r, decode: latin1} }, "iso-8859-1", "iso8859-1", "latin1", "l1")
+	Register(func(r io.Reader) io.Reader { return &byteDecoder{r: r, decode: windows1252} }, "windows-1252", "cp1252", "x-cp1252")
+	Register(func(r io.Reader) io.Reader { return &utf16Decoder{r: r, order: binary.BigEndian} }, "utf-16", "utf-16be")
+	Register(func(r io.Reader) io.Reader { return &utf16Decoder{r: r, order: binary.LittleEndian} }, "utf-16le")
+}
+
+// sniffLen is the prefix of HTML documents searched for meta tags.
+const sniffLen = 1024
+
+var metaCharset = regexp.MustCompile(`(?i)<meta[^>]*charset\s*=\s*["']?\s*([a-z0-9_:.+-]+)`)
+
+// Detect returns the charset of a body from the charset parameter of its
+// Content-Type, a byte order mark, or meta tags of HTML documents at the
+// start of the body.  Without any, Detect returns the empty string.
+func Detect(contentType string, prefix []byte) string {
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+	if cs := params["charset"]; cs != "" {
+		return strings.ToLower(cs)
+	}
+
+	switch {
+	case bytes.HasPrefix(prefix, []byte{0xef, 0xbb, 0xbf}):
+		return "utf-8"
+	case bytes.HasPrefix(prefix, []byte{0xfe, 0xff}), bytes.HasPrefix(prefix, []byte{0xff, 0xfe}):
+		return "utf-16"
+	}
+
+	if mediaType == "text/html" || mediaType == "application/xhtml+xml" {
+		if len(prefix) > sniffLen {
+			prefix = prefix[:sniffLen]
+		}
+		if m := metaCharset.FindSubmatch(prefix); m != nil {
+			return strings.ToLower(string(m[1]))
+		}
+	}
+
+	return ""
+}
+
+// NewReader returns a reader decoding r to UTF-8 from the charset of
+// contentType, or else from the charset detected at the start of r on the
+// first Read.  Bodies without a detected charset are read as is.
+func NewReader(r io.Reader, contentType string) (io.Reader, error) {
+	if _, params, _ := mime.ParseMediaType(contentType); params["charset"] != "" {
+		return decoder(r, strings.ToLower(params["charset"]))
+	}
+	return &sniffReader{r: r, contentType: contentType}, nil
+}
+
+func decoder(r io.Reader, cs string) (io.Reader, error) {
+	d, ok := Lookup(cs)
+	if !ok {
+		return nil, UnsupportedError{cs}
+	}
+	return d(r), nil
+}
+
+// sniffReader detects the charset on the first Read, waiting only for the
+// byte order mark unless meta tags of HTML documents are searched.
+type sniffReader struct {
+	r           io.Reader
+	contentType string
+	decoded     io.Reader
+	err         error
+}
+
+func (s *sniffReader) Read(p []byte) (int, error) {
+	if s.decoded == nil && s.err == nil {
+		s.decoded, s.err = sniff(s.r, s.contentType)
+	}
+	if s.err != nil {
+		return 0, s.err
+	}
+	return s.decoded.Read(p)
+}
+
+func sniff(r io.Reader, contentType string) (io.Reader, error) {
+	n := 3 // the longest byte order mark
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == "text/html" || mediaType == "application/xhtml+xml" {
+		n = sniffLen
+	}
+
+	prefix := make([]byte, n)
+	n, err := io.ReadFull(r, prefix)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	prefix = prefix[:n]
+	r = io.MultiReader(bytes.NewReader(prefix), r)
+
+	cs := Detect(contentType, prefix)
+	if cs == "" {
+		return r, nil
+	}
+	return decoder(r, cs)
+}
@@ -0,0 +1,60 @@
+package charset
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func decode(t *testing.T, contentType string, body []byte) string {
+	r, err := NewReader(iotest.OneByteReader(bytes.NewReader(body)), contentType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestNewReader(t *testing.T) {
+	for _, test := range []struct {
+		contentType string
+		body        []byte
+		want        string
+	}{
+		{"text/plain; charset=ISO-8859-1", []byte("caf\xe9"), "café"},
+		{"text/plain; charset=windows-1252", []byte("\x93quoted\x94 \x80"), "“quoted” €"},
+		{"text/plain; charset=us-ascii", []byte("ok\xff"), "ok�"},
+		{"text/plain; charset=utf-16le", []byte{'h', 0, 'i', 0, 0x3d, 0xd8, 0x00, 0xde}, "hi😀"},
+		{"text/plain", []byte{0xfe, 0xff, 0, 'h', 0, 'i'}, "hi"},
+		{"text/plain", []byte("as is \xe9"), "as is \xe9"},
+		{"text/html", []byte(`<html><head><meta http-equiv="Content-Type" content="text/html; charset=latin1">caf` + "\xe9"), `<html><head><meta http-equiv="Content-Type" content="text/html; charset=latin1">café`},
+		{"text/html", []byte(`<meta charset='windows-1252'>` + "\x85"), `<meta charset='windows-1252'>…`},
+	} {
+		if got := decode(t, test.contentType, test.body); test.want != got {
+			t.Errorf("%s %q: want %q, got %q", test.contentType, test.body, test.want, got)
+		}
+	}
+}
+
+func TestUnsupported(t *testing.T) {
+	_, err := NewReader(strings.NewReader("x"), "text/plain; charset=koi8-r")
+	if want, got := (UnsupportedError{"koi8-r"}), err; want != got {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+
+	Register(func(r io.Reader) io.Reader { return r }, "KOI8-R")
+	defer func() {
+		mu.Lock()
+		delete(decoders, "koi8-r")
+		mu.Unlock()
+	}()
+
+	if _, err := NewReader(strings.NewReader("x"), "text/plain; charset=koi8-r"); err != nil {
+		t.Fatalf("expected a registered decoder to be used, got: %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package charset
+
+import (
+	"encoding/binary"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// byteDecoder decodes single byte encodings through a table of runes.
+type byteDecoder struct {
+	r       io.Reader
+	decode  func(byte) rune
+	in, out []byte
+	err     error
+}
+
+func (d *byteDecoder) Read(p []byte) (int, error) {
+	for len(d.out) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		if d.in == nil {
+			d.in = make([]byte, 1024)
+		}
+
+		var n int
+		n, d.err = d.r.Read(d.in)
+		for _, b := range d.in[:n] {
+			d.out = utf8.AppendRune(d.out, d.decode(b))
+		}
+	}
+
+	n := copy(p, d.out)
+	d.out = d.out[n:]
+	return n, nil
+}
+
+func latin1(b byte) rune {
+	return rune(b)
+}
+
+// windows1252 differs from Latin-1 in the C1 control range.
+var windows1252High = [32]rune{
+	'€', utf8.RuneError, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', utf8.RuneError, 'Ž', utf8.RuneError,
+	utf8.RuneError, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', utf8.RuneError, 'ž', 'Ÿ',
+}
+
+func windows1252(b byte) rune {
+	if b >= 0x80 && b < 0xa0 {
+		return windows1252High[b-0x80]
+	}
+	return rune(b)
+}
+
+func ascii(b byte) rune {
+	if b >= 0x80 {
+		return utf8.RuneError
+	}
+	return rune(b)
+}
+
+// utf16Decoder decodes UTF-16 in the byte order of its BOM, or order when
+// there is none.
+type utf16Decoder struct {
+	r       io.Reader
+	order   binary.ByteOrder
+	started bool
+	in      []byte
+	pending []byte // an odd trailing byte
+	high    rune   // a high surrogate awaiting its pair
+	out     []byte
+	err     error
+}
+
+func (d *utf16Decoder) Read(p []byte) (int, error) {
+	for len(d.out) == 0 {
+		if d.err != nil {
+			if d.err == io.EOF && (len(d.pending) > 0 || d.high != 0) {
+				d.pending, d.high = nil, 0
+				d.out = utf8.AppendRune(d.out, utf8.RuneError)
+				break
+			}
+			return 0, d.err
+		}
+		if d.in == nil {
+			d.in = make([]byte, 1024)
+		}
+
+		var n int
+		n, d.err = d.r.Read(d.in)
+		buf := append(d.pending, d.in[:n]...)
+
+		if !d.started && len(buf) >= 2 {
+			d.started = true
+			switch {
+			case buf[0] == 0xfe && buf[1] == 0xff:
+				d.order, buf = binary.BigEndian, buf[2:]
+			case buf[0] == 0xff && buf[1] == 0xfe:
+				d.order, buf = binary.LittleEndian, buf[2:]
+			}
+		}
+		if !d.started {
+			d.pending = buf
+			continue
+		}
+
+		for len(buf) >= 2 {
+			r := rune(d.order.Uint16(buf))
+			buf = buf[2:]
+
+			switch {
+			case d.high != 0:
+				d.out = utf8.AppendRune(d.out, utf16.DecodeRune(d.high, r))
+				d.high = 0
+			case utf16.IsSurrogate(r) && r < 0xdc00:
+				d.high = r
+			default:
+				d.out = utf8.AppendRune(d.out, r)
+			}
+		}
+		d.pending = append([]byte(nil), buf...)
+	}
+
+	n := copy(p, d.out)
+	d.out = d.out[n:]
+	return n, nil
+}
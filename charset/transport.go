@@ -0,0 +1,67 @@
+package charset
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Transport is an http.RoundTripper decoding the bodies of textual
+// responses to UTF-8.  Responses announcing a charset in their Content-Type
+// are relabelled as UTF-8, and fail with an UnsupportedError for
+// unsupported charsets.  The charset of other responses is detected as
+// their body is read, leaving their Content-Type alone.
+type Transport struct {
+	// Next is the http.RoundTripper to which requests are forwarded.  If Next
+	// is nil, http.DefaultTransport is used.
+	Next http.RoundTripper
+}
+
+// RoundTrip implements the RoundTripper interface.
+func (t Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	resp, err := next.RoundTrip(req)
+	if err != nil || !textual(resp.Header.Get("Content-Type")) || resp.Header.Get("Content-Encoding") != "" {
+		return resp, err
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	body, err := NewReader(resp.Body, contentType)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	if mediaType, params, _ := mime.ParseMediaType(contentType); params["charset"] != "" {
+		params["charset"] = "utf-8"
+		resp.Header.Set("Content-Type", mime.FormatMediaType(mediaType, params))
+	}
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Body = readCloser{body, resp.Body}
+
+	return resp, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func textual(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "+xml") ||
+		strings.HasSuffix(mediaType, "+json") ||
+		mediaType == "application/json" ||
+		mediaType == "application/xml" ||
+		mediaType == "application/javascript"
+}
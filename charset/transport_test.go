@@ -0,0 +1,76 @@
+package charset
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTransport(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/binary" {
+			w.Header().Set("Content-Type", "application/octet-stream")
+		} else {
+			w.Header().Set("Content-Type", "text/plain; charset=iso-8859-1")
+		}
+		io.WriteString(w, "caf\xe9")
+	}))
+	defer s.Close()
+
+	client := &http.Client{Transport: Transport{}}
+
+	resp, err := client.Get(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if want, got := "café", string(body); want != got {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+	if want, got := "text/plain; charset=utf-8", resp.Header.Get("Content-Type"); want != got {
+		t.Fatalf("want Content-Type %q, got %q", want, got)
+	}
+
+	resp, err = client.Get(s.URL + "/binary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if want, got := "caf\xe9", string(body); want != got {
+		t.Fatalf("expected binary bodies unchanged, got %q", got)
+	}
+}
+
+func TestTransportStreams(t *testing.T) {
+	release := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: caf\xc3\xa9\n\n")
+		w.(http.Flusher).Flush()
+		<-release
+	}))
+	defer s.Close()
+	defer close(release)
+
+	resp, err := (&http.Client{Transport: Transport{}}).Get(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	buf := make([]byte, 12)
+	if _, err := io.ReadFull(resp.Body, buf); err != nil {
+		t.Fatal(err)
+	}
+	if want, got := "data: café\n", string(buf); want != got {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+	if want, got := "text/event-stream", resp.Header.Get("Content-Type"); want != got {
+		t.Fatalf("expected the Content-Type without charset to be kept, got %q", got)
+	}
+}
@@ -0,0 +1,204 @@
+/*
+Package concurrency limits the number of concurrent requests per upstream
+host, queueing requests over the limit, to protect fragile upstreams from
+bursts of fanned out requests.
+*/
+package concurrency
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// OverflowError is returned for requests refused because their host is at
+// its limit.
+type OverflowError struct {
+	Host  string
+	Limit int
+
+	// Waited is the time the request spent queued, zero when the queue was
+	// full.
+	Waited time.Duration
+}
+
+func (e OverflowError) Error() string {
+	if e.Waited == 0 {
+		return fmt.Sprintf("%s at its limit of %d concurrent requests with a full queue", e.Host, e.Limit)
+	}
+	return fmt.Sprintf("%s at its limit of %d concurrent requests after waiting %s", e.Host, e.Limit, e.Waited)
+}
+
+// Temporary reports the error as temporary, so retry.Temporary retries it.
+func (e OverflowError) Temporary() bool {
+	return true
+}
+
+// Transport is an http.RoundTripper limiting the requests in flight per
+// host.  A request is in flight until its response body is closed.
+type Transport struct {
+	// MaxPerHost is the limit of requests in flight per host.  If zero, the
+	// requests are not limited.
+	MaxPerHost int
+
+	// MaxQueue is the limit of requests waiting per host.  If zero, requests
+	// over MaxPerHost are refused without waiting.  If negative, the queue
+	// is unlimited.
+	MaxQueue int
+
+	// QueueTimeout is the time a request waits at most.  If zero, requests
+	// wait until their context is done.
+	QueueTimeout time.Duration
+
+	// Next is the http.RoundTripper to which requests are forwarded.  If Next
+	// is nil, http.DefaultTransport is used.
+	Next http.RoundTripper
+
+	mu    sync.Mutex
+	hosts map[string]*host
+}
+
+// host limits the requests to a host.  It is dropped once no request is in
+// flight or waiting.
+type host struct {
+	slots   chan struct{}
+	waiting int
+	refs    int // requests in flight or waiting
+}
+
+// enter returns the host of a request, counting it until leave.
+func (t *Transport) enter(name string) *host {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.hosts == nil {
+		t.hosts = make(map[string]*host)
+	}
+	h, ok := t.hosts[name]
+	if !ok {
+		h = &host{slots: make(chan struct{}, t.MaxPerHost)}
+		t.hosts[name] = h
+	}
+	h.refs++
+	return h
+}
+
+// leave drops the host once its last request left.
+func (t *Transport) leave(name string, h *host) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	h.refs--
+	if h.refs == 0 {
+		delete(t.hosts, name)
+	}
+}
+
+// InFlight returns the number of requests in flight to host.
+func (t *Transport) InFlight(name string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if h, ok := t.hosts[name]; ok {
+		return len(h.slots)
+	}
+	return 0
+}
+
+// Queued returns the number of requests waiting for host.
+func (t *Transport) Queued(name string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if h, ok := t.hosts[name]; ok {
+		return h.waiting
+	}
+	return 0
+}
+
+// RoundTrip implements the RoundTripper interface.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	if t.MaxPerHost <= 0 {
+		return next.RoundTrip(req)
+	}
+
+	name := req.URL.Host
+	h := t.enter(name)
+	if err := t.acquire(req, h); err != nil {
+		t.leave(name, h)
+		return nil, err
+	}
+
+	release := func() {
+		<-h.slots
+		t.leave(name, h)
+	}
+
+	resp, err := next.RoundTrip(req)
+	if err != nil || resp.Body == nil {
+		release()
+		return resp, err
+	}
+
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+func (t *Transport) acquire(req *http.Request, h *host) error {
+	select {
+	case h.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	t.mu.Lock()
+	if t.MaxQueue >= 0 && h.waiting >= t.MaxQueue {
+		t.mu.Unlock()
+		return OverflowError{Host: req.URL.Host, Limit: t.MaxPerHost}
+	}
+	h.waiting++
+	t.mu.Unlock()
+
+	defer func() {
+		t.mu.Lock()
+		h.waiting--
+		t.mu.Unlock()
+	}()
+
+	var timeout <-chan time.Time
+	if t.QueueTimeout > 0 {
+		timer := time.NewTimer(t.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	start := time.Now()
+	select {
+	case h.slots <- struct{}{}:
+		return nil
+	case <-timeout:
+		return OverflowError{Host: req.URL.Host, Limit: t.MaxPerHost, Waited: time.Since(start)}
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
+
+// releaseBody frees the slot of a request once its body is closed.
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
@@ -0,0 +1,134 @@
+package concurrency
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/streadway/handy/retry"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func ok(*http.Request) (*http.Response, error) {
+	return &http.Response{StatusCode: 200, Body: http.NoBody}, nil
+}
+
+func TestRefusesWithoutQueue(t *testing.T) {
+	trans := &Transport{MaxPerHost: 1, Next: roundTripFunc(ok)}
+
+	req, _ := http.NewRequest("GET", "http://example/", nil)
+	held, err := trans.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := 1, trans.InFlight("example"); want != got {
+		t.Fatalf("expected %d request in flight until the body is closed, got %d", want, got)
+	}
+
+	_, err = trans.RoundTrip(req)
+	overflow, isOverflow := err.(OverflowError)
+	if !isOverflow || overflow.Host != "example" || overflow.Limit != 1 || overflow.Waited != 0 {
+		t.Fatalf("expected an OverflowError for a full queue, got: %#v", err)
+	}
+	if decision, _ := retry.Temporary()(retry.Attempt{Err: err}); decision != retry.Retry {
+		t.Fatalf("expected retry.Temporary to retry an OverflowError, got: %v", decision)
+	}
+
+	other, _ := http.NewRequest("GET", "http://other/", nil)
+	if _, err := trans.RoundTrip(other); err != nil {
+		t.Fatalf("expected other hosts not to be limited, got: %v", err)
+	}
+
+	held.Body.Close()
+	held.Body.Close()
+
+	if want, got := 0, trans.InFlight("example"); want != got {
+		t.Fatalf("expected closing the body to release the request once, got %d in flight", got)
+	}
+}
+
+func TestQueuesUntilReleased(t *testing.T) {
+	trans := &Transport{MaxPerHost: 2, MaxQueue: -1, Next: roundTripFunc(ok)}
+	req, _ := http.NewRequest("GET", "http://example/", nil)
+
+	var held []*http.Response
+	for i := 0; i < 2; i++ {
+		resp, err := trans.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		held = append(held, resp)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		resp, err := trans.RoundTrip(req)
+		if err != nil {
+			t.Errorf("expected the queued request to proceed, got: %v", err)
+			return
+		}
+		resp.Body.Close()
+	}()
+
+	for trans.Queued("example") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	held[0].Body.Close()
+	wg.Wait()
+	held[1].Body.Close()
+
+	if want, got := 0, trans.Queued("example"); want != got {
+		t.Fatalf("expected an empty queue, got %d", got)
+	}
+}
+
+func TestQueueTimeout(t *testing.T) {
+	trans := &Transport{MaxPerHost: 1, MaxQueue: 1, QueueTimeout: 5 * time.Millisecond, Next: roundTripFunc(ok)}
+	req, _ := http.NewRequest("GET", "http://example/", nil)
+
+	held, _ := trans.RoundTrip(req)
+	defer held.Body.Close()
+
+	_, err := trans.RoundTrip(req)
+	if overflow, isOverflow := err.(OverflowError); !isOverflow || overflow.Waited < 5*time.Millisecond {
+		t.Fatalf("expected an OverflowError after waiting, got: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	trans.QueueTimeout = 0
+
+	if _, err := trans.RoundTrip(req.WithContext(ctx)); err != context.Canceled {
+		t.Fatalf("expected waiting to end with the context, got: %v", err)
+	}
+}
+
+func TestDropsIdleHosts(t *testing.T) {
+	trans := &Transport{MaxPerHost: 1, Next: roundTripFunc(ok)}
+
+	if want, got := 0, trans.InFlight("example")+trans.Queued("example"); want != got {
+		t.Fatalf("expected unknown hosts to report %d, got %d", want, got)
+	}
+
+	req, _ := http.NewRequest("GET", "http://example/", nil)
+	held, _ := trans.RoundTrip(req)
+	if _, err := trans.RoundTrip(req); err == nil {
+		t.Fatalf("expected the second request to be refused")
+	}
+	held.Body.Close()
+
+	if want, got := 0, len(trans.hosts); want != got {
+		t.Fatalf("expected %d hosts once idle, got %d", want, got)
+	}
+}
@@ -0,0 +1,238 @@
+/*
+Package config fetches remote configuration, verifies its detached signature
+and applies it, rolling back to the last known good configuration when it
+fails to apply.
+*/
+package config
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/streadway/handy/mirror"
+	"github.com/streadway/handy/random"
+	"github.com/streadway/handy/retry"
+)
+
+// Defaults used by Fetcher when not configured.
+const (
+	DefaultInterval = time.Minute
+	DefaultJitter   = 0.1
+)
+
+// ErrInvalidSignature is returned by Verifiers rejecting a payload.
+var ErrInvalidSignature = errors.New("config: invalid signature")
+
+// Verifier checks the detached signature of a payload.
+type Verifier interface {
+	Verify(payload, signature []byte) error
+}
+
+// VerifierFunc adapts a function to a Verifier.
+type VerifierFunc func(payload, signature []byte) error
+
+// Verify calls f.
+func (f VerifierFunc) Verify(payload, signature []byte) error {
+	return f(payload, signature)
+}
+
+// Ed25519 verifies signatures made by the private key of key.
+func Ed25519(key ed25519.PublicKey) Verifier {
+	return VerifierFunc(func(payload, signature []byte) error {
+		if !ed25519.Verify(key, payload, signature) {
+			return ErrInvalidSignature
+		}
+		return nil
+	})
+}
+
+// RollbackError is returned when a configuration failed to apply and the
+// last known good configuration was applied again.
+type RollbackError struct {
+	Err      error // failure to apply the new configuration
+	Rollback error // failure to apply the last known good configuration
+}
+
+func (e *RollbackError) Error() string {
+	if e.Rollback != nil {
+		return fmt.Sprintf("config: apply: %s, rollback: %s", e.Err, e.Rollback)
+	}
+	return fmt.Sprintf("config: apply: %s, rolled back", e.Err)
+}
+
+// Unwrap returns the error applying the new configuration.
+func (e *RollbackError) Unwrap() error {
+	return e.Err
+}
+
+// Fetcher polls a configuration and applies verified changes.
+type Fetcher struct {
+	// URL of the configuration.
+	URL string
+
+	// SignatureURL holds the base64 encoded detached signature of the
+	// configuration, URL with ".sig" appended when empty.
+	SignatureURL string
+
+	// Verifier checks the signature.
+	Verifier Verifier
+
+	// Apply validates and applies a verified configuration.  When it fails,
+	// the last known good configuration is applied again.
+	Apply func([]byte) error
+
+	// Client issues the requests, for example through a retry.Transport.  If
+	// nil, http.DefaultClient is used.
+	Client *http.Client
+
+	// Interval between polls, DefaultInterval when zero.
+	Interval time.Duration
+
+	// Jitter spreads the polls of many instances by a random fraction of
+	// Interval, DefaultJitter when zero.
+	Jitter float64
+
+	// Rand draws the jitter.  If nil, random.Default is used.
+	Rand random.Source
+
+	// Backoff chooses the wait after a failed poll from the count of
+	// consecutive failures.  If nil, the jittered Interval is used.
+	Backoff retry.Backoff
+
+	fetch  sync.Mutex // serializes polls
+	ctx    context.Context
+	mirror *mirror.Mirror
+
+	mu   sync.RWMutex
+	good []byte
+}
+
+// Current returns the last known good configuration, nil before the first
+// was applied.
+func (f *Fetcher) Current() []byte {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.good
+}
+
+// Fetch polls the configuration once with a conditional request and applies
+// it when changed.
+func (f *Fetcher) Fetch(ctx context.Context) error {
+	f.fetch.Lock()
+	defer f.fetch.Unlock()
+
+	if f.mirror == nil {
+		f.mirror = &mirror.Mirror{
+			URLs:     []string{f.URL},
+			Client:   f.Client,
+			OnUpdate: func(o mirror.Object) error { return f.update(f.ctx, o.Body) },
+		}
+	}
+
+	f.ctx = ctx
+	return f.mirror.Sync(ctx)
+}
+
+// Run polls the configuration until the context is done.
+func (f *Fetcher) Run(ctx context.Context) {
+	var (
+		failures uint
+		start    = time.Now()
+	)
+
+	for {
+		wait := f.wait()
+		if err := f.Fetch(ctx); err != nil {
+			failures++
+			if f.Backoff != nil {
+				wait = f.Backoff(retry.Attempt{Start: start, Count: failures, Err: err})
+			}
+		} else {
+			failures = 0
+			start = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// wait returns the Interval shifted by up to Jitter in either direction.
+func (f *Fetcher) wait() time.Duration {
+	interval := f.Interval
+	if interval == 0 {
+		interval = DefaultInterval
+	}
+	jitter := f.Jitter
+	if jitter == 0 {
+		jitter = DefaultJitter
+	}
+	return time.Duration(float64(interval) * (1 + jitter*(2*random.Or(f.Rand).Float64()-1)))
+}
+
+// update verifies and applies payload, rolling back on failure.
+func (f *Fetcher) update(ctx context.Context, payload []byte) error {
+	signature, err := f.signature(ctx)
+	if err != nil {
+		return err
+	}
+	if err := f.Verifier.Verify(payload, signature); err != nil {
+		return err
+	}
+
+	if err := f.Apply(payload); err != nil {
+		rollback := &RollbackError{Err: err}
+		if good := f.Current(); good != nil {
+			rollback.Rollback = f.Apply(good)
+		}
+		return rollback
+	}
+
+	f.mu.Lock()
+	f.good = payload
+	f.mu.Unlock()
+	return nil
+}
+
+func (f *Fetcher) signature(ctx context.Context) ([]byte, error) {
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	url := f.SignatureURL
+	if url == "" {
+		url = f.URL + ".sig"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &mirror.StatusError{URL: url, StatusCode: resp.StatusCode}
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/streadway/handy/random"
+)
+
+type signedServer struct {
+	key      ed25519.PrivateKey
+	payload  string
+	forged   bool
+	requests int
+}
+
+func (s *signedServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	etag := `"` + s.payload + `"`
+	switch r.URL.Path {
+	case "/config":
+		s.requests++
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(s.payload))
+	case "/config.sig":
+		signed := s.payload
+		if s.forged {
+			signed = "forged"
+		}
+		w.Write([]byte(base64.StdEncoding.EncodeToString(ed25519.Sign(s.key, []byte(signed)))))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newFetcher(t *testing.T, payload string) (*Fetcher, *signedServer, *[]string) {
+	pub, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &signedServer{key: key, payload: payload}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+
+	var applied []string
+	return &Fetcher{
+		URL:      srv.URL + "/config",
+		Verifier: Ed25519(pub),
+		Apply: func(b []byte) error {
+			applied = append(applied, string(b))
+			if string(b) == "invalid" {
+				return errors.New("invalid configuration")
+			}
+			return nil
+		},
+	}, s, &applied
+}
+
+func TestFetchAppliesVerifiedChanges(t *testing.T) {
+	f, s, applied := newFetcher(t, "v1")
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := f.Fetch(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s.payload = "v2"
+	if err := f.Fetch(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := []string{"v1", "v2"}, *applied; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expected to apply %v, got %v", want, got)
+	}
+	if want, got := "v2", string(f.Current()); want != got {
+		t.Fatalf("expected current %s, got %s", want, got)
+	}
+}
+
+func TestFetchRejectsInvalidSignature(t *testing.T) {
+	f, s, applied := newFetcher(t, "v1")
+	s.forged = true
+
+	if err := f.Fetch(context.Background()); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected an invalid signature, got: %v", err)
+	}
+	if len(*applied) != 0 || f.Current() != nil {
+		t.Fatalf("expected the forged configuration not to be applied")
+	}
+}
+
+func TestFetchRollsBack(t *testing.T) {
+	f, s, applied := newFetcher(t, "v1")
+	ctx := context.Background()
+
+	if err := f.Fetch(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	s.payload = "invalid"
+	var rollback *RollbackError
+	if err := f.Fetch(ctx); !errors.As(err, &rollback) || rollback.Rollback != nil {
+		t.Fatalf("expected a successful rollback, got: %v", err)
+	}
+
+	if want, got := []string{"v1", "invalid", "v1"}, *applied; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expected to apply %v, got %v", want, got)
+	}
+	if want, got := "v1", string(f.Current()); want != got {
+		t.Fatalf("expected the last known good configuration, got %s", got)
+	}
+}
+
+func TestWaitIsJittered(t *testing.T) {
+	for rand, want := range map[float64]time.Duration{
+		0:    500 * time.Millisecond,
+		0.5:  time.Second,
+		0.75: 1250 * time.Millisecond,
+	} {
+		f := &Fetcher{Interval: time.Second, Jitter: 0.5, Rand: random.Fixed(rand)}
+		if got := f.wait(); want != got {
+			t.Errorf("rand %v: expected wait %s, got %s", rand, want, got)
+		}
+	}
+}
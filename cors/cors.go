@@ -12,6 +12,7 @@ package cors
 import (
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -19,40 +20,180 @@ import (
 // configured.
 const DefaultAllowOrigin = "*"
 
+// DefaultMaxAge is the time preflight responses may be cached when not
+// configured.
+const DefaultMaxAge = 10 * time.Minute
+
+var (
+	// DefaultAllowMethods are the methods allowed when not configured.
+	DefaultAllowMethods = []string{"GET"}
+
+	// DefaultAllowHeaders are the request headers allowed when not
+	// configured.
+	DefaultAllowHeaders = []string{"Accept", "Accept-Encoding", "Authorization", "Content-Type", "Origin"}
+)
+
 // Config parameterizes CORS behavior.
 type Config struct {
 	// AllowOrigin transforms a request into the Access-Control-Allow-Origin
-	// header, default is full access "*".
+	// header, default is full access "*".  When set, AllowOrigins and
+	// AllowOriginFunc are not consulted.
 	AllowOrigin func(*http.Request) string
+
+	// AllowOrigins lists the allowed origins, either exact like
+	// "https://example.com", with a wildcard subdomain like
+	// "https://*.example.com", or "*" for any origin.  Allowed origins are
+	// reflected in the Access-Control-Allow-Origin header.
+	AllowOrigins []string
+
+	// AllowOriginFunc allows the origins it returns true for, in addition to
+	// AllowOrigins.
+	AllowOriginFunc func(origin string) bool
+
+	// AllowMethods are the allowed methods, default is DefaultAllowMethods.
+	// HEAD is allowed along with GET.  Requests with other methods are
+	// answered with "405 Method Not Allowed".
+	AllowMethods []string
+
+	// AllowHeaders are the allowed request headers, default is
+	// DefaultAllowHeaders.
+	AllowHeaders []string
+
+	// ExposeHeaders are the response headers exposed to the client.
+	ExposeHeaders []string
+
+	// AllowCredentials allows requests with credentials.  The request origin
+	// is reflected instead of "*" as required for credentialed requests.
+	// Credentials are never allowed for any origin: "*" matches no origin
+	// then, and without AllowOrigins or AllowOriginFunc no origin is
+	// allowed.
+	AllowCredentials bool
+
+	// MaxAge is the time preflight responses may be cached, default is
+	// DefaultMaxAge.
+	MaxAge time.Duration
+}
+
+// origin returns the Access-Control-Allow-Origin value for the request, or
+// an empty string when the origin is not allowed.
+func (cfg Config) origin(r *http.Request) string {
+	if cfg.AllowOrigin != nil {
+		return cfg.AllowOrigin(r)
+	}
+
+	if len(cfg.AllowOrigins) == 0 && cfg.AllowOriginFunc == nil {
+		if cfg.AllowCredentials {
+			return ""
+		}
+		return DefaultAllowOrigin
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return ""
+	}
+
+	for _, allowed := range cfg.AllowOrigins {
+		if allowed == "*" && cfg.AllowCredentials {
+			continue
+		}
+		if matchOrigin(allowed, origin) {
+			if allowed == "*" {
+				return "*"
+			}
+			return origin
+		}
+	}
+
+	if cfg.AllowOriginFunc != nil && cfg.AllowOriginFunc(origin) {
+		return origin
+	}
+
+	return ""
+}
+
+// matchOrigin compares case-insensitively, with "*" matching any origin and
+// a "*." prefix of the host matching any subdomain.
+func matchOrigin(pattern, origin string) bool {
+	if pattern == "*" {
+		return true
+	}
+
+	pattern, origin = strings.ToLower(pattern), strings.ToLower(origin)
+
+	i := strings.Index(pattern, "*.")
+	if i < 0 {
+		return pattern == origin
+	}
+
+	prefix, suffix := pattern[:i], pattern[i+1:]
+	return len(origin) > len(prefix)+len(suffix) &&
+		strings.HasPrefix(origin, prefix) &&
+		strings.HasSuffix(origin, suffix)
 }
 
 // Middleware returns a middleware that applies Config to the request.
+// Preflight OPTIONS requests for allowed methods are answered directly,
+// other OPTIONS requests with "401 Unauthorized".
 func Middleware(cfg Config) func(http.Handler) http.Handler {
-	const maxAge = 10 * time.Minute
-	age := strconv.Itoa(int(maxAge / time.Second))
+	if cfg.MaxAge == 0 {
+		cfg.MaxAge = DefaultMaxAge
+	}
+	if len(cfg.AllowMethods) == 0 {
+		cfg.AllowMethods = DefaultAllowMethods
+	}
+	if len(cfg.AllowHeaders) == 0 {
+		cfg.AllowHeaders = DefaultAllowHeaders
+	}
+
+	var (
+		age     = strconv.Itoa(int(cfg.MaxAge / time.Second))
+		methods = strings.Join(cfg.AllowMethods, ", ")
+		headers = strings.Join(cfg.AllowHeaders, ", ")
+		expose  = strings.Join(cfg.ExposeHeaders, ", ")
+		allowed = make(map[string]bool, len(cfg.AllowMethods)+1)
+	)
+
+	for _, m := range cfg.AllowMethods {
+		allowed[strings.ToUpper(m)] = true
+	}
+	if allowed["GET"] {
+		allowed["HEAD"] = true
+	}
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			origin := DefaultAllowOrigin
-			if cfg.AllowOrigin != nil {
-				origin = cfg.AllowOrigin(r)
+			origin := cfg.origin(r)
+
+			// Allowed or not, the answer depends on the origin unless any is
+			// allowed
+			if origin != "*" && cfg.AllowOrigin == nil {
+				w.Header().Add("Vary", "Origin")
 			}
 
-			w.Header().Set("Access-Control-Allow-Methods", "GET")
-			w.Header().Set("Access-Control-Allow-Headers", "Accept, Accept-Encoding, Authorization, Content-Type, Origin")
-			w.Header().Set("Access-Control-Allow-Origin", origin)
+			if origin != "" {
+				w.Header().Set("Access-Control-Allow-Methods", methods)
+				w.Header().Set("Access-Control-Allow-Headers", headers)
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				if cfg.AllowCredentials {
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
+				}
+				if expose != "" {
+					w.Header().Set("Access-Control-Expose-Headers", expose)
+				}
+			}
 
-			switch r.Method {
-			default:
-				w.WriteHeader(http.StatusMethodNotAllowed)
-			case "OPTIONS":
-				if r.Header.Get("Access-Control-Request-Method") == "GET" {
+			switch {
+			case r.Method == "OPTIONS":
+				if origin != "" && allowed[strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))] {
 					w.Header().Set("Access-Control-Max-Age", age)
 					return
 				}
 				w.WriteHeader(http.StatusUnauthorized)
-			case "HEAD", "GET":
+			case allowed[r.Method]:
 				next.ServeHTTP(w, r)
+			default:
+				w.WriteHeader(http.StatusMethodNotAllowed)
 			}
 		})
 	}
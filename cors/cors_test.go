@@ -10,6 +10,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 type code int
@@ -93,3 +94,108 @@ func TestGetWithPOST(t *testing.T) {
 		t.Fatalf("expected 405 for GET, got: %d", res)
 	}
 }
+
+func request(method, origin string, header http.Header) *http.Request {
+	req := &http.Request{Method: method, Header: http.Header{}}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	return req
+}
+
+func TestAllowOrigins(t *testing.T) {
+	h := Middleware(Config{
+		AllowOrigins:    []string{"https://example.com", "https://*.example.org"},
+		AllowOriginFunc: func(origin string) bool { return origin == "https://partner.net" },
+	})(code(200))
+
+	for origin, want := range map[string]string{
+		"https://example.com":     "https://example.com",
+		"https://api.example.org": "https://api.example.org",
+		"https://example.org":     "",
+		"https://partner.net":     "https://partner.net",
+		"https://evil.com":        "",
+	} {
+		resp := httptest.NewRecorder()
+		h.ServeHTTP(resp, request("GET", origin, nil))
+
+		if got := resp.Header().Get("Access-Control-Allow-Origin"); want != got {
+			t.Errorf("origin %q: want Access-Control-Allow-Origin %q, got %q", origin, want, got)
+		}
+		if resp.Header().Get("Vary") != "Origin" {
+			t.Errorf("origin %q: expected to vary on Origin", origin)
+		}
+	}
+}
+
+func TestCredentialsRequireOrigins(t *testing.T) {
+	for name, cfg := range map[string]Config{
+		"default":  {AllowCredentials: true},
+		"wildcard": {AllowCredentials: true, AllowOrigins: []string{"*"}},
+	} {
+		resp := httptest.NewRecorder()
+		Middleware(cfg)(code(200)).ServeHTTP(resp, request("GET", "https://evil.com", nil))
+
+		if got := resp.Header().Get("Access-Control-Allow-Origin"); got != "" {
+			t.Errorf("%s: expected no origin to be allowed with credentials, got %q", name, got)
+		}
+		if got := resp.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+			t.Errorf("%s: expected no credentials to be allowed, got %q", name, got)
+		}
+		if want, got := "Origin", resp.Header().Get("Vary"); want != got {
+			t.Errorf("%s: expected to vary on Origin, got %q", name, got)
+		}
+	}
+}
+
+func TestPreflightConfigured(t *testing.T) {
+	h := Middleware(Config{
+		AllowOrigins:     []string{"https://example.com"},
+		AllowMethods:     []string{"GET", "PUT"},
+		AllowHeaders:     []string{"X-Token"},
+		ExposeHeaders:    []string{"X-Request-Id"},
+		AllowCredentials: true,
+		MaxAge:           time.Hour,
+	})(code(404))
+
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, request("OPTIONS", "https://example.com", http.Header{
+		"Access-Control-Request-Method": {"PUT"},
+	}))
+
+	if want, got := 200, resp.Code; want != got {
+		t.Fatalf("expected %d for an allowed preflight, got %d", want, got)
+	}
+
+	for hdr, want := range map[string]string{
+		"Access-Control-Allow-Origin":      "https://example.com",
+		"Access-Control-Allow-Methods":     "GET, PUT",
+		"Access-Control-Allow-Headers":     "X-Token",
+		"Access-Control-Expose-Headers":    "X-Request-Id",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Max-Age":           "3600",
+	} {
+		if got := resp.Header().Get(hdr); want != got {
+			t.Errorf("expected %s %q, got %q", hdr, want, got)
+		}
+	}
+
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, request("OPTIONS", "https://example.com", http.Header{
+		"Access-Control-Request-Method": {"DELETE"},
+	}))
+
+	if want, got := http.StatusUnauthorized, resp.Code; want != got {
+		t.Fatalf("expected %d for a preflight of another method, got %d", want, got)
+	}
+
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, request("PUT", "https://example.com", nil))
+
+	if want, got := 404, resp.Code; want != got {
+		t.Fatalf("expected the next handler to serve allowed methods, got %d", got)
+	}
+}
@@ -0,0 +1,180 @@
+/*
+Package debug dumps client requests and responses with sensitive headers and
+query parameters redacted.
+*/
+package debug
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/streadway/handy/redact"
+	"github.com/streadway/handy/retry"
+)
+
+// Redacted replaces the values of redacted headers and query parameters.
+const Redacted = redact.Redacted
+
+// DefaultMaxBody is the number of body bytes dumped when not configured.
+const DefaultMaxBody = 4096
+
+var (
+	// DefaultRedactHeaders are the headers redacted when not configured.
+	DefaultRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+	// DefaultRedactQuery are the query parameters redacted when not
+	// configured.
+	DefaultRedactQuery = redact.Query
+)
+
+// Logger allows to use loggers like retry.Logger.
+type Logger interface {
+	Printf(string, ...interface{})
+}
+
+// Transport is an http.RoundTripper dumping requests and responses to
+// Writer or Logger.  When used as the Next of a retry.Transport, each dump is
+// tagged with the attempt number.
+type Transport struct {
+	// Writer receives the dumps when set.
+	Writer io.Writer
+
+	// Logger receives the dumps when set.
+	Logger Logger
+
+	// Bodies dumps up to MaxBody bytes of request and response bodies.
+	Bodies bool
+
+	// MaxBody limits the dumped body bytes, DefaultMaxBody when zero.
+	MaxBody int64
+
+	// RedactHeaders are redacted, DefaultRedactHeaders when nil.
+	RedactHeaders []string
+
+	// RedactQuery are the query parameters redacted, DefaultRedactQuery
+	// when nil.
+	RedactQuery []string
+
+	// Next is the http.RoundTripper to which requests are forwarded.  If Next
+	// is nil, http.DefaultTransport is used.
+	Next http.RoundTripper
+
+	mu sync.Mutex // serializes dumps to Writer
+}
+
+// RoundTrip implements the RoundTripper interface.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	tag := fmt.Sprintf("attempt %d", retry.AttemptFrom(req.Context()))
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "--> [%s] %s %s %s\n", tag, req.Method, t.redactURL(req.URL), req.Proto)
+	t.dumpHeader(buf, req.Header)
+	if t.Bodies && req.Body != nil && req.Body != http.NoBody {
+		// Replay the peeked body on a copy, leaving the request to the caller
+		peeked := *req
+		var prefix []byte
+		prefix, peeked.Body = t.peek(req.Body)
+		fmt.Fprintf(buf, "\n%s\n", prefix)
+		req = &peeked
+	}
+	t.output(buf)
+
+	start := time.Now()
+	resp, err := next.RoundTrip(req)
+	elapsed := time.Since(start)
+
+	buf = &bytes.Buffer{}
+	if err != nil {
+		fmt.Fprintf(buf, "<-- [%s] %s %s error: %v (%s)\n", tag, req.Method, t.redactURL(req.URL), err, elapsed)
+		t.output(buf)
+		return resp, err
+	}
+
+	fmt.Fprintf(buf, "<-- [%s] %s %s (%s)\n", tag, resp.Proto, resp.Status, elapsed)
+	t.dumpHeader(buf, resp.Header)
+	if t.Bodies && resp.Body != nil {
+		var prefix []byte
+		prefix, resp.Body = t.peek(resp.Body)
+		fmt.Fprintf(buf, "\n%s\n", prefix)
+	}
+	t.output(buf)
+
+	return resp, nil
+}
+
+func (t *Transport) output(buf *bytes.Buffer) {
+	if t.Writer != nil {
+		t.mu.Lock()
+		t.Writer.Write(buf.Bytes())
+		t.mu.Unlock()
+	}
+	if t.Logger != nil {
+		t.Logger.Printf("%s", strings.TrimSuffix(buf.String(), "\n"))
+	}
+}
+
+func (t *Transport) dumpHeader(w io.Writer, h http.Header) {
+	names := t.RedactHeaders
+	if names == nil {
+		names = DefaultRedactHeaders
+	}
+
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		for _, v := range h[k] {
+			if contains(names, k) {
+				v = Redacted
+			}
+			fmt.Fprintf(w, "%s: %s\n", k, v)
+		}
+	}
+}
+
+func (t *Transport) redactURL(u *url.URL) string {
+	params := t.RedactQuery
+	if params == nil {
+		params = DefaultRedactQuery
+	}
+	return redact.URL(u, params)
+}
+
+// peek reads up to MaxBody bytes from body and returns them with a body
+// replaying them before the rest.
+func (t *Transport) peek(body io.ReadCloser) ([]byte, io.ReadCloser) {
+	limit := t.MaxBody
+	if limit == 0 {
+		limit = DefaultMaxBody
+	}
+	prefix, _ := ioutil.ReadAll(io.LimitReader(body, limit))
+	return prefix, struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(prefix), body), body}
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
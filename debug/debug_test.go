@@ -0,0 +1,110 @@
+package debug
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/streadway/handy/retry"
+)
+
+func TestDumpRedactsAndTagsAttempts(t *testing.T) {
+	calls := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(503)
+			return
+		}
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Write([]byte("response body"))
+	}))
+	defer s.Close()
+
+	var (
+		out bytes.Buffer
+		c   = http.Client{
+			Transport: retry.Transport{
+				Retry: retry.Limit(retry.Over(500), retry.Max(2)),
+				Next:  &Transport{Writer: &out, Bodies: true},
+			},
+		}
+	)
+
+	req, _ := http.NewRequest("PUT", s.URL+"/path?token=secret&signature=secret&page=1", strings.NewReader("request body"))
+	req.Header.Set("Authorization", "Bearer secret")
+
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if string(body) != "response body" {
+		t.Fatalf("expected the response body to be intact, got %q", body)
+	}
+
+	dump := out.String()
+	t.Log(dump)
+
+	if strings.Contains(dump, "secret") {
+		t.Fatalf("expected secrets to be redacted")
+	}
+
+	for _, want := range []string{
+		"--> [attempt 1] PUT",
+		"<-- [attempt 1] HTTP/1.1 503",
+		"--> [attempt 2] PUT",
+		"<-- [attempt 2] HTTP/1.1 200",
+		"token=REDACTED",
+		"page=1",
+		"Authorization: REDACTED",
+		"request body",
+		"response body",
+	} {
+		if !strings.Contains(dump, want) {
+			t.Errorf("expected dump to contain %q", want)
+		}
+	}
+}
+
+func TestDumpLeavesRequestUnaltered(t *testing.T) {
+	var (
+		out   bytes.Buffer
+		trans = &Transport{
+			Writer: &out,
+			Bodies: true,
+			Next: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				body, _ := ioutil.ReadAll(req.Body)
+				return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader(body))}, nil
+			}),
+		}
+	)
+
+	body := strings.NewReader("request body")
+	req, _ := http.NewRequest("POST", "http://example/", body)
+	original := req.Body
+
+	resp, err := trans.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	echo, _ := ioutil.ReadAll(resp.Body)
+
+	if req.Body != original {
+		t.Fatalf("expected the request body of the caller to be left alone")
+	}
+	if want, got := "request body", string(echo); want != got {
+		t.Fatalf("expected the whole body to be forwarded %q, got %q", want, got)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,248 @@
+/*
+Package degrade implements a client transport that degrades gracefully from
+live responses to stale cached responses and finally to a static fallback
+when the upstream fails.
+*/
+package degrade
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"sync"
+
+	"github.com/streadway/handy/atomic"
+)
+
+// DefaultMaxBody is the largest body stored when not configured.
+const DefaultMaxBody = 1 << 20
+
+// Tier identifies the source a response was served from.
+type Tier int
+
+const (
+	// Live responses come from the upstream.
+	Live Tier = iota
+	// Stale responses are the last successful live response to the same
+	// request.
+	Stale
+	// Static responses come from the Static fallback.
+	Static
+	// Failed signals that no tier could serve a response.
+	Failed
+
+	tiers
+)
+
+func (t Tier) String() string {
+	switch t {
+	case Live:
+		return "live"
+	case Stale:
+		return "stale"
+	case Static:
+		return "static"
+	case Failed:
+		return "failed"
+	}
+	return "unknown"
+}
+
+// Cache stores serialized responses by key.
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, response []byte)
+}
+
+// MemoryCache is an unbounded Cache kept in memory, safe for concurrent use.
+type MemoryCache struct {
+	mu      sync.RWMutex
+	entries map[string][]byte
+}
+
+// Get returns the response stored under key.
+func (c *MemoryCache) Get(key string) ([]byte, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	b, ok := c.entries[key]
+	return b, ok
+}
+
+// Set stores the response under key.
+func (c *MemoryCache) Set(key string, response []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string][]byte)
+	}
+	c.entries[key] = response
+}
+
+// Transport is an http.RoundTripper that tries Next first, typically a
+// retry.Transport, then the last successful response from Cache and finally
+// Static.  It counts the responses served per Tier.
+type Transport struct {
+	// Next is the live http.RoundTripper.  If Next is nil,
+	// http.DefaultTransport is used.
+	Next http.RoundTripper
+
+	// Cache stores successful GET responses to serve when stale, once their
+	// body was read to the end.  If Cache is nil, no stale responses are
+	// served.
+	Cache Cache
+
+	// MaxBody is the largest body stored, DefaultMaxBody when zero.  Larger
+	// responses are not stored.
+	MaxBody int64
+
+	// Key derives the Cache key of requests, for example with Key and its
+	// Rules.  If Key is nil, DefaultKey is used.
+	Key KeyFunc
+
+	// Static provides the last resort response.  If Static is nil or returns
+	// an error, the live response and error are returned.
+	Static func(*http.Request) (*http.Response, error)
+
+	// Failed determines whether a live response should be degraded.  If
+	// Failed is nil, DefaultFailed is used.
+	Failed func(*http.Response, error) bool
+
+	served [tiers]atomic.Int
+}
+
+// DefaultFailed considers errors and server errors as failed.
+func DefaultFailed(resp *http.Response, err error) bool {
+	return err != nil || resp == nil || resp.StatusCode >= 500
+}
+
+// Served returns the number of responses served from the tier.
+func (t *Transport) Served(tier Tier) int64 {
+	if tier < 0 || tier >= tiers {
+		return 0
+	}
+	return t.served[tier].Get()
+}
+
+// RoundTrip implements the RoundTripper interface.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	failed := t.Failed
+	if failed == nil {
+		failed = DefaultFailed
+	}
+
+	resp, err := next.RoundTrip(req)
+	if !failed(resp, err) {
+		t.store(req, resp, err)
+		t.served[Live].Add(1)
+		return resp, err
+	}
+
+	if stale, ok := t.stale(req); ok {
+		discard(resp)
+		t.served[Stale].Add(1)
+		return stale, nil
+	}
+
+	if t.Static != nil {
+		if static, staticErr := t.Static(req); staticErr == nil {
+			discard(resp)
+			t.served[Static].Add(1)
+			return static, nil
+		}
+	}
+
+	t.served[Failed].Add(1)
+	return resp, err
+}
+
+// store keeps the response in the Cache once the caller has read its body.
+func (t *Transport) store(req *http.Request, resp *http.Response, err error) {
+	if err != nil || resp == nil || t.Cache == nil || req.Method != "GET" || resp.StatusCode != http.StatusOK {
+		return
+	}
+
+	limit := t.MaxBody
+	if limit <= 0 {
+		limit = DefaultMaxBody
+	}
+	if resp.ContentLength > limit {
+		return
+	}
+
+	stored := *resp
+	stored.Header = resp.Header.Clone()
+	key := t.key(req)
+
+	resp.Body = &storeBody{ReadCloser: resp.Body, limit: limit, done: func(body []byte) {
+		stored.Body = io.NopCloser(bytes.NewReader(body))
+		stored.ContentLength = int64(len(body))
+		stored.TransferEncoding = nil
+		b, err := httputil.DumpResponse(&stored, true)
+		if err != nil {
+			return
+		}
+		t.Cache.Set(key, b)
+	}}
+}
+
+// storeBody keeps the body read by the caller up to limit bytes, and passes
+// it to done once read to the end.
+type storeBody struct {
+	io.ReadCloser
+	limit int64
+	buf   bytes.Buffer
+	over  bool
+	done  func([]byte)
+}
+
+func (b *storeBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if !b.over {
+		if int64(b.buf.Len()+n) > b.limit {
+			b.over = true
+			b.buf = bytes.Buffer{}
+		} else {
+			b.buf.Write(p[:n])
+		}
+	}
+	if err == io.EOF && !b.over {
+		b.over = true
+		b.done(b.buf.Bytes())
+	}
+	return n, err
+}
+
+func (t *Transport) stale(req *http.Request) (*http.Response, bool) {
+	if t.Cache == nil || req.Method != "GET" {
+		return nil, false
+	}
+	b, ok := t.Cache.Get(t.key(req))
+	if !ok {
+		return nil, false
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(b)), req)
+	if err != nil {
+		return nil, false
+	}
+	return resp, true
+}
+
+func (t *Transport) key(req *http.Request) string {
+	if t.Key == nil {
+		return DefaultKey(req)
+	}
+	return t.Key(req)
+}
+
+func discard(resp *http.Response) {
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+}
@@ -0,0 +1,121 @@
+package degrade
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type upstream struct {
+	status int
+	body   string
+}
+
+func (u *upstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(u.status)
+	w.Write([]byte(u.body))
+}
+
+func get(t *testing.T, rt http.RoundTripper, url string) (*http.Response, string) {
+	req, _ := http.NewRequest("GET", url, nil)
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	return resp, string(body)
+}
+
+func TestServesTiersInOrder(t *testing.T) {
+	u := &upstream{status: 200, body: "live"}
+	s := httptest.NewServer(u)
+	defer s.Close()
+
+	trans := &Transport{
+		Cache: &MemoryCache{},
+		Static: func(*http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader("static")),
+			}, nil
+		},
+	}
+
+	if _, body := get(t, trans, s.URL+"/cached"); body != "live" {
+		t.Fatalf("expected live body, got: %q", body)
+	}
+
+	u.status, u.body = 503, "unavailable"
+
+	if _, body := get(t, trans, s.URL+"/cached"); body != "live" {
+		t.Fatalf("expected stale body, got: %q", body)
+	}
+
+	if _, body := get(t, trans, s.URL+"/uncached"); body != "static" {
+		t.Fatalf("expected static body, got: %q", body)
+	}
+
+	for tier, want := range map[Tier]int64{Live: 1, Stale: 1, Static: 1, Failed: 0} {
+		if got := trans.Served(tier); want != got {
+			t.Errorf("expected %d responses served from %s, got %d", want, tier, got)
+		}
+	}
+}
+
+type failing struct{}
+
+func (failing) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("unreachable")
+}
+
+func TestReturnsLiveErrorWithoutTiers(t *testing.T) {
+	trans := &Transport{Next: failing{}}
+
+	req, _ := http.NewRequest("GET", "http://example/test", nil)
+	if _, err := trans.RoundTrip(req); err == nil {
+		t.Fatalf("expected the live error without fallback tiers")
+	}
+
+	if want, got := int64(1), trans.Served(Failed); want != got {
+		t.Fatalf("expected %d failed responses, got %d", want, got)
+	}
+}
+
+func TestCustomFailedWithoutResponse(t *testing.T) {
+	trans := &Transport{
+		Next:   failing{},
+		Cache:  &MemoryCache{},
+		Failed: func(resp *http.Response, err error) bool { return resp != nil && resp.StatusCode >= 500 },
+	}
+
+	req, _ := http.NewRequest("GET", "http://example/test", nil)
+	if _, err := trans.RoundTrip(req); err == nil {
+		t.Fatalf("expected the live error to be returned")
+	}
+}
+
+func TestSkipsStoringLargeBodies(t *testing.T) {
+	u := &upstream{status: 200, body: strings.Repeat("x", 64)}
+	s := httptest.NewServer(u)
+	defer s.Close()
+
+	cache := &MemoryCache{}
+	trans := &Transport{Cache: cache, MaxBody: 32}
+
+	if _, body := get(t, trans, s.URL+"/large"); len(body) != 64 {
+		t.Fatalf("expected the whole live body, got %d bytes", len(body))
+	}
+	if _, ok := cache.Get(DefaultKey(httptest.NewRequest("GET", s.URL+"/large", nil))); ok {
+		t.Fatalf("expected a body over MaxBody not to be stored")
+	}
+
+	u.body = "small"
+	get(t, trans, s.URL+"/small")
+	if _, ok := cache.Get(DefaultKey(httptest.NewRequest("GET", s.URL+"/small", nil))); !ok {
+		t.Fatalf("expected a body within MaxBody to be stored")
+	}
+}
@@ -0,0 +1,116 @@
+package degrade
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// KeyFunc derives the Cache key of a request.
+type KeyFunc func(*http.Request) string
+
+// DefaultKey keys requests by method and URL.
+func DefaultKey(req *http.Request) string {
+	return req.Method + " " + req.URL.String()
+}
+
+// Keyed is a request being keyed by the Rules of Key.
+type Keyed struct {
+	// Request is the original request, which Rules must not modify.
+	Request *http.Request
+
+	// URL is a copy of the request URL normalized by the Rules.
+	URL url.URL
+
+	// Parts are added to the key after the URL.
+	Parts []string
+}
+
+// Rule normalizes a request before keying.
+type Rule func(*Keyed)
+
+// Key returns a KeyFunc applying rules in order before keying requests by
+// method, normalized URL and the added parts.
+func Key(rules ...Rule) KeyFunc {
+	return func(req *http.Request) string {
+		k := Keyed{Request: req, URL: *req.URL}
+		for _, rule := range rules {
+			rule(&k)
+		}
+
+		key := req.Method + " " + k.URL.String()
+		for _, part := range k.Parts {
+			key += " " + part
+		}
+		return key
+	}
+}
+
+// IgnoreQuery removes the query parameters with names, like tracking or
+// cache busting parameters, from the key.
+func IgnoreQuery(names ...string) Rule {
+	return func(k *Keyed) {
+		q := k.URL.Query()
+		for _, name := range names {
+			q.Del(name)
+		}
+		k.URL.RawQuery = q.Encode()
+	}
+}
+
+// KeepQuery removes all query parameters but the ones with names.
+func KeepQuery(names ...string) Rule {
+	return func(k *Keyed) {
+		q := k.URL.Query()
+		for name := range q {
+			if !contains(names, name) {
+				q.Del(name)
+			}
+		}
+		k.URL.RawQuery = q.Encode()
+	}
+}
+
+// SortQuery orders the query parameters, so that their order does not
+// fragment the cache.
+func SortQuery() Rule {
+	return func(k *Keyed) {
+		k.URL.RawQuery = k.URL.Query().Encode()
+	}
+}
+
+// LowercaseHost ignores the case of the host.
+func LowercaseHost() Rule {
+	return func(k *Keyed) {
+		k.URL.Host = strings.ToLower(k.URL.Host)
+	}
+}
+
+// TrimSlash ignores trailing slashes of the path.
+func TrimSlash() Rule {
+	return func(k *Keyed) {
+		if len(k.URL.Path) > 1 {
+			k.URL.Path = strings.TrimRight(k.URL.Path, "/")
+			k.URL.RawPath = ""
+		}
+	}
+}
+
+// Header adds the values of the request headers with names, like a tenant
+// or language, so that their responses do not collide.
+func Header(names ...string) Rule {
+	return func(k *Keyed) {
+		for _, name := range names {
+			k.Parts = append(k.Parts, http.CanonicalHeaderKey(name)+"="+strings.Join(k.Request.Header.Values(name), ","))
+		}
+	}
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
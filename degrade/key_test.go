@@ -0,0 +1,62 @@
+package degrade
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestKeyRules(t *testing.T) {
+	key := Key(LowercaseHost(), TrimSlash(), IgnoreQuery("utm_source", "_"), SortQuery(), Header("X-Tenant"))
+
+	request := func(url, tenant string) *http.Request {
+		req, _ := http.NewRequest("GET", url, nil)
+		if tenant != "" {
+			req.Header.Set("X-Tenant", tenant)
+		}
+		return req
+	}
+
+	base := key(request("http://api/items?b=2&a=1", "acme"))
+	if want := "GET http://api/items?a=1&b=2 X-Tenant=acme"; want != base {
+		t.Fatalf("expected key %q, got %q", want, base)
+	}
+
+	for _, url := range []string{
+		"http://API/items/?a=1&b=2",
+		"http://api/items?a=1&utm_source=mail&b=2&_=1234",
+	} {
+		if got := key(request(url, "acme")); base != got {
+			t.Errorf("%s: expected to collide with %q, got %q", url, base, got)
+		}
+	}
+
+	if got := key(request("http://api/items?a=1&b=2", "globex")); base == got {
+		t.Errorf("expected tenants not to collide")
+	}
+}
+
+func TestKeepQuery(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://api/items?page=2&session=abc", nil)
+	if want, got := "GET http://api/items?page=2", Key(KeepQuery("page"))(req); want != got {
+		t.Fatalf("expected key %q, got %q", want, got)
+	}
+}
+
+func TestTransportUsesKey(t *testing.T) {
+	u := &upstream{status: 200, body: "live"}
+	s := httptest.NewServer(u)
+	defer s.Close()
+
+	trans := &Transport{
+		Cache: &MemoryCache{},
+		Key:   Key(IgnoreQuery("cb")),
+	}
+
+	get(t, trans, s.URL+"/items?cb=1")
+	u.status, u.body = 503, "unavailable"
+
+	if _, body := get(t, trans, s.URL+"/items?cb=2"); body != "live" {
+		t.Fatalf("expected stale body for the normalized key, got: %q", body)
+	}
+}
@@ -0,0 +1,158 @@
+/*
+Package dial provides dialers for http.Transport.DialContext that choose how
+to reach an upstream by attempt, so that retries can leave through another
+source address or network interface on multi-homed hosts.
+
+The attempt is read from the request context with retry.AttemptFrom, so the
+dialers take effect when used below a retry.Transport.  Connections are
+pooled by upstream rather than by source, so an attempt may reuse an idle
+connection dialed for an earlier one.
+*/
+package dial
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/streadway/handy/retry"
+)
+
+// ContextDialer has the signature of http.Transport.DialContext.
+type ContextDialer func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// Source is a local endpoint to dial from, either an IP address or the
+// address of a network interface.
+type Source struct {
+	IP        net.IP
+	Interface string
+}
+
+func (s Source) String() string {
+	if s.Interface != "" {
+		return s.Interface
+	}
+	return s.IP.String()
+}
+
+// ip returns the address of the source matching the IPv4 or IPv6 family.
+func (s Source) ip(v4 bool) (net.IP, error) {
+	if s.Interface == "" {
+		return s.IP, nil
+	}
+
+	iface, err := net.InterfaceByName(s.Interface)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+
+	var fallback net.IP
+	for _, a := range addrs {
+		n, ok := a.(*net.IPNet)
+		if !ok || n.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		if (n.IP.To4() != nil) == v4 {
+			return n.IP, nil
+		}
+		if fallback == nil {
+			fallback = n.IP
+		}
+	}
+	if fallback == nil {
+		return nil, fmt.Errorf("dial: no address on interface %s", s.Interface)
+	}
+	return fallback, nil
+}
+
+// Policy chooses the index of the source for an attempt, counting from 1,
+// among n sources.
+type Policy func(attempt uint, n int) int
+
+// PerAttempt switches to the next source on every attempt.
+func PerAttempt() Policy {
+	return func(attempt uint, n int) int {
+		return int((attempt - 1) % uint(n))
+	}
+}
+
+// After keeps the first source for the first attempts, then switches to the
+// next source on every further attempt.
+func After(attempts uint) Policy {
+	return func(attempt uint, n int) int {
+		if attempt <= attempts {
+			return 0
+		}
+		return int((attempt - attempts) % uint(n))
+	}
+}
+
+// Sources is a dialer leaving from the source chosen by Policy for the
+// attempt of the request.
+type Sources struct {
+	// Sources to dial from.  If empty, the system chooses.
+	Sources []Source
+
+	// Policy chooses the source by attempt, PerAttempt when nil.
+	Policy Policy
+
+	// Dialer dials the connections, a zero net.Dialer when nil.  Its
+	// LocalAddr is replaced by the chosen source.
+	Dialer *net.Dialer
+}
+
+// DialContext dials addr from the source chosen for the attempt of the
+// request in ctx.
+func (s Sources) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	var d net.Dialer
+	if s.Dialer != nil {
+		d = *s.Dialer
+	}
+
+	if len(s.Sources) == 0 {
+		return d.DialContext(ctx, network, addr)
+	}
+
+	policy := s.Policy
+	if policy == nil {
+		policy = PerAttempt()
+	}
+
+	i := policy(retry.AttemptFrom(ctx), len(s.Sources))
+	if i < 0 || i >= len(s.Sources) {
+		return nil, fmt.Errorf("dial: policy chose source %d of %d", i, len(s.Sources))
+	}
+	source := s.Sources[i]
+
+	ip, err := source.ip(network != "tcp6" && network != "udp6")
+	if err != nil {
+		return nil, err
+	}
+
+	// Restrict the remote address to the family of the source
+	switch network {
+	case "tcp", "udp":
+		if ip.To4() != nil {
+			network += "4"
+		} else {
+			network += "6"
+		}
+	}
+
+	switch network {
+	case "udp4", "udp6":
+		d.LocalAddr = &net.UDPAddr{IP: ip}
+	default:
+		d.LocalAddr = &net.TCPAddr{IP: ip}
+	}
+
+	conn, err := d.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("dial from %s: %w", source, err)
+	}
+	return conn, nil
+}
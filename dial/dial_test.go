@@ -0,0 +1,86 @@
+package dial
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/streadway/handy/retry"
+)
+
+func TestPolicies(t *testing.T) {
+	for name, test := range map[string]struct {
+		policy Policy
+		want   []int
+	}{
+		"per attempt": {PerAttempt(), []int{0, 1, 2, 0, 1}},
+		"after 2":     {After(2), []int{0, 0, 1, 2, 0}},
+	} {
+		var got []int
+		for attempt := uint(1); attempt <= 5; attempt++ {
+			got = append(got, test.policy(attempt, 3))
+		}
+		if fmt.Sprint(test.want) != fmt.Sprint(got) {
+			t.Errorf("%s: want %v, got %v", name, test.want, got)
+		}
+	}
+}
+
+func TestSourcesSwitchOnRetry(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		remotes []string
+	)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		host, _, _ := net.SplitHostPort(r.RemoteAddr)
+		remotes = append(remotes, host)
+		if len(remotes) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer s.Close()
+
+	sources := Sources{Sources: []Source{
+		{IP: net.ParseIP("127.0.0.1")},
+		{IP: net.ParseIP("127.0.0.2")},
+	}}
+
+	client := retry.NewClient(
+		retry.WithDialContext(sources.DialContext),
+		retry.WithConstantBackoff(0),
+	)
+	client.Transport.(*retry.Transport).Next.(*http.Transport).DisableKeepAlives = true
+
+	resp, err := client.Get(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if want, got := "127.0.0.1 127.0.0.2", strings.Join(remotes, " "); want != got {
+		t.Fatalf("expected attempts from %s, got %s", want, got)
+	}
+}
+
+func TestSourcesReportsSource(t *testing.T) {
+	sources := Sources{Sources: []Source{{Interface: "no-such-interface"}}}
+
+	_, err := sources.DialContext(context.Background(), "tcp", "127.0.0.1:1")
+	if err == nil {
+		t.Fatalf("expected an unknown interface to fail")
+	}
+
+	sources = Sources{Sources: []Source{{IP: net.ParseIP("127.0.0.1")}}}
+	_, err = sources.DialContext(context.Background(), "tcp", "127.0.0.1:1")
+	if err == nil || !strings.Contains(err.Error(), "dial from 127.0.0.1") {
+		t.Fatalf("expected the source in the error, got: %v", err)
+	}
+}
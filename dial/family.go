@@ -0,0 +1,170 @@
+package dial
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/streadway/handy/retry"
+)
+
+// Defaults used by Families when the corresponding field is zero.
+const (
+	DefaultFamilyThreshold = 2
+	DefaultFamilyHold      = 5 * time.Minute
+)
+
+// Families is a dialer falling back to the other IP family of a host after
+// repeated connect failures over one family, as broken IPv6 paths are a
+// common cause of flaky clients.  Retried attempts to a host that fell back
+// are dialed over the other family only, until the Hold passes.
+type Families struct {
+	// Threshold is the number of consecutive connect failures over one
+	// family of a host before falling back, DefaultFamilyThreshold when
+	// zero.
+	Threshold int
+
+	// Hold is the time the fallback lasts, DefaultFamilyHold when zero.
+	Hold time.Duration
+
+	// Next dials the connections, a zero net.Dialer when nil.
+	Next ContextDialer
+
+	mu    sync.Mutex
+	hosts map[string]*family
+}
+
+type family struct {
+	failures [2]int // consecutive connect failures over IPv4 and IPv6
+	forced   string // "4" or "6" while falling back
+	until    time.Time
+}
+
+// DialContext dials addr, over the fallback family of its host on retried
+// attempts.
+func (f *Families) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	next := f.Next
+	if next == nil {
+		next = (&net.Dialer{}).DialContext
+	}
+
+	if network != "tcp" && network != "udp" {
+		return next(ctx, network, addr)
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return next(ctx, network, addr)
+	}
+
+	if forced := f.Forced(host); forced != "" && retry.AttemptFrom(ctx) > 1 {
+		network += forced
+	}
+
+	conn, err := next(ctx, network, addr)
+	f.record(host, conn, err)
+	return conn, err
+}
+
+// Forced returns "4" or "6" while host falls back to IPv4 or IPv6, or "".
+func (f *Families) Forced(host string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	h := f.hosts[host]
+	if h == nil || h.forced == "" {
+		return ""
+	}
+	if !time.Now().Before(h.until) {
+		h.forced = ""
+		return ""
+	}
+	return h.forced
+}
+
+// Retryer retries attempts that failed to connect to a host now falling
+// back to the other family, and ignores all others.  Combine it with
+// retry.All or retry.Limit to bound the attempts.
+func (f *Families) Retryer() retry.Retryer {
+	return func(a retry.Attempt) (retry.Decision, error) {
+		var op *net.OpError
+		if a.Err == nil || !errors.As(a.Err, &op) || op.Op != "dial" {
+			return retry.Ignore, nil
+		}
+		if f.Forced(a.Request.URL.Hostname()) == "" {
+			return retry.Ignore, nil
+		}
+		return retry.Retry, nil
+	}
+}
+
+// record counts the connect failures by family.
+func (f *Families) record(host string, conn net.Conn, err error) {
+	var ip net.IP
+	if err == nil {
+		ip = addrIP(conn.RemoteAddr())
+	} else if op := (*net.OpError)(nil); errors.As(err, &op) {
+		ip = addrIP(op.Addr)
+	}
+	if ip == nil {
+		return
+	}
+
+	i := 0
+	if ip.To4() == nil {
+		i = 1
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.hosts == nil {
+		f.hosts = make(map[string]*family)
+	}
+	h := f.hosts[host]
+	if h == nil {
+		h = &family{}
+		f.hosts[host] = h
+	}
+
+	if err == nil {
+		h.failures[i] = 0
+		return
+	}
+
+	h.failures[i]++
+
+	threshold := f.Threshold
+	if threshold <= 0 {
+		threshold = DefaultFamilyThreshold
+	}
+	if h.failures[i] < threshold {
+		return
+	}
+
+	h.failures[i] = 0
+	if h.forced != "" && h.forced == [2]string{"4", "6"}[i] {
+		// The fallback fails as well, leave the choice to the dialer again
+		h.forced = ""
+		return
+	}
+
+	hold := f.Hold
+	if hold <= 0 {
+		hold = DefaultFamilyHold
+	}
+	h.forced = [2]string{"6", "4"}[i]
+	h.until = time.Now().Add(hold)
+}
+
+func addrIP(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return a.IP
+	case *net.UDPAddr:
+		return a.IP
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package dial
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/streadway/handy/retry"
+)
+
+// brokenIPv6 fails to connect over IPv6 and records the dialed networks.
+type brokenIPv6 struct {
+	networks []string
+}
+
+func (d *brokenIPv6) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	d.networks = append(d.networks, network)
+	if network == "tcp4" {
+		client, server := net.Pipe()
+		server.Close()
+		return remoteConn{client, &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 443}}, nil
+	}
+	return nil, &net.OpError{
+		Op:   "dial",
+		Net:  network,
+		Addr: &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 443},
+		Err:  errors.New("network is unreachable"),
+	}
+}
+
+type remoteConn struct {
+	net.Conn
+	remote net.Addr
+}
+
+func (c remoteConn) RemoteAddr() net.Addr { return c.remote }
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// attemptContext returns the context a retry.Transport passes to the
+// attempt numbered n.
+func attemptContext(n uint) context.Context {
+	var ctx context.Context
+	req, _ := http.NewRequest("GET", "http://example/", nil)
+	retry.Transport{
+		Retry: retry.Limit(retry.Errors(), retry.Max(n)),
+		Next: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			ctx = r.Context()
+			return nil, errors.New("next")
+		}),
+	}.RoundTrip(req)
+	return ctx
+}
+
+func TestFamiliesFallBackAfterThreshold(t *testing.T) {
+	var (
+		next     = &brokenIPv6{}
+		families = &Families{Next: next.DialContext}
+	)
+
+	dial := func(attempt uint) error {
+		conn, err := families.DialContext(attemptContext(attempt), "tcp", "example:443")
+		if conn != nil {
+			conn.Close()
+		}
+		return err
+	}
+
+	if err := dial(1); err == nil {
+		t.Fatalf("expected the first IPv6 connect to fail")
+	}
+	if forced := families.Forced("example"); forced != "" {
+		t.Fatalf("expected no fallback below the threshold, got %q", forced)
+	}
+	if err := dial(2); err == nil {
+		t.Fatalf("expected the second IPv6 connect to fail")
+	}
+	if want, got := "4", families.Forced("example"); want != got {
+		t.Fatalf("expected to fall back to IPv%s, got %q", want, got)
+	}
+	if err := dial(3); err != nil {
+		t.Fatalf("expected the retried attempt to connect over IPv4, got: %v", err)
+	}
+	if err := dial(1); err == nil {
+		t.Fatalf("expected first attempts to be left to the dialer")
+	}
+
+	if want, got := []string{"tcp", "tcp", "tcp4", "tcp"}, next.networks; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expected networks %v, got %v", want, got)
+	}
+}
+
+func TestFamiliesRetryer(t *testing.T) {
+	var (
+		next     = &brokenIPv6{}
+		families = &Families{Next: next.DialContext, Threshold: 1}
+		req, _   = http.NewRequest("GET", "https://example/", nil)
+	)
+
+	_, err := families.DialContext(context.Background(), "tcp", "example:443")
+
+	decision, _ := families.Retryer()(retry.Attempt{Count: 1, Err: err, Request: req})
+	if want, got := retry.Retry, decision; want != got {
+		t.Fatalf("expected to retry a connect failure after falling back, got %v", got)
+	}
+
+	decision, _ = families.Retryer()(retry.Attempt{Count: 1, Err: errors.New("other"), Request: req})
+	if want, got := retry.Ignore, decision; want != got {
+		t.Fatalf("expected to ignore other errors, got %v", got)
+	}
+}
@@ -0,0 +1,89 @@
+package dial
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"syscall"
+
+	"github.com/streadway/handy/reqctx"
+)
+
+// Class is the quality of service outbound connections are marked with.
+// The zero Class leaves connections unmarked.
+type Class struct {
+	// DSCP is the Differentiated Services Code Point, from 0 to 63, set in
+	// the TOS or traffic class of the IP header.
+	DSCP uint8
+
+	// Priority is the socket priority queuing the packets on the host.
+	// Values above 6 require CAP_NET_ADMIN.
+	Priority int
+}
+
+// Request classes with the conventional code points.
+var (
+	Critical = Class{DSCP: 46, Priority: 6} // Expedited Forwarding
+	Standard = Class{}
+	Bulk     = Class{DSCP: 8, Priority: 1} // Class Selector 1
+)
+
+var classKey = reqctx.NewKey[Class]("dial.Class")
+
+// WithClass returns a context marking the connections dialed for requests
+// with ctx with class.
+func WithClass(ctx context.Context, class Class) context.Context {
+	return classKey.With(ctx, class)
+}
+
+// ClassFrom returns the Class carried by ctx.
+func ClassFrom(ctx context.Context) (Class, bool) {
+	return classKey.Value(ctx)
+}
+
+// QoS is a dialer marking the outbound connections with the Class of the
+// request context.  Marking is only supported on Linux, elsewhere
+// connections are dialed unmarked.
+//
+// Connections are pooled by upstream rather than by class, so requests
+// should use separate transports per class when marking matters, for
+// example through retry.WithDialContext.
+type QoS struct {
+	// Default is the Class of requests without one in their context.
+	Default Class
+
+	// Dialer dials the connections, a zero net.Dialer when nil.  Its Control
+	// is called before marking.
+	Dialer *net.Dialer
+}
+
+// DialContext dials addr with the socket options of the request class.
+func (q QoS) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	var d net.Dialer
+	if q.Dialer != nil {
+		d = *q.Dialer
+	}
+
+	class, ok := ClassFrom(ctx)
+	if !ok {
+		class = q.Default
+	}
+
+	if class != (Class{}) {
+		if class.DSCP > 63 {
+			return nil, fmt.Errorf("dial: invalid DSCP %d", class.DSCP)
+		}
+
+		control := d.Control
+		d.Control = func(network, address string, c syscall.RawConn) error {
+			if control != nil {
+				if err := control(network, address, c); err != nil {
+					return err
+				}
+			}
+			return mark(network, c, class)
+		}
+	}
+
+	return d.DialContext(ctx, network, addr)
+}
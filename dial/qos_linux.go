@@ -0,0 +1,34 @@
+package dial
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+)
+
+// mark sets the TOS or traffic class and the priority of the socket.
+func mark(network string, c syscall.RawConn, class Class) error {
+	var err error
+	cerr := c.Control(func(fd uintptr) {
+		tos := int(class.DSCP) << 2
+		if strings.HasSuffix(network, "6") {
+			err = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6, syscall.IPV6_TCLASS, tos)
+		} else {
+			err = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_TOS, tos)
+		}
+		if err != nil {
+			err = fmt.Errorf("dial: set DSCP %d: %w", class.DSCP, err)
+			return
+		}
+
+		if class.Priority != 0 {
+			if err = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_PRIORITY, class.Priority); err != nil {
+				err = fmt.Errorf("dial: set priority %d: %w", class.Priority, err)
+			}
+		}
+	})
+	if cerr != nil {
+		return cerr
+	}
+	return err
+}
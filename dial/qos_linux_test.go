@@ -0,0 +1,41 @@
+package dial
+
+import (
+	"context"
+	"net"
+	"syscall"
+	"testing"
+)
+
+func TestQoSMarksSocket(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	ctx := WithClass(context.Background(), Critical)
+	conn, err := (QoS{}).DialContext(ctx, "tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	raw, err := conn.(*net.TCPConn).SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tos, priority int
+	raw.Control(func(fd uintptr) {
+		tos, _ = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_TOS)
+		priority, _ = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_PRIORITY)
+	})
+
+	if want, got := 46<<2, tos; want != got {
+		t.Fatalf("expected TOS %#x, got %#x", want, got)
+	}
+	if want, got := 6, priority; want != got {
+		t.Fatalf("expected priority %d, got %d", want, got)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package dial
+
+import "syscall"
+
+// mark leaves the socket unmarked outside of Linux.
+func mark(network string, c syscall.RawConn, class Class) error {
+	return nil
+}
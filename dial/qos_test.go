@@ -0,0 +1,39 @@
+package dial
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+func TestClassFromContext(t *testing.T) {
+	if _, ok := ClassFrom(context.Background()); ok {
+		t.Fatalf("expected no class without WithClass")
+	}
+
+	class, ok := ClassFrom(WithClass(context.Background(), Critical))
+	if !ok || class != Critical {
+		t.Fatalf("expected the Critical class, got %+v", class)
+	}
+}
+
+func TestQoSRejectsInvalidDSCP(t *testing.T) {
+	ctx := WithClass(context.Background(), Class{DSCP: 64})
+	if _, err := (QoS{}).DialContext(ctx, "tcp", "127.0.0.1:1"); err == nil {
+		t.Fatalf("expected an invalid DSCP to fail")
+	}
+}
+
+func TestQoSDials(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	conn, err := (QoS{Default: Bulk}).DialContext(context.Background(), "tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+}
@@ -0,0 +1,220 @@
+// Copyright (c) 2013, SoundCloud Ltd.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the README file.
+// Source code and contact info at http://github.com/streadway/handy
+
+package encoding
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Config parameterizes the Compressor middleware.
+type Config struct {
+	// Level is the compression level, gzip.DefaultCompression when zero.
+	Level int
+
+	// MinSize is the number of bytes a response must have before it is
+	// compressed.  Smaller responses are written unaltered.
+	MinSize int
+
+	// Types are the media types to compress, matched as substrings of the
+	// response Content-Type like GzipTypes.  When empty, all types are
+	// compressed.
+	Types []string
+}
+
+// Compressor returns a composable middleware compressing responses with gzip
+// or deflate, whichever the request accepts in its Accept-Encoding header,
+// preferring gzip.  Responses are buffered up to Config.MinSize bytes to
+// decide whether to compress them.  It panics when Config.Level is not a
+// valid compression level.
+func Compressor(cfg Config) func(http.Handler) http.Handler {
+	if cfg.Level == 0 {
+		cfg.Level = gzip.DefaultCompression
+	}
+	if _, err := gzip.NewWriterLevel(io.Discard, cfg.Level); err != nil {
+		panic("encoding: " + err.Error())
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			encoding := negotiate(r.Header.Get("Accept-Encoding"))
+			if encoding == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{ResponseWriter: w, cfg: cfg, encoding: encoding, code: http.StatusOK}
+			defer cw.Close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// negotiate picks gzip or deflate from an Accept-Encoding header, or returns
+// an empty string when neither is acceptable.
+func negotiate(accept string) string {
+	q := map[string]float64{}
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(fields[0]))
+		weight := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if w, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					weight = w
+				}
+			}
+		}
+		q[coding] = weight
+	}
+
+	for _, coding := range []string{"gzip", "deflate"} {
+		weight, ok := q[coding]
+		if !ok {
+			weight, ok = q["*"]
+		}
+		if ok && weight > 0 {
+			return coding
+		}
+	}
+	return ""
+}
+
+type compressWriter struct {
+	http.ResponseWriter
+	cfg      Config
+	encoding string
+	code     int
+
+	buf     bytes.Buffer
+	decided bool
+	out     io.Writer
+	closer  io.Closer
+	err     error
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.decided {
+		return
+	}
+	// Informational responses precede the final one
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.code = code
+	if code < 200 || code == http.StatusNoContent || code == http.StatusNotModified || !w.compressible() {
+		w.decide(false)
+	}
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		if !w.compressible() {
+			w.decide(false)
+		} else {
+			w.buf.Write(b)
+			if w.buf.Len() >= w.cfg.MinSize {
+				w.decide(true)
+			}
+			return len(b), w.err
+		}
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+	return w.out.Write(b)
+}
+
+// compressible checks the headers set so far by the handler.
+func (w *compressWriter) compressible() bool {
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+	if len(w.cfg.Types) == 0 {
+		return true
+	}
+	contentType := h.Get("Content-Type")
+	for _, mediaType := range w.cfg.Types {
+		if strings.Contains(contentType, mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *compressWriter) decide(compress bool) {
+	w.decided = true
+	w.out = w.ResponseWriter
+
+	if compress {
+		switch w.encoding {
+		case "gzip":
+			gz, err := gzip.NewWriterLevel(w.ResponseWriter, w.cfg.Level)
+			w.out, w.closer, w.err = gz, gz, err
+		case "deflate":
+			zw, err := zlib.NewWriterLevel(w.ResponseWriter, w.cfg.Level)
+			w.out, w.closer, w.err = zw, zw, err
+		}
+		if w.err != nil {
+			return
+		}
+		w.Header().Set("Content-Encoding", w.encoding)
+		w.Header().Del("Content-Length")
+	}
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	w.ResponseWriter.WriteHeader(w.code)
+
+	if w.buf.Len() > 0 {
+		_, w.err = w.out.Write(w.buf.Bytes())
+		w.buf.Reset()
+	}
+}
+
+// Flush compresses the buffered response regardless of its size.
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide(w.compressible())
+	}
+	if f, ok := w.out.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker when the underlying ResponseWriter does.
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		w.decided = true
+		return h.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+func (w *compressWriter) Close() error {
+	if !w.decided {
+		w.decide(false)
+	}
+	if w.err != nil {
+		return w.err
+	}
+	if w.closer != nil {
+		return w.closer.Close()
+	}
+	return nil
+}
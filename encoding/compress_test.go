@@ -0,0 +1,166 @@
+// Copyright (c) 2013, SoundCloud Ltd.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the README file.
+// Source code and contact info at http://github.com/streadway/handy
+
+package encoding
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	for accept, want := range map[string]string{
+		"":                        "",
+		"gzip":                    "gzip",
+		"deflate":                 "deflate",
+		"deflate, gzip":           "gzip",
+		"gzip;q=0, deflate":       "deflate",
+		"*":                       "gzip",
+		"br, identity":            "",
+		"GZIP;q=0.5, deflate;q=1": "gzip",
+	} {
+		if got := negotiate(accept); want != got {
+			t.Errorf("negotiate(%q): want %q, got %q", accept, want, got)
+		}
+	}
+}
+
+func TestCompressorSkipsSmallResponses(t *testing.T) {
+	var (
+		handler = Compressor(Config{MinSize: 1024})(plain("small"))
+		resp    = httptest.NewRecorder()
+		req     = acceptGzip()
+	)
+
+	handler.ServeHTTP(resp, req)
+
+	if got := resp.Header().Get("Content-Encoding"); got != "" {
+		t.Fatalf("expected no content encoding below the minimum size, got: %q", got)
+	}
+	if want, got := "small", resp.Body.String(); want != got {
+		t.Fatalf("expected body %q, got %q", want, got)
+	}
+}
+
+func TestCompressorCompressesLargeResponses(t *testing.T) {
+	msg := strings.Repeat("large ", 512)
+
+	var (
+		handler = Compressor(Config{MinSize: 1024})(plain(msg))
+		resp    = httptest.NewRecorder()
+		req     = acceptGzip()
+	)
+
+	handler.ServeHTTP(resp, req)
+
+	if want, got := http.StatusOK, resp.Code; want != got {
+		t.Fatalf("expected status %d, got %d", want, got)
+	}
+	if want, got := "gzip", resp.Header().Get("Content-Encoding"); want != got {
+		t.Fatalf("expected content encoding %q, got: %q", want, got)
+	}
+	if want, got := msg, decode(t, resp.Body); want != got {
+		t.Fatalf("expected to decompress message")
+	}
+}
+
+func TestCompressorPassesEarlyHints(t *testing.T) {
+	msg := strings.Repeat("missing ", 512)
+
+	s := httptest.NewServer(Compressor(Config{MinSize: 1024})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", "</style.css>; rel=preload")
+		w.WriteHeader(http.StatusEarlyHints)
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, msg)
+	})))
+	defer s.Close()
+
+	var hints []int
+	trace := &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, _ textproto.MIMEHeader) error {
+			hints = append(hints, code)
+			return nil
+		},
+	}
+
+	req, _ := http.NewRequestWithContext(httptrace.WithClientTrace(context.Background(), trace), "GET", s.URL, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if len(hints) != 1 || hints[0] != http.StatusEarlyHints {
+		t.Fatalf("expected the early hints to be passed, got %v", hints)
+	}
+	if want, got := http.StatusNotFound, resp.StatusCode; want != got {
+		t.Fatalf("expected the final status %d, got %d", want, got)
+	}
+	if want, got := "gzip", resp.Header.Get("Content-Encoding"); want != got {
+		t.Fatalf("expected content encoding %q, got: %q", want, got)
+	}
+	if want, got := msg, decode(t, resp.Body); want != got {
+		t.Fatalf("expected to decompress message")
+	}
+}
+
+func TestCompressorDeflate(t *testing.T) {
+	const msg = `{"meaning": 42}`
+
+	var (
+		handler = Compressor(Config{Types: []string{"application/json"}})(json(msg))
+		resp    = httptest.NewRecorder()
+		req     = &http.Request{Header: http.Header{"Accept-Encoding": {"deflate"}}}
+	)
+
+	handler.ServeHTTP(resp, req)
+
+	if want, got := "deflate", resp.Header().Get("Content-Encoding"); want != got {
+		t.Fatalf("expected content encoding %q, got: %q", want, got)
+	}
+
+	zr, err := zlib.NewReader(resp.Body)
+	if err != nil {
+		t.Fatalf("expected a deflate stream, got: %q", err)
+	}
+	var out bytes.Buffer
+	io.Copy(&out, zr)
+	if want, got := msg, out.String(); want != got {
+		t.Fatalf("expected decoded json %q, got %q", want, got)
+	}
+}
+
+func TestCompressorSkipsOtherTypes(t *testing.T) {
+	var (
+		handler = Compressor(Config{Types: []string{"application/json"}})(plain("text"))
+		resp    = httptest.NewRecorder()
+		req     = acceptGzip()
+	)
+
+	handler.ServeHTTP(resp, req)
+
+	if got := resp.Header().Get("Content-Encoding"); got != "" {
+		t.Fatalf("expected no content encoding for other types, got: %q", got)
+	}
+}
+
+func TestCompressorRejectsInvalidLevel(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected an invalid level to panic when building the middleware")
+		}
+	}()
+	Compressor(Config{Level: 42})
+}
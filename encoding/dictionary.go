@@ -0,0 +1,286 @@
+// Copyright (c) 2013, SoundCloud Ltd.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the README file.
+// Source code and contact info at http://github.com/streadway/handy
+
+package encoding
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Defaults used by Dictionaries when the corresponding field is zero.
+const (
+	DefaultMaxDictionaries   = 16
+	DefaultMaxDictionarySize = 1 << 20
+)
+
+const dictionaryHashSize = sha256.Size
+
+// Dictionary-compressed content encodings of Compression Dictionary
+// Transport, brotli and zstd with a header naming the dictionary.
+var dictionaryMagic = map[string][]byte{
+	"dcb": {0xff, 0x44, 0x43, 0x42},
+	"dcz": {0x5e, 0x2a, 0x4d, 0x18, 0x20, 0x00, 0x00, 0x00},
+}
+
+// DictionaryDecoder wraps a response body compressed against dict with a
+// reader of the decoded content.  The dictionary header of the dcb and dcz
+// encodings is verified and removed before the body is passed.
+type DictionaryDecoder func(r io.Reader, dict []byte) (io.ReadCloser, error)
+
+// ErrDictionaryMismatch is returned when a dictionary-compressed response
+// names another dictionary than the one offered.
+var ErrDictionaryMismatch = errors.New("encoding: response compressed with another dictionary")
+
+// Dictionaries stores the responses servers offer as compression
+// dictionaries with the Use-As-Dictionary header, for use by a Transport on
+// later requests matching them.  Support is experimental and follows
+// Compression Dictionary Transport, without expiring dictionaries by their
+// cache freshness.
+type Dictionaries struct {
+	// MaxDictionaries is the number of dictionaries stored, evicting the
+	// oldest, DefaultMaxDictionaries when zero.
+	MaxDictionaries int
+
+	// MaxSize is the largest dictionary stored in bytes,
+	// DefaultMaxDictionarySize when zero.
+	MaxSize int64
+
+	mu      sync.Mutex
+	entries []*dictionary
+}
+
+type dictionary struct {
+	origin string
+	match  string
+	id     string
+	hash   [dictionaryHashSize]byte
+	data   []byte
+}
+
+// header returns the Available-Dictionary value naming d.
+func (d *dictionary) header() string {
+	return ":" + base64.StdEncoding.EncodeToString(d.hash[:]) + ":"
+}
+
+// Len returns the number of dictionaries stored.
+func (ds *Dictionaries) Len() int {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	return len(ds.entries)
+}
+
+// lookup returns the dictionary with the most specific match of u.
+func (ds *Dictionaries) lookup(u *url.URL) *dictionary {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	var best *dictionary
+	for _, d := range ds.entries {
+		if d.origin != origin(u) || !glob(d.match, u.RequestURI()) {
+			continue
+		}
+		if best == nil || len(d.match) >= len(best.match) {
+			best = d
+		}
+	}
+	return best
+}
+
+// store adds data as the dictionary offered by resp with the value of its
+// Use-As-Dictionary header.
+func (ds *Dictionaries) store(u *url.URL, value string, data []byte) {
+	params := parseDictionary(value)
+	if params["type"] != "" && params["type"] != "raw" {
+		return
+	}
+
+	match := params["match"]
+	if match == "" {
+		return
+	}
+	if m, err := u.Parse(match); err != nil || origin(m) != origin(u) {
+		return
+	} else if !strings.HasPrefix(match, "/") {
+		match = m.RequestURI()
+	}
+
+	d := &dictionary{
+		origin: origin(u),
+		match:  match,
+		id:     params["id"],
+		hash:   sha256.Sum256(data),
+		data:   data,
+	}
+
+	max := ds.MaxDictionaries
+	if max <= 0 {
+		max = DefaultMaxDictionaries
+	}
+
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	for i, e := range ds.entries {
+		if e.origin == d.origin && e.match == d.match {
+			ds.entries = append(ds.entries[:i], ds.entries[i+1:]...)
+			break
+		}
+	}
+	ds.entries = append(ds.entries, d)
+	if len(ds.entries) > max {
+		ds.entries = ds.entries[len(ds.entries)-max:]
+	}
+}
+
+func (ds *Dictionaries) maxSize() int64 {
+	if ds.MaxSize <= 0 {
+		return DefaultMaxDictionarySize
+	}
+	return ds.MaxSize
+}
+
+// offer advertises the dictionary matching out and the encodings using it.
+func (t Transport) offer(out *http.Request) *dictionary {
+	if t.Dictionaries == nil || len(t.DictionaryDecoders) == 0 {
+		return nil
+	}
+
+	d := t.Dictionaries.lookup(out.URL)
+	if d == nil {
+		return nil
+	}
+
+	encodings := make([]string, 0, len(t.DictionaryDecoders))
+	for encoding := range t.DictionaryDecoders {
+		encodings = append(encodings, encoding)
+	}
+	sort.Strings(encodings)
+
+	out.Header.Set("Accept-Encoding", strings.Join(encodings, ", ")+", "+out.Header.Get("Accept-Encoding"))
+	out.Header.Set("Available-Dictionary", d.header())
+	if d.id != "" {
+		out.Header.Set("Dictionary-ID", `"`+d.id+`"`)
+	}
+	return d
+}
+
+// decodeDictionary decodes a body compressed against the offered d.
+func (t Transport) decodeDictionary(body io.Reader, encoding string, d *dictionary) (io.ReadCloser, error) {
+	if d == nil {
+		return nil, fmt.Errorf("encoding: %s response without an offered dictionary", encoding)
+	}
+
+	if magic, ok := dictionaryMagic[encoding]; ok {
+		header := make([]byte, len(magic)+dictionaryHashSize)
+		if _, err := io.ReadFull(body, header); err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(header[:len(magic)], magic) || !bytes.Equal(header[len(magic):], d.hash[:]) {
+			return nil, ErrDictionaryMismatch
+		}
+	}
+
+	return t.DictionaryDecoders[encoding](body, d.data)
+}
+
+// dictionaryBody stores the content read to its end as a dictionary.
+type dictionaryBody struct {
+	io.ReadCloser
+	url   *url.URL
+	value string
+	store *Dictionaries
+	buf   bytes.Buffer
+}
+
+func (b *dictionaryBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if b.store != nil {
+		if int64(b.buf.Len()+n) > b.store.maxSize() {
+			b.store = nil
+			b.buf = bytes.Buffer{}
+		} else {
+			b.buf.Write(p[:n])
+		}
+	}
+	if err == io.EOF && b.store != nil {
+		b.store.store(b.url, b.value, b.buf.Bytes())
+		b.store = nil
+	}
+	return n, err
+}
+
+// parseDictionary returns the string and token members of the structured
+// dictionary of a Use-As-Dictionary header.
+func parseDictionary(value string) map[string]string {
+	params := make(map[string]string)
+	for _, member := range splitQuoted(value, ',') {
+		key, val, _ := strings.Cut(strings.TrimSpace(member), "=")
+		if strings.HasPrefix(val, `"`) {
+			val = strings.ReplaceAll(strings.Trim(val, `"`), `\"`, `"`)
+		}
+		params[strings.ToLower(strings.TrimSpace(key))] = val
+	}
+	return params
+}
+
+// splitQuoted splits s at sep outside of quoted strings and lists.
+func splitQuoted(s string, sep byte) []string {
+	var (
+		parts  []string
+		quoted bool
+		depth  int
+		start  int
+	)
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && quoted:
+			i++
+		case c == '"':
+			quoted = !quoted
+		case c == '(' && !quoted:
+			depth++
+		case c == ')' && !quoted:
+			depth--
+		case c == sep && !quoted && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// glob reports whether s matches pattern, where * matches any sequence.
+func glob(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	for i, part := range parts[1:] {
+		if i == len(parts)-2 {
+			return strings.HasSuffix(s, part)
+		}
+		j := strings.Index(s, part)
+		if j < 0 {
+			return false
+		}
+		s = s[j+len(part):]
+	}
+	return s == ""
+}
+
+func origin(u *url.URL) string {
+	return strings.ToLower(u.Scheme + "://" + u.Host)
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2013, SoundCloud Ltd.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the README file.
+// Source code and contact info at http://github.com/streadway/handy
+
+package encoding
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// appendDictionary decodes bodies holding the content following the
+// dictionary.
+func appendDictionary(r io.Reader, dict []byte) (io.ReadCloser, error) {
+	return ioutil.NopCloser(io.MultiReader(strings.NewReader(string(dict)), r)), nil
+}
+
+func dictionaryServer(t *testing.T, available *[]string) *httptest.Server {
+	const version1 = "version 1 of the catalog"
+	hash := sha256.Sum256([]byte(version1))
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		offered := r.Header.Get("Available-Dictionary")
+		*available = append(*available, offered)
+
+		if offered != ":"+base64.StdEncoding.EncodeToString(hash[:])+":" || !strings.Contains(r.Header.Get("Accept-Encoding"), "dcb") {
+			w.Header().Set("Use-As-Dictionary", `match="/catalog*", id="v1"`)
+			w.Write([]byte(version1))
+			return
+		}
+
+		if want, got := `"v1"`, r.Header.Get("Dictionary-ID"); want != got {
+			t.Errorf("expected Dictionary-ID %s, got %s", want, got)
+		}
+
+		w.Header().Set("Content-Encoding", "dcb")
+		w.Write(dictionaryMagic["dcb"])
+		w.Write(hash[:])
+		w.Write([]byte(", amended"))
+	}))
+}
+
+func get(t *testing.T, c http.Client, url string) string {
+	resp, err := c.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	return string(body)
+}
+
+func TestTransportUsesDictionaries(t *testing.T) {
+	var available []string
+	srv := dictionaryServer(t, &available)
+	defer srv.Close()
+
+	dicts := &Dictionaries{}
+	c := http.Client{Transport: Transport{
+		Dictionaries:       dicts,
+		DictionaryDecoders: map[string]DictionaryDecoder{"dcb": appendDictionary},
+	}}
+
+	if want, got := "version 1 of the catalog", get(t, c, srv.URL+"/catalog"); want != got {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+	if want, got := 1, dicts.Len(); want != got {
+		t.Fatalf("expected %d stored dictionary, got %d", want, got)
+	}
+
+	if want, got := "version 1 of the catalog, amended", get(t, c, srv.URL+"/catalog?page=2"); want != got {
+		t.Fatalf("expected the dictionary to be applied, got %q", got)
+	}
+
+	get(t, c, srv.URL+"/other")
+	if want, got := "", available[2]; want != got {
+		t.Fatalf("expected no dictionary offered for unmatched paths, got %q", got)
+	}
+}
+
+func TestTransportWithoutDictionaryDecodersFallsBack(t *testing.T) {
+	var available []string
+	srv := dictionaryServer(t, &available)
+	defer srv.Close()
+
+	dicts := &Dictionaries{}
+	c := http.Client{Transport: Transport{Dictionaries: dicts}}
+
+	get(t, c, srv.URL+"/catalog")
+	if want, got := "version 1 of the catalog", get(t, c, srv.URL+"/catalog"); want != got {
+		t.Fatalf("expected the full response, got %q", got)
+	}
+	if want, got := "", available[1]; want != got {
+		t.Fatalf("expected no dictionary offered without decoders, got %q", got)
+	}
+}
+
+func TestTransportRejectsOtherDictionary(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Use-As-Dictionary", `match="/*"`)
+		if r.Header.Get("Available-Dictionary") != "" {
+			w.Header().Set("Content-Encoding", "dcb")
+			w.Write(dictionaryMagic["dcb"])
+			w.Write(make([]byte, sha256.Size))
+		}
+		w.Write([]byte("content"))
+	}))
+	defer srv.Close()
+
+	c := http.Client{Transport: Transport{
+		Dictionaries:       &Dictionaries{},
+		DictionaryDecoders: map[string]DictionaryDecoder{"dcb": appendDictionary},
+	}}
+
+	get(t, c, srv.URL)
+	if _, err := c.Get(srv.URL); err == nil || !strings.Contains(err.Error(), ErrDictionaryMismatch.Error()) {
+		t.Fatalf("expected a dictionary mismatch, got: %v", err)
+	}
+}
+
+func TestParseDictionary(t *testing.T) {
+	params := parseDictionary(`match="/a,b/*", match-dest=("document" "frame"), id="x\"y", type=raw`)
+
+	for key, want := range map[string]string{
+		"match": "/a,b/*",
+		"id":    `x"y`,
+		"type":  "raw",
+	} {
+		if got := params[key]; want != got {
+			t.Errorf("expected %s %q, got %q", key, want, got)
+		}
+	}
+}
+
+func TestGlob(t *testing.T) {
+	for _, test := range []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"/catalog*", "/catalog?page=2", true},
+		{"/catalog", "/catalog/1", false},
+		{"/a/*/c", "/a/b/c", true},
+		{"/a/*/c", "/a/b/d", false},
+		{"*", "/", true},
+	} {
+		if got := glob(test.pattern, test.s); test.want != got {
+			t.Errorf("glob(%q, %q): want %v, got %v", test.pattern, test.s, test.want, got)
+		}
+	}
+}
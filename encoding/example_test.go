@@ -0,0 +1,35 @@
+package encoding_test
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/streadway/handy/encoding"
+)
+
+// newZstdReader stands for a zstd implementation outside of the standard
+// library, like github.com/klauspost/compress/zstd:
+//
+//	d, err := zstd.NewReader(r)
+//	if err != nil {
+//		return nil, err
+//	}
+//	return d.IOReadCloser(), nil
+var newZstdReader encoding.Decoder
+
+func ExampleTransport_zstd() {
+	decoders := map[string]encoding.Decoder{"zstd": newZstdReader}
+	for name, decoder := range encoding.DefaultDecoders {
+		decoders[name] = decoder
+	}
+
+	// Requests accept "gzip, deflate, zstd" and responses are decoded
+	client := &http.Client{Transport: encoding.Transport{Decoders: decoders}}
+
+	resp, err := client.Get("https://example.org/")
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2013, SoundCloud Ltd.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the README file.
+// Source code and contact info at http://github.com/streadway/handy
+
+package encoding
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Decoder wraps a compressed response body with a reader of the decoded
+// content.
+type Decoder func(io.Reader) (io.ReadCloser, error)
+
+// DefaultDecoders decode the gzip and deflate content encodings.  zstd is
+// not decoded by default, as the standard library has no decoder for it;
+// add a Decoder wrapping an implementation to a Transport to support it, as
+// in the Transport zstd example.
+var DefaultDecoders = map[string]Decoder{
+	"gzip": func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	},
+	"deflate": func(r io.Reader) (io.ReadCloser, error) {
+		return zlib.NewReader(r)
+	},
+}
+
+// Transport is an http.RoundTripper that advertises the content encodings it
+// can decode in the Accept-Encoding header and transparently decodes
+// response bodies.  Requests that already carry an Accept-Encoding header are
+// forwarded unaltered, and so are their responses.
+type Transport struct {
+	// Decoders by content encoding.  If Decoders is nil, DefaultDecoders is
+	// used.
+	Decoders map[string]Decoder
+
+	// Dictionaries stores the dictionaries offered by servers.  If nil, or
+	// without DictionaryDecoders, no dictionaries are used.
+	Dictionaries *Dictionaries
+
+	// DictionaryDecoders by dictionary content encoding, like dcb and dcz.
+	// Dictionaries are only offered for the encodings decoded here, so that
+	// servers fall back to the other Decoders.
+	DictionaryDecoders map[string]DictionaryDecoder
+
+	// Next is the http.RoundTripper to which requests are forwarded.  If Next
+	// is nil, http.DefaultTransport is used.
+	Next http.RoundTripper
+}
+
+// RoundTrip implements the RoundTripper interface.
+func (t Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	if req.Header.Get("Accept-Encoding") != "" || req.Method == "HEAD" {
+		return next.RoundTrip(req)
+	}
+
+	decoders := t.Decoders
+	if decoders == nil {
+		decoders = DefaultDecoders
+	}
+
+	out := req.Clone(req.Context())
+	out.Header.Set("Accept-Encoding", acceptEncoding(decoders))
+	dict := t.offer(out)
+
+	resp, err := next.RoundTrip(out)
+	if err != nil {
+		return resp, err
+	}
+
+	if resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified {
+		return resp, nil
+	}
+
+	if err := t.decode(resp, decoders, dict); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	if value := resp.Header.Get("Use-As-Dictionary"); value != "" && t.Dictionaries != nil && resp.StatusCode == http.StatusOK {
+		resp.Body = &dictionaryBody{ReadCloser: resp.Body, url: req.URL, value: value, store: t.Dictionaries}
+	}
+
+	return resp, nil
+}
+
+// decode replaces the body of resp with its decoded content when its
+// encoding is supported.
+func (t Transport) decode(resp *http.Response, decoders map[string]Decoder, dict *dictionary) error {
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+
+	var (
+		decoded io.ReadCloser
+		err     error
+	)
+	if _, ok := t.DictionaryDecoders[encoding]; ok {
+		decoded, err = t.decodeDictionary(resp.Body, encoding, dict)
+	} else if decode, ok := decoders[encoding]; ok {
+		decoded, err = decode(resp.Body)
+	} else {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	resp.Body = &decodedBody{ReadCloser: decoded, raw: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+
+	return nil
+}
+
+// acceptEncoding lists gzip and deflate first, as most servers support them,
+// followed by the remaining encodings in alphabetical order.
+func acceptEncoding(decoders map[string]Decoder) string {
+	rank := map[string]int{"gzip": 0, "deflate": 1}
+	encodings := make([]string, 0, len(decoders))
+	for encoding := range decoders {
+		encodings = append(encodings, encoding)
+	}
+	sort.Slice(encodings, func(i, j int) bool {
+		ri, iok := rank[encodings[i]]
+		rj, jok := rank[encodings[j]]
+		switch {
+		case iok && jok:
+			return ri < rj
+		case iok != jok:
+			return iok
+		}
+		return encodings[i] < encodings[j]
+	})
+	return strings.Join(encodings, ", ")
+}
+
+// decodedBody closes both the decoder and the underlying response body.
+type decodedBody struct {
+	io.ReadCloser
+	raw io.ReadCloser
+}
+
+func (b *decodedBody) Close() error {
+	err := b.ReadCloser.Close()
+	if rawErr := b.raw.Close(); err == nil {
+		err = rawErr
+	}
+	return err
+}
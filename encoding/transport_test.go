@@ -0,0 +1,102 @@
+// Copyright (c) 2013, SoundCloud Ltd.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the README file.
+// Source code and contact info at http://github.com/streadway/handy
+
+package encoding
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTransportDecodes(t *testing.T) {
+	const msg = "the meaning of life, the universe and everything"
+
+	for _, encoding := range []string{"gzip", "deflate"} {
+		t.Run(encoding, func(t *testing.T) {
+			var accepted string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				accepted = r.Header.Get("Accept-Encoding")
+				r.Header.Set("Accept-Encoding", encoding)
+				Compressor(Config{})(plain(msg)).ServeHTTP(w, r)
+			}))
+			defer srv.Close()
+
+			c := http.Client{Transport: Transport{}}
+			resp, err := c.Get(srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if want, got := "gzip, deflate", accepted; want != got {
+				t.Fatalf("expected Accept-Encoding %q, got %q", want, got)
+			}
+			if got := resp.Header.Get("Content-Encoding"); got != "" {
+				t.Fatalf("expected Content-Encoding to be removed, got %q", got)
+			}
+			if body, _ := ioutil.ReadAll(resp.Body); string(body) != msg {
+				t.Fatalf("expected decoded body %q, got %q", msg, body)
+			}
+		})
+	}
+}
+
+func TestTransportCustomDecoder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "upper")
+		w.Write([]byte("SHOUT"))
+	}))
+	defer srv.Close()
+
+	lower := func(r io.Reader) (io.ReadCloser, error) {
+		b, err := ioutil.ReadAll(r)
+		return ioutil.NopCloser(strings.NewReader(strings.ToLower(string(b)))), err
+	}
+
+	c := http.Client{Transport: Transport{Decoders: map[string]Decoder{"upper": lower}}}
+	resp, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if body, _ := ioutil.ReadAll(resp.Body); string(body) != "shout" {
+		t.Fatalf("expected body decoded by the custom decoder, got %q", body)
+	}
+}
+
+func TestTransportAdvertisesZstdDecoder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if want, got := "gzip, deflate, zstd", r.Header.Get("Accept-Encoding"); want != got {
+			t.Errorf("expected Accept-Encoding %q, got %q", want, got)
+		}
+		w.Header().Set("Content-Encoding", "zstd")
+		w.Write([]byte("frame"))
+	}))
+	defer srv.Close()
+
+	decoders := map[string]Decoder{"zstd": func(r io.Reader) (io.ReadCloser, error) {
+		ioutil.ReadAll(r)
+		return ioutil.NopCloser(strings.NewReader("decoded")), nil
+	}}
+	for name, decoder := range DefaultDecoders {
+		decoders[name] = decoder
+	}
+
+	c := http.Client{Transport: Transport{Decoders: decoders}}
+	resp, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if body, _ := ioutil.ReadAll(resp.Body); string(body) != "decoded" {
+		t.Fatalf("expected body decoded by the zstd decoder, got %q", body)
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Package failure defines the classes of request failures shared by the handy
+transports, so that callers can branch on them with errors.Is regardless of
+the transport that failed.
+*/
+package failure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Failure classes.
+var (
+	// ErrConnectTimeout is the failure to establish a connection in time.
+	ErrConnectTimeout = errors.New("connect timeout")
+
+	// ErrTTFBTimeout is the failure to receive the first byte of a response
+	// in time once the request was sent.
+	ErrTTFBTimeout = errors.New("time to first byte timeout")
+
+	// ErrOverallDeadline is the failure to complete a request, including all
+	// its attempts, before its deadline.
+	ErrOverallDeadline = errors.New("overall deadline exceeded")
+
+	// ErrDelayInterrupted is the end of a request while waiting between
+	// attempts.
+	ErrDelayInterrupted = errors.New("delay interrupted")
+
+	// ErrBudgetExhausted is the failure of a request after using up the
+	// attempts it was allowed.
+	ErrBudgetExhausted = errors.New("retry budget exhausted")
+)
+
+// Of returns the failure class of err, or nil when err is not classified.
+// Besides the errors of the handy transports matching a class with
+// errors.Is, dial timeouts are connect timeouts and exceeded context
+// deadlines are overall deadlines.
+func Of(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	for _, class := range []error{ErrConnectTimeout, ErrTTFBTimeout, ErrOverallDeadline, ErrDelayInterrupted, ErrBudgetExhausted} {
+		if errors.Is(err, class) {
+			return class
+		}
+	}
+
+	var op *net.OpError
+	if errors.As(err, &op) && op.Op == "dial" && op.Timeout() {
+		return ErrConnectTimeout
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrOverallDeadline
+	}
+
+	return nil
+}
+
+// Sleep waits for d or until ctx is done, returning an error matching both
+// ErrDelayInterrupted and the error of ctx when interrupted.
+func Sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return Interrupted(err)
+	}
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return Interrupted(ctx.Err())
+	}
+}
+
+// Interrupted returns an error matching both ErrDelayInterrupted and err.
+func Interrupted(err error) error {
+	return fmt.Errorf("%w: %w", ErrDelayInterrupted, err)
+}
@@ -0,0 +1,49 @@
+package failure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+type timeout struct{}
+
+func (timeout) Error() string   { return "i/o timeout" }
+func (timeout) Timeout() bool   { return true }
+func (timeout) Temporary() bool { return true }
+
+func TestOf(t *testing.T) {
+	for name, test := range map[string]struct {
+		err  error
+		want error
+	}{
+		"nil":          {nil, nil},
+		"other":        {errors.New("other"), nil},
+		"wrapped":      {fmt.Errorf("get: %w", ErrBudgetExhausted), ErrBudgetExhausted},
+		"dial timeout": {&net.OpError{Op: "dial", Err: timeout{}}, ErrConnectTimeout},
+		"read timeout": {&net.OpError{Op: "read", Err: timeout{}}, nil},
+		"deadline":     {fmt.Errorf("get: %w", context.DeadlineExceeded), ErrOverallDeadline},
+		"interrupted":  {Interrupted(context.DeadlineExceeded), ErrDelayInterrupted},
+	} {
+		if got := Of(test.err); test.want != got {
+			t.Errorf("%s: want %v, got %v", name, test.want, got)
+		}
+	}
+}
+
+func TestSleepInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(time.Millisecond, cancel)
+
+	err := Sleep(ctx, time.Minute)
+	if !errors.Is(err, ErrDelayInterrupted) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected an interrupted delay, got: %v", err)
+	}
+
+	if err := Sleep(context.Background(), time.Millisecond); err != nil {
+		t.Fatalf("expected an uninterrupted delay, got: %v", err)
+	}
+}
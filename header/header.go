@@ -0,0 +1,99 @@
+/*
+Package header contains utilities to sanitize HTTP headers forwarded by
+proxies and client transports.
+*/
+package header
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// HopByHop lists the headers meaningful only for a single transport-level
+// connection which must not be forwarded by proxies.
+// https://tools.ietf.org/html/rfc7230#section-6.1
+var HopByHop = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// StripHopByHop removes the HopByHop headers and the headers listed in the
+// Connection header from h.  As end-to-end requests, "TE: trailers", needed
+// by gRPC, and the Upgrade of a "Connection: upgrade" are kept.
+func StripHopByHop(h http.Header) {
+	var (
+		trailers = hasToken(h, "Te", "trailers")
+		upgrade  string
+	)
+	if hasToken(h, "Connection", "upgrade") {
+		upgrade = h.Get("Upgrade")
+	}
+
+	for _, connection := range h["Connection"] {
+		for _, name := range strings.Split(connection, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				h.Del(name)
+			}
+		}
+	}
+	for _, name := range HopByHop {
+		h.Del(name)
+	}
+
+	if trailers {
+		h.Set("Te", "trailers")
+	}
+	if upgrade != "" {
+		h.Set("Connection", "Upgrade")
+		h.Set("Upgrade", upgrade)
+	}
+}
+
+// hasToken reports whether the comma separated values of the header contain
+// token, ignoring case and parameters.
+func hasToken(h http.Header, key, token string) bool {
+	for _, value := range h[key] {
+		for _, t := range strings.Split(value, ",") {
+			if i := strings.IndexByte(t, ';'); i >= 0 {
+				t = t[:i]
+			}
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Canonicalize moves the values of keys that are not in canonical form, as
+// set by direct map access, to their canonical key.  Keys are merged in
+// sorted order so the resulting value order is deterministic.
+func Canonicalize(h http.Header) {
+	var keys []string
+	for key := range h {
+		if key != http.CanonicalHeaderKey(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		canonical := http.CanonicalHeaderKey(key)
+		h[canonical] = append(h[canonical], h[key]...)
+		delete(h, key)
+	}
+}
+
+// Sanitize canonicalizes h and strips its hop-by-hop headers.
+func Sanitize(h http.Header) {
+	Canonicalize(h)
+	StripHopByHop(h)
+}
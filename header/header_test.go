@@ -0,0 +1,63 @@
+package header
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestStripHopByHop(t *testing.T) {
+	h := http.Header{
+		"Connection":        {"close, X-Private"},
+		"Keep-Alive":        {"timeout=5"},
+		"Upgrade":           {"websocket"},
+		"Te":                {"gzip"},
+		"X-Private":         {"secret"},
+		"Content-Type":      {"text/plain"},
+		"Transfer-Encoding": {"chunked"},
+	}
+
+	StripHopByHop(h)
+
+	if want := (http.Header{"Content-Type": {"text/plain"}}); !reflect.DeepEqual(want, h) {
+		t.Fatalf("expected only end-to-end headers %v, got %v", want, h)
+	}
+}
+
+func TestStripHopByHopKeepsTrailersAndUpgrade(t *testing.T) {
+	h := http.Header{
+		"Connection": {"keep-alive, Upgrade"},
+		"Upgrade":    {"websocket"},
+		"Te":         {"gzip, trailers;q=1"},
+	}
+
+	StripHopByHop(h)
+
+	want := http.Header{
+		"Connection": {"Upgrade"},
+		"Upgrade":    {"websocket"},
+		"Te":         {"trailers"},
+	}
+	if !reflect.DeepEqual(want, h) {
+		t.Fatalf("expected the trailers and upgrade to be kept %v, got %v", want, h)
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+	h := http.Header{
+		"content-type": {"b"},
+		"CONTENT-TYPE": {"a"},
+		"Content-Type": {"c"},
+		"x-request-id": {"1"},
+	}
+
+	Canonicalize(h)
+
+	want := http.Header{
+		"Content-Type": {"c", "a", "b"},
+		"X-Request-Id": {"1"},
+	}
+	if !reflect.DeepEqual(want, h) {
+		t.Fatalf("expected canonical headers %v, got %v", want, h)
+	}
+}
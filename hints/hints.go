@@ -0,0 +1,116 @@
+/*
+Package hints surfaces the links of 103 Early Hints responses to clients, so
+that they can preconnect or prefetch while waiting for the final response.
+*/
+package hints
+
+import (
+	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
+	"strings"
+)
+
+// Link is a link of a Link header.
+type Link struct {
+	URL    string
+	Rel    string
+	Params map[string]string
+}
+
+// ParseLinks parses the links of Link header values, like
+// `</style.css>; rel=preload; as=style`.
+func ParseLinks(values []string) []Link {
+	var links []Link
+	for _, value := range values {
+		for _, part := range split(value, ',') {
+			part = strings.TrimSpace(part)
+			if !strings.HasPrefix(part, "<") {
+				continue
+			}
+			end := strings.IndexByte(part, '>')
+			if end < 0 {
+				continue
+			}
+
+			link := Link{URL: part[1:end], Params: make(map[string]string)}
+			for _, param := range split(part[end+1:], ';') {
+				key, val, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if key == "" {
+					continue
+				}
+				key = strings.ToLower(strings.TrimSpace(key))
+				val = strings.Trim(strings.TrimSpace(val), `"`)
+				if key == "rel" {
+					link.Rel = val
+				} else {
+					link.Params[key] = val
+				}
+			}
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+// split splits s at sep outside of <> and quotes.
+func split(s string, sep byte) []string {
+	var (
+		parts  []string
+		quoted bool
+		angled bool
+		start  int
+	)
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' && !angled:
+			quoted = !quoted
+		case c == '<' && !quoted:
+			angled = true
+		case c == '>' && !quoted:
+			angled = false
+		case c == sep && !quoted && !angled:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// Transport is an http.RoundTripper passing the links of the Early Hints
+// received for a request to OnHints while the final response is awaited.
+// Traces already in the request context keep receiving the interim
+// responses.
+type Transport struct {
+	// OnHints is called for each 103 response with its links and header.
+	// It must not block, as the final response is read after it returns.
+	OnHints func(req *http.Request, links []Link, header http.Header)
+
+	// Next is the http.RoundTripper to which requests are forwarded.  If Next
+	// is nil, http.DefaultTransport is used.
+	Next http.RoundTripper
+}
+
+// RoundTrip implements the RoundTripper interface.
+func (t Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	if t.OnHints == nil {
+		return next.RoundTrip(req)
+	}
+
+	ctx := httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			if code == http.StatusEarlyHints {
+				h := http.Header(header).Clone()
+				t.OnHints(req, ParseLinks(h.Values("Link")), h)
+			}
+			return nil
+		},
+	})
+
+	return next.RoundTrip(req.WithContext(ctx))
+}
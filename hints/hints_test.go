@@ -0,0 +1,70 @@
+package hints
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
+	"reflect"
+	"testing"
+)
+
+func TestParseLinks(t *testing.T) {
+	links := ParseLinks([]string{
+		`</style.css>; rel=preload; as=style, <https://cdn.example>; rel="preconnect"`,
+		`</a,b.js>; rel=preload; as=script; title="x;y"`,
+	})
+
+	want := []Link{
+		{URL: "/style.css", Rel: "preload", Params: map[string]string{"as": "style"}},
+		{URL: "https://cdn.example", Rel: "preconnect", Params: map[string]string{}},
+		{URL: "/a,b.js", Rel: "preload", Params: map[string]string{"as": "script", "title": "x;y"}},
+	}
+	if !reflect.DeepEqual(want, links) {
+		t.Fatalf("expected links\n%v\ngot\n%v", want, links)
+	}
+}
+
+func TestTransportSurfacesEarlyHints(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Link", "</style.css>; rel=preload; as=style")
+		w.WriteHeader(http.StatusEarlyHints)
+		w.Header().Del("Link")
+		w.Write([]byte("page"))
+	}))
+	defer s.Close()
+
+	var (
+		hinted []Link
+		traced []int
+	)
+	trans := Transport{
+		OnHints: func(req *http.Request, links []Link, header http.Header) {
+			hinted = append(hinted, links...)
+		},
+	}
+
+	req, _ := http.NewRequest("GET", s.URL, nil)
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			traced = append(traced, code)
+			return nil
+		},
+	}))
+
+	resp, err := trans.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if want, got := 200, resp.StatusCode; want != got {
+		t.Fatalf("expected the final status %d, got %d", want, got)
+	}
+	if len(hinted) != 1 || hinted[0].URL != "/style.css" {
+		t.Fatalf("expected the hinted stylesheet, got %v", hinted)
+	}
+	if want, got := []int{103}, traced; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expected the existing trace to receive %v, got %v", want, got)
+	}
+}
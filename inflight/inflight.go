@@ -0,0 +1,182 @@
+/*
+Package inflight tracks in-flight client requests by labels, like route or
+tenant, to cancel groups of them at once.  Requests are labeled with the
+route and tenant of their context, see reqctx.
+*/
+package inflight
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/streadway/handy/reqctx"
+)
+
+// Labels describe a request, like {"tenant": "acme"}.
+type Labels map[string]string
+
+// Labels taken from the values shared with reqctx.
+const (
+	RouteLabel  = "route"
+	TenantLabel = "tenant"
+)
+
+var labelsKey = reqctx.NewKey[Labels]("inflight.Labels")
+
+// WithLabels returns a context carrying the labels used by a Transport
+// without a Labeler.
+func WithLabels(ctx context.Context, labels Labels) context.Context {
+	return labelsKey.With(ctx, labels)
+}
+
+// FromContext returns the labels carried by the context, along with the
+// RouteLabel and TenantLabel of its reqctx.Route and reqctx.Tenant unless
+// labeled otherwise.
+func FromContext(ctx context.Context) Labels {
+	carried, _ := labelsKey.Value(ctx)
+
+	labels := make(Labels, len(carried)+2)
+	if route := reqctx.Route(ctx); route != "" {
+		labels[RouteLabel] = route
+	}
+	if tenant := reqctx.Tenant(ctx); tenant != "" {
+		labels[TenantLabel] = tenant
+	}
+	for k, v := range carried {
+		labels[k] = v
+	}
+	return labels
+}
+
+type entry struct {
+	labels Labels
+	cancel context.CancelFunc
+}
+
+// Registry holds the in-flight requests of one or more Transports, safe for
+// concurrent use.
+type Registry struct {
+	mu       sync.Mutex
+	seq      uint64
+	requests map[uint64]entry
+}
+
+// NewRegistry constructs an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{requests: make(map[uint64]entry)}
+}
+
+func (r *Registry) add(labels Labels, cancel context.CancelFunc) uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	r.requests[r.seq] = entry{labels, cancel}
+	return r.seq
+}
+
+func (r *Registry) remove(id uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.requests, id)
+}
+
+// Cancel cancels the in-flight requests labeled key=value and returns how
+// many were canceled.
+func (r *Registry) Cancel(key, value string) int {
+	r.mu.Lock()
+	var cancels []context.CancelFunc
+	for id, e := range r.requests {
+		if v, ok := e.labels[key]; ok && v == value {
+			cancels = append(cancels, e.cancel)
+			delete(r.requests, id)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, cancel := range cancels {
+		cancel()
+	}
+	return len(cancels)
+}
+
+// Count returns the number of in-flight requests labeled key=value.
+func (r *Registry) Count(key, value string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, e := range r.requests {
+		if v, ok := e.labels[key]; ok && v == value {
+			n++
+		}
+	}
+	return n
+}
+
+// Len returns the number of in-flight requests.
+func (r *Registry) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+// Transport is an http.RoundTripper registering requests in the Registry
+// until their response body is closed.
+type Transport struct {
+	// Registry holds the in-flight requests.
+	Registry *Registry
+
+	// Labeler labels a request.  If Labeler is nil, the labels from the
+	// request context are used.
+	Labeler func(*http.Request) Labels
+
+	// Next is the http.RoundTripper to which requests are forwarded.  If Next
+	// is nil, http.DefaultTransport is used.
+	Next http.RoundTripper
+}
+
+// RoundTrip implements the RoundTripper interface.
+func (t Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	var labels Labels
+	if t.Labeler != nil {
+		labels = t.Labeler(req)
+	} else {
+		labels = FromContext(req.Context())
+	}
+
+	ctx, cancel := context.WithCancel(req.Context())
+	id := t.Registry.add(labels, cancel)
+
+	done := func() {
+		t.Registry.remove(id)
+		cancel()
+	}
+
+	resp, err := next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		done()
+		return resp, err
+	}
+
+	resp.Body = &body{ReadCloser: resp.Body, done: done}
+	return resp, nil
+}
+
+// body deregisters the request once closed.
+type body struct {
+	io.ReadCloser
+	once sync.Once
+	done func()
+}
+
+func (b *body) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.done)
+	return err
+}
@@ -0,0 +1,107 @@
+package inflight
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/streadway/handy/reqctx"
+)
+
+func TestCancelByLabel(t *testing.T) {
+	release := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer s.Close()
+	defer close(release)
+
+	var (
+		registry = NewRegistry()
+		trans    = Transport{Registry: registry}
+		errs     = make(chan error, 2)
+	)
+
+	for _, tenant := range []string{"acme", "other"} {
+		go func(tenant string) {
+			req, _ := http.NewRequest("GET", s.URL, nil)
+			req = req.WithContext(WithLabels(req.Context(), Labels{"tenant": tenant}))
+			_, err := trans.RoundTrip(req)
+			errs <- err
+		}(tenant)
+	}
+
+	for registry.Len() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if want, got := 1, registry.Count("tenant", "acme"); want != got {
+		t.Fatalf("expected %d in-flight requests for acme, got %d", want, got)
+	}
+
+	if want, got := 1, registry.Cancel("tenant", "acme"); want != got {
+		t.Fatalf("expected to cancel %d requests, got %d", want, got)
+	}
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected canceled request, got: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected the canceled request to return")
+	}
+
+	if want, got := 1, registry.Len(); want != got {
+		t.Fatalf("expected %d remaining in-flight requests, got %d", want, got)
+	}
+}
+
+func TestDeregistersOnClose(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer s.Close()
+
+	registry := NewRegistry()
+	trans := Transport{
+		Registry: registry,
+		Labeler:  func(*http.Request) Labels { return Labels{"route": "test"} },
+	}
+
+	req, _ := http.NewRequest("GET", s.URL, nil)
+	resp, err := trans.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := 1, registry.Count("route", "test"); want != got {
+		t.Fatalf("expected %d in-flight requests before close, got %d", want, got)
+	}
+
+	resp.Body.Close()
+
+	if want, got := 0, registry.Len(); want != got {
+		t.Fatalf("expected %d in-flight requests after close, got %d", want, got)
+	}
+}
+
+func TestFromContextLabelsSharedValues(t *testing.T) {
+	ctx := reqctx.WithTenant(reqctx.WithRoute(context.Background(), "/users/{id}"), "acme")
+	ctx = WithLabels(ctx, Labels{"tenant": "override", "shard": "2"})
+
+	labels := FromContext(ctx)
+	for k, want := range map[string]string{
+		RouteLabel:  "/users/{id}",
+		TenantLabel: "override",
+		"shard":     "2",
+	} {
+		if got := labels[k]; want != got {
+			t.Errorf("expected label %s=%q, got %q", k, want, got)
+		}
+	}
+}
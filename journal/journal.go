@@ -0,0 +1,174 @@
+/*
+Package journal writes an append-only journal of outbound requests and their
+final outcomes for audits.
+
+Each record carries the hash of its predecessor, so that removing, reordering
+or altering records breaks the chain detected by Verify.
+*/
+package journal
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Kinds of records.
+const (
+	KindRequest = "request"
+	KindOutcome = "outcome"
+)
+
+var now = time.Now
+
+// Record is a line of the journal.
+type Record struct {
+	Seq  uint64    `json:"seq"`
+	Time time.Time `json:"time"`
+	Kind string    `json:"kind"`
+
+	// Request is the Seq of the request record an outcome belongs to.
+	Request uint64 `json:"request,omitempty"`
+
+	Method string      `json:"method,omitempty"`
+	URL    string      `json:"url,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+
+	Status   int           `json:"status,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Attempts uint          `json:"attempts,omitempty"`
+	Elapsed  time.Duration `json:"elapsed,omitempty"`
+
+	// Prev is the Hash of the previous record, empty for the first.
+	Prev string `json:"prev"`
+
+	// Hash is the hex SHA-256 of Prev and the record without its Hash.
+	Hash string `json:"hash"`
+}
+
+// digest returns the hash of r.
+func (r Record) digest() (string, error) {
+	r.Hash = ""
+	b, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(append([]byte(r.Prev), b...))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// ChainError is returned by Verify for the first record breaking the chain.
+type ChainError struct {
+	Line   int
+	Seq    uint64
+	Reason string
+}
+
+func (e *ChainError) Error() string {
+	return fmt.Sprintf("journal: line %d (seq %d): %s", e.Line, e.Seq, e.Reason)
+}
+
+// Journal appends chained records as lines of JSON to a writer.
+type Journal struct {
+	mu   sync.Mutex
+	w    io.Writer
+	seq  uint64
+	last string
+}
+
+// New starts a journal writing to w.
+func New(w io.Writer) *Journal {
+	return &Journal{w: w}
+}
+
+// Resume verifies the existing journal read from r and continues its chain
+// writing to w, which usually appends to the same file.
+func Resume(r io.Reader, w io.Writer) (*Journal, error) {
+	last, err := verify(r)
+	if err != nil {
+		return nil, err
+	}
+	return &Journal{w: w, seq: last.Seq, last: last.Hash}, nil
+}
+
+// Append chains rec to the journal and writes it, returning the record as
+// written.  The Seq, Prev and Hash of rec are assigned, and Time when zero.
+// The journal is not advanced when writing fails.
+func (j *Journal) Append(rec Record) (Record, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if rec.Time.IsZero() {
+		rec.Time = now()
+	}
+	rec.Time = rec.Time.UTC().Round(0)
+	rec.Seq = j.seq + 1
+	rec.Prev = j.last
+
+	hash, err := rec.digest()
+	if err != nil {
+		return rec, err
+	}
+	rec.Hash = hash
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return rec, err
+	}
+	if _, err := j.w.Write(append(line, '\n')); err != nil {
+		return rec, err
+	}
+
+	j.seq = rec.Seq
+	j.last = rec.Hash
+	return rec, nil
+}
+
+// Verify reads a journal from r and checks the sequence and hash chain of
+// its records, returning a *ChainError for the first broken record.
+func Verify(r io.Reader) error {
+	_, err := verify(r)
+	return err
+}
+
+// verify returns the last record of the valid journal read from r.
+func verify(r io.Reader) (Record, error) {
+	var (
+		last Record
+		line int
+	)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line++
+
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return last, &ChainError{Line: line, Seq: last.Seq + 1, Reason: err.Error()}
+		}
+
+		if want := last.Seq + 1; rec.Seq != want {
+			return last, &ChainError{Line: line, Seq: rec.Seq, Reason: fmt.Sprintf("expected seq %d", want)}
+		}
+		if rec.Prev != last.Hash {
+			return last, &ChainError{Line: line, Seq: rec.Seq, Reason: "previous hash mismatch"}
+		}
+		hash, err := rec.digest()
+		if err != nil {
+			return last, err
+		}
+		if rec.Hash != hash {
+			return last, &ChainError{Line: line, Seq: rec.Seq, Reason: "hash mismatch"}
+		}
+
+		last = rec
+	}
+	return last, scanner.Err()
+}
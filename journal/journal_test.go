@@ -0,0 +1,71 @@
+package journal
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func write(t *testing.T, n int) string {
+	var buf bytes.Buffer
+	j := New(&buf)
+	for i := 0; i < n; i++ {
+		if _, err := j.Append(Record{Kind: KindRequest, Method: "GET", URL: "https://bank/transfers"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.String()
+}
+
+func TestVerifyValidJournal(t *testing.T) {
+	if err := Verify(strings.NewReader(write(t, 3))); err != nil {
+		t.Fatalf("expected a valid journal, got: %v", err)
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	lines := strings.SplitAfter(write(t, 3), "\n")
+
+	for name, test := range map[string]struct {
+		journal string
+		seq     uint64
+	}{
+		"altered":   {lines[0] + strings.Replace(lines[1], "GET", "PUT", 1) + lines[2], 2},
+		"removed":   {lines[0] + lines[2], 3},
+		"reordered": {lines[1] + lines[0] + lines[2], 2},
+	} {
+		var chain *ChainError
+		if err := Verify(strings.NewReader(test.journal)); !errors.As(err, &chain) {
+			t.Errorf("%s: expected a ChainError, got: %v", name, err)
+		} else if want, got := test.seq, chain.Seq; want != got {
+			t.Errorf("%s: expected seq %d to break the chain, got %d", name, want, got)
+		}
+	}
+}
+
+func TestResumeContinuesChain(t *testing.T) {
+	existing := write(t, 2)
+
+	var appended bytes.Buffer
+	j, err := Resume(strings.NewReader(existing), &appended)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := j.Append(Record{Kind: KindRequest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := uint64(3), rec.Seq; want != got {
+		t.Fatalf("expected to continue at seq %d, got %d", want, got)
+	}
+
+	if err := Verify(strings.NewReader(existing + appended.String())); err != nil {
+		t.Fatalf("expected the resumed journal to verify, got: %v", err)
+	}
+
+	if _, err := Resume(strings.NewReader(existing[1:]), &appended); err == nil {
+		t.Fatalf("expected resuming a broken journal to fail")
+	}
+}
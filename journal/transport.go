@@ -0,0 +1,136 @@
+package journal
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/streadway/handy/redact"
+	"github.com/streadway/handy/retry"
+)
+
+// Redacted replaces the values of redacted headers and query parameters.
+const Redacted = redact.Redacted
+
+var (
+	// DefaultRedactHeaders are the headers redacted when not configured.
+	DefaultRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+	// DefaultRedactQuery are the query parameters redacted when not
+	// configured.
+	DefaultRedactQuery = redact.Query
+)
+
+// Transport is an http.RoundTripper journaling each request before it is
+// sent and its final outcome after.  Used around a retry.Transport, the
+// outcome records the number of attempts.
+//
+// The Transport fails closed: requests are not sent when their record
+// cannot be written, and responses are discarded with an error when their
+// outcome cannot be written.
+type Transport struct {
+	// Journal receives the records.
+	Journal *Journal
+
+	// Headers are the request headers recorded.  If nil, all headers are
+	// recorded.
+	Headers []string
+
+	// RedactHeaders are redacted, DefaultRedactHeaders when nil.
+	RedactHeaders []string
+
+	// RedactQuery are the query parameters redacted, DefaultRedactQuery
+	// when nil.
+	RedactQuery []string
+
+	// Next is the http.RoundTripper to which requests are forwarded.  If Next
+	// is nil, http.DefaultTransport is used.
+	Next http.RoundTripper
+}
+
+// RoundTrip implements the RoundTripper interface.
+func (t Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	sent, err := t.Journal.Append(Record{
+		Kind:   KindRequest,
+		Method: req.Method,
+		URL:    t.redactURL(req.URL),
+		Header: t.header(req.Header),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("journal: record request: %w", err)
+	}
+
+	counter := retry.CounterFrom(req.Context())
+	if counter == nil {
+		counter = &retry.Counter{}
+		req = req.WithContext(retry.WithCounter(req.Context(), counter))
+	}
+
+	resp, err := next.RoundTrip(req)
+
+	outcome := Record{
+		Kind:     KindOutcome,
+		Request:  sent.Seq,
+		Attempts: counter.Attempts(),
+		Elapsed:  now().Sub(sent.Time),
+	}
+	if err != nil {
+		outcome.Error = err.Error()
+	} else {
+		outcome.Status = resp.StatusCode
+	}
+
+	if _, jerr := t.Journal.Append(outcome); jerr != nil {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return nil, fmt.Errorf("journal: record outcome: %w", jerr)
+	}
+
+	return resp, err
+}
+
+func (t Transport) header(h http.Header) http.Header {
+	names := t.RedactHeaders
+	if names == nil {
+		names = DefaultRedactHeaders
+	}
+
+	out := make(http.Header)
+	for k, vs := range h {
+		if t.Headers != nil && !contains(t.Headers, k) {
+			continue
+		}
+		if contains(names, k) {
+			vs = []string{Redacted}
+		}
+		out[k] = append([]string(nil), vs...)
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+func (t Transport) redactURL(u *url.URL) string {
+	params := t.RedactQuery
+	if params == nil {
+		params = DefaultRedactQuery
+	}
+	return redact.URL(u, params)
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
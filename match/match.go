@@ -0,0 +1,250 @@
+/*
+Package match compares JSON bodies semantically, ignoring the order of object
+keys and the formatting of numbers, for request expectations of test doubles
+and replayed recordings.
+
+Paths address values from the root "$" with ".key" for object fields and
+"[n]" for array elements.  In Ignore and Patterns, "*" matches any key and
+"[*]" any element, like "$.items[*].id".
+*/
+package match
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// MismatchError describes the first difference found.
+type MismatchError struct {
+	Path   string
+	Reason string
+}
+
+func (e MismatchError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Reason)
+}
+
+// JSON compares JSON documents.
+type JSON struct {
+	// Ignore are the paths of values that are not compared, like generated
+	// identifiers and timestamps.
+	Ignore []string
+
+	// Patterns match the values at paths by regular expression instead of
+	// comparing them to the expected value.  Numbers and booleans are
+	// matched in their JSON form.
+	Patterns map[string]*regexp.Regexp
+
+	// AllowExtra accepts fields in actual objects that are not expected.
+	AllowExtra bool
+}
+
+type compiled struct {
+	JSON
+	ignore   []*regexp.Regexp
+	patterns []pattern
+}
+
+type pattern struct {
+	path  *regexp.Regexp
+	value *regexp.Regexp
+}
+
+// pathPattern compiles a path with wildcards.
+func pathPattern(p string) *regexp.Regexp {
+	quoted := regexp.QuoteMeta(p)
+	quoted = strings.ReplaceAll(quoted, `\[\*\]`, `\[\d+\]`)
+	quoted = strings.ReplaceAll(quoted, `\.\*`, `\.[^.\[]+`)
+	return regexp.MustCompile("^" + quoted + "$")
+}
+
+func (m JSON) compile() compiled {
+	c := compiled{JSON: m}
+	for _, p := range m.Ignore {
+		c.ignore = append(c.ignore, pathPattern(p))
+	}
+	for p, v := range m.Patterns {
+		c.patterns = append(c.patterns, pattern{pathPattern(p), v})
+	}
+	return c
+}
+
+// Match decodes the actual document from r and compares it to expected,
+// returning a MismatchError for the first difference.
+func (m JSON) Match(expected []byte, actual io.Reader) error {
+	want, err := decode(bytes.NewReader(expected))
+	if err != nil {
+		return fmt.Errorf("match: invalid expected JSON: %w", err)
+	}
+	got, err := decode(actual)
+	if err != nil {
+		return MismatchError{"$", "invalid JSON: " + err.Error()}
+	}
+	return m.compile().compare("$", want, got)
+}
+
+// Equal reports whether the documents match.
+func (m JSON) Equal(expected, actual []byte) bool {
+	return m.Match(expected, bytes.NewReader(actual)) == nil
+}
+
+// Request returns a request matcher comparing request bodies to expected.
+// The body is restored for later readers.
+func (m JSON) Request(expected []byte) func(*http.Request) bool {
+	return func(r *http.Request) bool {
+		if r.Body == nil {
+			return false
+		}
+		body, err := io.ReadAll(r.Body)
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		return err == nil && m.Equal(expected, body)
+	}
+}
+
+func decode(r io.Reader) (interface{}, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after the document")
+	}
+	return v, nil
+}
+
+func (c compiled) compare(path string, want, got interface{}) error {
+	for _, ig := range c.ignore {
+		if ig.MatchString(path) {
+			return nil
+		}
+	}
+
+	for _, p := range c.patterns {
+		if p.path.MatchString(path) {
+			s, ok := got.(string)
+			if !ok {
+				raw, _ := json.Marshal(got)
+				s = string(raw)
+			}
+			if !p.value.MatchString(s) {
+				return MismatchError{path, fmt.Sprintf("%q does not match %s", s, p.value)}
+			}
+			return nil
+		}
+	}
+
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok {
+			return MismatchError{path, fmt.Sprintf("expected an object, got %s", kind(got))}
+		}
+
+		keys := make([]string, 0, len(w))
+		for k := range w {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			child := path + "." + k
+			gv, ok := g[k]
+			if !ok {
+				if c.ignored(child) {
+					continue
+				}
+				return MismatchError{child, "missing"}
+			}
+			if err := c.compare(child, w[k], gv); err != nil {
+				return err
+			}
+		}
+
+		if !c.AllowExtra {
+			extra := make([]string, 0)
+			for k := range g {
+				if _, ok := w[k]; !ok && !c.ignored(path+"."+k) {
+					extra = append(extra, k)
+				}
+			}
+			if len(extra) > 0 {
+				sort.Strings(extra)
+				return MismatchError{path + "." + extra[0], "unexpected"}
+			}
+		}
+		return nil
+
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok {
+			return MismatchError{path, fmt.Sprintf("expected an array, got %s", kind(got))}
+		}
+		if len(w) != len(g) {
+			return MismatchError{path, fmt.Sprintf("expected %d elements, got %d", len(w), len(g))}
+		}
+		for i := range w {
+			if err := c.compare(fmt.Sprintf("%s[%d]", path, i), w[i], g[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case json.Number:
+		g, ok := got.(json.Number)
+		if !ok {
+			return MismatchError{path, fmt.Sprintf("expected a number, got %s", kind(got))}
+		}
+		wr, wok := new(big.Rat).SetString(string(w))
+		gr, gok := new(big.Rat).SetString(string(g))
+		if !wok || !gok || wr.Cmp(gr) != 0 {
+			return MismatchError{path, fmt.Sprintf("expected %s, got %s", w, g)}
+		}
+		return nil
+	}
+
+	if want != got {
+		return MismatchError{path, fmt.Sprintf("expected %s, got %s", repr(want), repr(got))}
+	}
+	return nil
+}
+
+func (c compiled) ignored(path string) bool {
+	for _, ig := range c.ignore {
+		if ig.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+func kind(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return "an object"
+	case []interface{}:
+		return "an array"
+	case json.Number:
+		return "a number"
+	case string:
+		return "a string"
+	case bool:
+		return "a boolean"
+	}
+	return "null"
+}
+
+func repr(v interface{}) string {
+	raw, _ := json.Marshal(v)
+	return string(raw)
+}
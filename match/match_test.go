@@ -0,0 +1,63 @@
+package match
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestJSONMatch(t *testing.T) {
+	m := JSON{
+		Ignore:   []string{"$.meta.requestId", "$.items[*].createdAt"},
+		Patterns: map[string]*regexp.Regexp{"$.items[*].id": regexp.MustCompile(`^[0-9a-f]{4}$`)},
+	}
+
+	expected := []byte(`{"items":[{"id":"","name":"a","price":1.50}],"meta":{"requestId":"x","total":1}}`)
+
+	for actual, want := range map[string]string{
+		`{"meta":{"total":1.0,"requestId":"y"},"items":[{"name":"a","price":1.5,"id":"beef","createdAt":"now"}]}`: "",
+		`{"meta":{"total":1,"requestId":"y"},"items":[{"name":"a","price":1.5,"id":"nope"}]}`:                     `$.items[0].id: "nope" does not match ^[0-9a-f]{4}$`,
+		`{"meta":{"total":2},"items":[{"name":"a","price":1.5,"id":"beef"}]}`:                                     "$.meta.total: expected 1, got 2",
+		`{"meta":{"total":1},"items":[]}`:                                                  "$.items: expected 1 elements, got 0",
+		`{"meta":{"total":1},"items":[{"price":1.5,"id":"beef"}]}`:                         "$.items[0].name: missing",
+		`{"meta":{"total":1,"extra":true},"items":[{"name":"a","price":1.5,"id":"beef"}]}`: "$.meta.extra: unexpected",
+		`{"items":[{"id":"beef","name":"a","price":"1.5"}],"meta":{"total":1}}`:            "$.items[0].price: expected a number, got a string",
+		`{} trailing`: "$: invalid JSON: unexpected data after the document",
+	} {
+		err := m.Match(expected, strings.NewReader(actual))
+		var got string
+		if err != nil {
+			got = err.Error()
+		}
+		if want != got {
+			t.Errorf("%s:\nwant %q\ngot  %q", actual, want, got)
+		}
+	}
+}
+
+func TestJSONAllowExtra(t *testing.T) {
+	m := JSON{AllowExtra: true}
+	if !m.Equal([]byte(`{"a":1}`), []byte(`{"a":1,"b":2}`)) {
+		t.Fatalf("expected extra fields to be allowed")
+	}
+	if m.Equal([]byte(`{"a":1,"b":2}`), []byte(`{"a":1}`)) {
+		t.Fatalf("expected missing fields to mismatch")
+	}
+}
+
+func TestJSONRequest(t *testing.T) {
+	matches := JSON{}.Request([]byte(`{"a":[1,2],"b":null}`))
+
+	req, _ := http.NewRequest("POST", "http://example/", strings.NewReader(`{"b":null,"a":[1,2.0]}`))
+	if !matches(req) {
+		t.Fatalf("expected the request body to match")
+	}
+
+	body, _ := io.ReadAll(req.Body)
+	if !bytes.Equal(body, []byte(`{"b":null,"a":[1,2.0]}`)) {
+		t.Fatalf("expected the body to be restored, got %q", body)
+	}
+}
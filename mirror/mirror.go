@@ -0,0 +1,225 @@
+/*
+Package mirror maintains local copies of remote resources, polling them with
+conditional GET requests and applying changes through callbacks, as used by
+consumers of configuration or CDN content.
+*/
+package mirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/streadway/handy/retry"
+)
+
+// Defaults used by Mirror when not configured.
+const (
+	DefaultInterval = time.Minute
+	DefaultBackoff  = time.Second
+)
+
+// Object is the local copy of a remote resource.
+type Object struct {
+	URL          string
+	Body         []byte
+	Header       http.Header
+	ETag         string
+	LastModified string
+	Fetched      time.Time
+}
+
+// StatusError is returned for responses other than 200, 304, 404 and 410.
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("mirror: %s responded %d", e.URL, e.StatusCode)
+}
+
+// Temporary reports server errors as temporary.
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode >= 500
+}
+
+// Mirror keeps the objects of URLs in sync with their remote resources.
+type Mirror struct {
+	// URLs of the mirrored resources.
+	URLs []string
+
+	// Client issues the requests, for example through a retry.Transport.  If
+	// nil, http.DefaultClient is used.
+	Client *http.Client
+
+	// Interval between successful syncs, DefaultInterval when zero.
+	Interval time.Duration
+
+	// Backoff chooses the wait after a failed sync from the count of
+	// consecutive failures.  If nil, an exponential backoff from
+	// DefaultBackoff capped at Interval is used.
+	Backoff retry.Backoff
+
+	// OnUpdate applies a new or changed object.  When it fails, the object
+	// is not kept and is fetched in full on the next sync.
+	OnUpdate func(Object) error
+
+	// OnDelete removes the object of a resource that responded 404 or 410.
+	OnDelete func(url string)
+
+	mu      sync.RWMutex
+	objects map[string]Object
+}
+
+// Get returns the current object of url.
+func (m *Mirror) Get(url string) (Object, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	o, ok := m.objects[url]
+	return o, ok
+}
+
+// Sync fetches each of the URLs once, returning the joined errors.
+func (m *Mirror) Sync(ctx context.Context) error {
+	var errs []error
+	for _, url := range m.URLs {
+		if err := m.fetch(ctx, url); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Run syncs until the context is done, backing off after failed syncs.
+func (m *Mirror) Run(ctx context.Context) {
+	interval := m.Interval
+	if interval == 0 {
+		interval = DefaultInterval
+	}
+
+	backoff := m.Backoff
+	if backoff == nil {
+		exponential := retry.ExponentialBackoff(DefaultBackoff)
+		backoff = func(a retry.Attempt) time.Duration {
+			if d := exponential(a); d < interval {
+				return d
+			}
+			return interval
+		}
+	}
+
+	var (
+		failures uint
+		start    = time.Now()
+	)
+
+	for {
+		wait := interval
+		if err := m.Sync(ctx); err != nil {
+			failures++
+			wait = backoff(retry.Attempt{Start: start, Count: failures, Err: err})
+		} else {
+			failures = 0
+			start = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (m *Mirror) fetch(ctx context.Context, url string) error {
+	client := m.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+
+	old, ok := m.Get(url)
+	if ok {
+		if old.ETag != "" {
+			req.Header.Set("If-None-Match", old.ETag)
+		}
+		if old.LastModified != "" {
+			req.Header.Set("If-Modified-Since", old.LastModified)
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		if ok {
+			old.Fetched = time.Now()
+			m.set(url, &old)
+		}
+		return nil
+	case http.StatusNotFound, http.StatusGone:
+		if ok {
+			m.set(url, nil)
+			if m.OnDelete != nil {
+				m.OnDelete(url)
+			}
+		}
+		return nil
+	default:
+		return &StatusError{URL: url, StatusCode: resp.StatusCode}
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	o := Object{
+		URL:          url,
+		Body:         body,
+		Header:       resp.Header,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Fetched:      time.Now(),
+	}
+
+	if !ok || string(old.Body) != string(body) {
+		if m.OnUpdate != nil {
+			if err := m.OnUpdate(o); err != nil {
+				return fmt.Errorf("mirror: apply %s: %w", url, err)
+			}
+		}
+	}
+
+	m.set(url, &o)
+	return nil
+}
+
+// set replaces the object of url, or removes it when o is nil.
+func (m *Mirror) set(url string, o *Object) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if o == nil {
+		delete(m.objects, url)
+		return
+	}
+	if m.objects == nil {
+		m.objects = make(map[string]Object)
+	}
+	m.objects[url] = *o
+}
@@ -0,0 +1,146 @@
+package mirror
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/streadway/handy/retry"
+)
+
+type resource struct {
+	body, etag string
+	status     int
+	requests   []string
+}
+
+func (r *resource) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.requests = append(r.requests, req.Header.Get("If-None-Match"))
+	if r.status != 0 {
+		w.WriteHeader(r.status)
+		return
+	}
+	if req.Header.Get("If-None-Match") == r.etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", r.etag)
+	w.Write([]byte(r.body))
+}
+
+func TestSyncAppliesChanges(t *testing.T) {
+	res := &resource{body: "v1", etag: `"1"`}
+	srv := httptest.NewServer(res)
+	defer srv.Close()
+
+	var (
+		updates []string
+		deleted []string
+		m       = &Mirror{
+			URLs:     []string{srv.URL},
+			OnUpdate: func(o Object) error { updates = append(updates, string(o.Body)); return nil },
+			OnDelete: func(url string) { deleted = append(deleted, url) },
+		}
+		ctx = context.Background()
+	)
+
+	for i := 0; i < 2; i++ {
+		if err := m.Sync(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res.body, res.etag = "v2", `"2"`
+	if err := m.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := []string{"", `"1"`, `"1"`}, res.requests; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expected If-None-Match %q, got %q", want, got)
+	}
+	if want, got := []string{"v1", "v2"}, updates; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expected updates %v, got %v", want, got)
+	}
+	if o, _ := m.Get(srv.URL); o.ETag != `"2"` || string(o.Body) != "v2" {
+		t.Fatalf("expected the mirror to hold v2, got %+v", o)
+	}
+
+	res.status = http.StatusGone
+	if err := m.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.Get(srv.URL); ok || len(deleted) != 1 {
+		t.Fatalf("expected the gone resource to be deleted")
+	}
+}
+
+func TestSyncKeepsObjectOnlyWhenApplied(t *testing.T) {
+	res := &resource{body: "v1", etag: `"1"`}
+	srv := httptest.NewServer(res)
+	defer srv.Close()
+
+	fail := true
+	m := &Mirror{
+		URLs: []string{srv.URL},
+		OnUpdate: func(Object) error {
+			if fail {
+				return errors.New("disk full")
+			}
+			return nil
+		},
+	}
+
+	if err := m.Sync(context.Background()); err == nil {
+		t.Fatalf("expected the failed update to be reported")
+	}
+	if _, ok := m.Get(srv.URL); ok {
+		t.Fatalf("expected the failed update not to be kept")
+	}
+
+	fail = false
+	if err := m.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if want, got := "", res.requests[1]; want != got {
+		t.Fatalf("expected an unconditional request after the failed update, got If-None-Match %s", got)
+	}
+}
+
+func TestSyncReportsServerErrors(t *testing.T) {
+	srv := httptest.NewServer(&resource{status: http.StatusServiceUnavailable})
+	defer srv.Close()
+
+	err := (&Mirror{URLs: []string{srv.URL}}).Sync(context.Background())
+
+	var status *StatusError
+	if !errors.As(err, &status) || !status.Temporary() {
+		t.Fatalf("expected a temporary StatusError, got: %v", err)
+	}
+}
+
+func TestRunBacksOff(t *testing.T) {
+	srv := httptest.NewServer(&resource{status: http.StatusServiceUnavailable})
+	defer srv.Close()
+
+	var attempts []uint
+	m := &Mirror{
+		URLs:     []string{srv.URL},
+		Interval: time.Hour,
+		Backoff: func(a retry.Attempt) time.Duration {
+			attempts = append(attempts, a.Count)
+			return time.Millisecond
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	m.Run(ctx)
+
+	if len(attempts) < 2 || attempts[1] != 2 {
+		t.Fatalf("expected consecutive failures to back off, got %v", attempts)
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Package probe checks the health of upstreams with TCP or TLS connects and
+reports the results to a health state like a breaker.Breaker.
+*/
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/streadway/handy/retry"
+)
+
+// Defaults used by Probe when not configured.
+const (
+	DefaultInterval = 10 * time.Second
+	DefaultTimeout  = 2 * time.Second
+	DefaultBackoff  = time.Second
+)
+
+// Check performs a single probe.
+type Check func(context.Context) error
+
+// TCP checks that a TCP connection to addr can be established.
+func TCP(addr string) Check {
+	return func(ctx context.Context) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+// TLS checks that a TLS handshake with addr completes.  A nil config uses
+// the host of addr as the server name.
+func TLS(addr string, config *tls.Config) Check {
+	return func(ctx context.Context) error {
+		d := tls.Dialer{Config: config}
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+// Health receives the outcome of each probe.  breaker.Breaker implements
+// Health, so a Probe can eject and restore an upstream.
+type Health interface {
+	Success(time.Duration)
+	Failure(time.Duration)
+}
+
+// Probe runs Check periodically and reports to Health.
+type Probe struct {
+	// Check performs the probe.
+	Check Check
+
+	// Health receives the outcomes.
+	Health Health
+
+	// Interval between successful probes, DefaultInterval when zero.
+	Interval time.Duration
+
+	// Timeout of each probe, DefaultTimeout when zero.
+	Timeout time.Duration
+
+	// Backoff chooses the wait after a failed probe from the count of
+	// consecutive failures.  If nil, an exponential backoff from
+	// DefaultBackoff capped at Interval is used.
+	Backoff retry.Backoff
+}
+
+// Once runs a single probe and reports its outcome.
+func (p Probe) Once(ctx context.Context) error {
+	timeout := p.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := p.Check(ctx)
+	elapsed := time.Since(start)
+
+	if p.Health != nil {
+		if err != nil {
+			p.Health.Failure(elapsed)
+		} else {
+			p.Health.Success(elapsed)
+		}
+	}
+
+	return err
+}
+
+// Run probes until the context is done.
+func (p Probe) Run(ctx context.Context) {
+	interval := p.Interval
+	if interval == 0 {
+		interval = DefaultInterval
+	}
+
+	backoff := p.Backoff
+	if backoff == nil {
+		exponential := retry.ExponentialBackoff(DefaultBackoff)
+		backoff = func(a retry.Attempt) time.Duration {
+			if d := exponential(a); d < interval {
+				return d
+			}
+			return interval
+		}
+	}
+
+	var (
+		failures uint
+		start    = time.Now()
+	)
+
+	for {
+		wait := interval
+		if err := p.Once(ctx); err != nil {
+			failures++
+			wait = backoff(retry.Attempt{Start: start, Count: failures, Err: err})
+		} else {
+			failures = 0
+			start = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/streadway/handy/retry"
+)
+
+type health struct {
+	sync.Mutex
+	successes, failures int
+}
+
+func (h *health) Success(time.Duration) { h.Lock(); h.successes++; h.Unlock() }
+func (h *health) Failure(time.Duration) { h.Lock(); h.failures++; h.Unlock() }
+
+func TestTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+
+	if err := TCP(addr)(context.Background()); err != nil {
+		t.Fatalf("expected to connect to a listener, got: %v", err)
+	}
+
+	l.Close()
+
+	if err := TCP(addr)(context.Background()); err == nil {
+		t.Fatalf("expected to fail connecting to a closed listener")
+	}
+}
+
+func TestTLS(t *testing.T) {
+	s := httptest.NewTLSServer(nil)
+	defer s.Close()
+
+	addr := s.Listener.Addr().String()
+
+	if err := TLS(addr, s.Client().Transport.(*http.Transport).TLSClientConfig)(context.Background()); err != nil {
+		t.Fatalf("expected a TLS handshake, got: %v", err)
+	}
+}
+
+func TestRunBacksOffOnFailures(t *testing.T) {
+	var (
+		h        health
+		attempts []uint
+		ctx, end = context.WithCancel(context.Background())
+	)
+	defer end()
+
+	p := Probe{
+		Check:  func(context.Context) error { return errors.New("down") },
+		Health: &h,
+		Backoff: func(a retry.Attempt) time.Duration {
+			attempts = append(attempts, a.Count)
+			if a.Count == 3 {
+				end()
+			}
+			return time.Millisecond
+		},
+	}
+
+	p.Run(ctx)
+
+	if want, got := 3, h.failures; want != got {
+		t.Fatalf("expected %d failures reported, got %d", want, got)
+	}
+	if want, got := []uint{1, 2, 3}, attempts; len(got) != 3 || got[2] != want[2] {
+		t.Fatalf("expected backoff for consecutive failures %v, got %v", want, got)
+	}
+}
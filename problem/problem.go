@@ -0,0 +1,122 @@
+/*
+Package problem writes machine-readable error responses, by default as
+application/problem+json documents following RFC 7807, so that clients can
+tell why a request was refused and when to try again.
+*/
+package problem
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Reasons of the middlewares refusing requests in this repository.
+const (
+	ReasonRateLimited = "rate_limited"
+	ReasonCircuitOpen = "circuit_open"
+)
+
+// Problem describes why a request was refused.
+type Problem struct {
+	// Type is a URI identifying the kind of problem, "about:blank" when
+	// empty.
+	Type string
+
+	// Status is the HTTP status code of the response.
+	Status int
+
+	// Title is a short summary, the status text when empty.
+	Title string
+
+	// Detail explains this occurrence of the problem.
+	Detail string
+
+	// Reason is a machine-readable token like ReasonRateLimited.
+	Reason string
+
+	// RetryAfter is the time after which the request may succeed, omitted
+	// when zero.
+	RetryAfter time.Duration
+}
+
+// Seconds returns RetryAfter rounded up to whole seconds.
+func (p Problem) Seconds() int64 {
+	return int64(math.Ceil(p.RetryAfter.Seconds()))
+}
+
+// MarshalJSON encodes p with the RFC 7807 members and the "reason" and
+// "retry_after" extensions, the latter in seconds.
+func (p Problem) MarshalJSON() ([]byte, error) {
+	doc := struct {
+		Type       string `json:"type"`
+		Title      string `json:"title"`
+		Status     int    `json:"status"`
+		Detail     string `json:"detail,omitempty"`
+		Reason     string `json:"reason,omitempty"`
+		RetryAfter int64  `json:"retry_after,omitempty"`
+	}{p.Type, p.Title, p.Status, p.Detail, p.Reason, p.Seconds()}
+
+	if doc.Type == "" {
+		doc.Type = "about:blank"
+	}
+	if doc.Title == "" {
+		doc.Title = http.StatusText(p.Status)
+	}
+
+	return json.Marshal(doc)
+}
+
+// Writer responds to a request with a Problem.
+type Writer func(http.ResponseWriter, *http.Request, Problem)
+
+// DefaultWriter is used by Write.  Replace it to change the format of all
+// refusals in the process.
+var DefaultWriter Writer = JSON
+
+// Write responds with p using the DefaultWriter.
+func Write(w http.ResponseWriter, r *http.Request, p Problem) {
+	DefaultWriter(w, r, p)
+}
+
+// JSON responds with p as application/problem+json and sets the Retry-After
+// header.
+func JSON(w http.ResponseWriter, r *http.Request, p Problem) {
+	body, err := json.Marshal(p)
+	if err != nil {
+		Text(w, r, p)
+		return
+	}
+
+	header(w, p, "application/problem+json")
+	w.Write(append(body, '\n'))
+}
+
+// Text responds with p as a line of plain text and sets the Retry-After
+// header.
+func Text(w http.ResponseWriter, r *http.Request, p Problem) {
+	title := p.Title
+	if title == "" {
+		title = http.StatusText(p.Status)
+	}
+
+	header(w, p, "text/plain; charset=utf-8")
+
+	if p.Reason != "" {
+		fmt.Fprintf(w, "%s (%s)\n", title, p.Reason)
+	} else {
+		fmt.Fprintln(w, title)
+	}
+}
+
+func header(w http.ResponseWriter, p Problem, contentType string) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if p.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.FormatInt(p.Seconds(), 10))
+	}
+	w.WriteHeader(p.Status)
+}
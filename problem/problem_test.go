@@ -0,0 +1,59 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestJSON(t *testing.T) {
+	resp := httptest.NewRecorder()
+	JSON(resp, httptest.NewRequest("GET", "/", nil), Problem{
+		Status:     429,
+		Reason:     ReasonRateLimited,
+		RetryAfter: 1500 * time.Millisecond,
+	})
+
+	if want, got := 429, resp.Code; want != got {
+		t.Fatalf("expected status %d, got %d", want, got)
+	}
+	if want, got := "application/problem+json", resp.Header().Get("Content-Type"); want != got {
+		t.Fatalf("expected content type %q, got %q", want, got)
+	}
+	if want, got := "2", resp.Header().Get("Retry-After"); want != got {
+		t.Fatalf("expected Retry-After rounded up to %q, got %q", want, got)
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(resp.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	for k, want := range map[string]interface{}{
+		"type":        "about:blank",
+		"title":       "Too Many Requests",
+		"status":      float64(429),
+		"reason":      "rate_limited",
+		"retry_after": float64(2),
+	} {
+		if got := doc[k]; want != got {
+			t.Errorf("expected %s %v, got %v", k, want, got)
+		}
+	}
+}
+
+func TestWriteUsesDefaultWriter(t *testing.T) {
+	defer func(w Writer) { DefaultWriter = w }(DefaultWriter)
+	DefaultWriter = Text
+
+	resp := httptest.NewRecorder()
+	Write(resp, httptest.NewRequest("GET", "/", nil), Problem{Status: 503, Reason: ReasonCircuitOpen})
+
+	if want, got := "Service Unavailable (circuit_open)\n", resp.Body.String(); want != got {
+		t.Fatalf("expected body %q, got %q", want, got)
+	}
+	if got := resp.Header().Get("Retry-After"); got != "" {
+		t.Fatalf("expected no Retry-After without a delay, got %q", got)
+	}
+}
@@ -0,0 +1,243 @@
+// Copyright (c) 2013, SoundCloud Ltd.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the README file.
+// Source code and contact info at http://github.com/streadway/handy
+
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/streadway/handy/breaker"
+	"github.com/streadway/handy/probe"
+	"github.com/streadway/handy/retry"
+	"github.com/streadway/handy/stream"
+)
+
+// ErrNoUpstream is returned by the Balancer transport when the circuits of
+// all upstreams are open.
+var ErrNoUpstream = errors.New("no healthy upstream")
+
+// DefaultFailureRatio is the failure ratio opening the circuit of an
+// upstream when not configured.
+const DefaultFailureRatio = 0.5
+
+// BalancerConfig parameterizes NewBalancer.
+type BalancerConfig struct {
+	// Upstreams receive the proxied requests in turn.
+	Upstreams []*url.URL
+
+	// Retry decides whether to retry a request against the next upstream.
+	// If nil, requests without a body are retried on connection errors and
+	// 502, 503 and 504 responses, once against each upstream.
+	// When Retry aborts, the last upstream response is proxied, like a 503
+	// from every upstream.
+	Retry retry.Retryer
+
+	// Delay is called between attempts.  If nil, no delay will be used.
+	Delay retry.Delayer
+
+	// FailureRatio opens the circuit of an upstream, DefaultFailureRatio
+	// when zero.
+	FailureRatio float64
+
+	// HealthPath enables active health checks against this path of each
+	// upstream.  Responses below 500 are healthy.
+	HealthPath string
+
+	// HealthInterval is the time between health checks, the
+	// probe.DefaultInterval when zero.
+	HealthInterval time.Duration
+
+	// Copier provides the buffers, flush pacing and progress reporting of
+	// proxied response bodies.  If nil, the httputil.ReverseProxy defaults
+	// are used.
+	Copier *stream.Copier
+
+	// Next is the http.RoundTripper used to reach the upstreams.  If Next is
+	// nil, http.DefaultTransport is used.
+	Next http.RoundTripper
+}
+
+// Balancer is a reverse proxy distributing requests across upstreams in
+// turn, skipping upstreams whose circuit breaker is open and retrying failed
+// requests against the next upstream.
+type Balancer struct {
+	*httputil.ReverseProxy
+	stop context.CancelFunc
+}
+
+// NewBalancer constructs a Balancer and starts its health checks.
+func NewBalancer(cfg BalancerConfig) *Balancer {
+	if cfg.FailureRatio == 0 {
+		cfg.FailureRatio = DefaultFailureRatio
+	}
+	if cfg.Next == nil {
+		cfg.Next = http.DefaultTransport
+	}
+	if cfg.Retry == nil {
+		cfg.Retry = retry.DefaultPolicy{
+			MaxAttempts: uint(len(cfg.Upstreams)),
+			Methods:     []string{"GET", "HEAD", "OPTIONS", "TRACE"},
+			Statuses: []int{
+				http.StatusBadGateway,
+				http.StatusServiceUnavailable,
+				http.StatusGatewayTimeout,
+			},
+		}.Retryer()
+	}
+
+	lb := &balancer{next: cfg.Next}
+	for _, u := range cfg.Upstreams {
+		lb.upstreams = append(lb.upstreams, upstream{
+			url:     u,
+			breaker: breaker.NewBreaker(cfg.FailureRatio),
+		})
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	if cfg.HealthPath != "" {
+		for _, u := range lb.upstreams {
+			go probe.Probe{
+				Check:    healthCheck(cfg.Next, u.url, cfg.HealthPath),
+				Health:   u.breaker,
+				Interval: cfg.HealthInterval,
+			}.Run(ctx)
+		}
+	}
+
+	b := &Balancer{
+		ReverseProxy: &httputil.ReverseProxy{
+			Director: func(*http.Request) {},
+			Transport: retry.Transport{
+				Retry:    cfg.Retry,
+				Delay:    cfg.Delay,
+				Next:     lb,
+				Fallback: lastResponse,
+			},
+			ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+				if errors.Is(err, ErrNoUpstream) {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.WriteHeader(http.StatusBadGateway)
+			},
+		},
+		stop: stop,
+	}
+
+	if c := cfg.Copier; c != nil {
+		b.BufferPool = c
+		b.FlushInterval = c.FlushInterval
+		b.ModifyResponse = func(resp *http.Response) error {
+			resp.Body = c.Body(resp)
+			return nil
+		}
+	}
+
+	return b
+}
+
+// lastResponse proxies the response of the last attempt, as ReverseProxy
+// discards responses returned with an error without closing them.
+func lastResponse(a retry.Attempt) (*http.Response, error) {
+	if a.Response != nil {
+		return a.Response, nil
+	}
+	return nil, a.Err
+}
+
+// Close stops the health checks.
+func (b *Balancer) Close() {
+	b.stop()
+}
+
+type upstream struct {
+	url     *url.URL
+	breaker breaker.Breaker
+}
+
+type balancer struct {
+	upstreams []upstream
+	next      http.RoundTripper
+	turn      uint32
+}
+
+// pick returns the next upstream allowed by its circuit breaker.
+func (lb *balancer) pick() (upstream, bool) {
+	n := uint32(len(lb.upstreams))
+	start := atomic.AddUint32(&lb.turn, 1)
+	for i := uint32(0); i < n; i++ {
+		u := lb.upstreams[(start+i)%n]
+		if u.breaker.Allow() {
+			return u, true
+		}
+	}
+	return upstream{}, false
+}
+
+func (lb *balancer) RoundTrip(req *http.Request) (*http.Response, error) {
+	u, ok := lb.pick()
+	if !ok {
+		return nil, ErrNoUpstream
+	}
+
+	out := *req
+	target := *req.URL
+	target.Scheme = u.url.Scheme
+	target.Host = u.url.Host
+	target.Path = singleJoiningSlash(u.url.Path, req.URL.Path)
+	target.RawPath = ""
+	out.URL = &target
+
+	begin := time.Now()
+	resp, err := lb.next.RoundTrip(&out)
+	duration := time.Since(begin)
+
+	if err != nil || resp.StatusCode >= 500 {
+		u.breaker.Failure(duration)
+	} else {
+		u.breaker.Success(duration)
+	}
+
+	return resp, err
+}
+
+func healthCheck(next http.RoundTripper, u *url.URL, path string) probe.Check {
+	target := *u
+	target.Path = singleJoiningSlash(u.Path, path)
+	return func(ctx context.Context) error {
+		req, err := http.NewRequest("GET", target.String(), nil)
+		if err != nil {
+			return err
+		}
+		resp, err := next.RoundTrip(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 500 {
+			return errors.New(resp.Status)
+		}
+		return nil
+	}
+}
+
+func singleJoiningSlash(a, b string) string {
+	aslash := strings.HasSuffix(a, "/")
+	bslash := strings.HasPrefix(b, "/")
+	switch {
+	case aslash && bslash:
+		return a + b[1:]
+	case !aslash && !bslash && b != "":
+		return a + "/" + b
+	}
+	return a + b
+}
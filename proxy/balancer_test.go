@@ -0,0 +1,210 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
+	"net/url"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/streadway/handy/stream"
+)
+
+func TestBalancerRetriesAgainstAlternateUpstream(t *testing.T) {
+	var healthy count
+	good := httptest.NewServer(&healthy)
+	defer good.Close()
+
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer bad.Close()
+
+	goodURL, _ := url.Parse(good.URL)
+	badURL, _ := url.Parse(bad.URL)
+
+	lb := NewBalancer(BalancerConfig{Upstreams: []*url.URL{badURL, goodURL}})
+	defer lb.Close()
+
+	front := httptest.NewServer(lb)
+	defer front.Close()
+
+	for i := 0; i < 4; i++ {
+		resp, err := http.Get(front.URL + "/path")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if want, got := 200, resp.StatusCode; want != got {
+			t.Fatalf("request %d: expected %d from the healthy upstream, got %d", i, want, got)
+		}
+	}
+
+	if want, got := 4, int(healthy); want != got {
+		t.Fatalf("expected %d requests served by the healthy upstream, got %d", want, got)
+	}
+}
+
+func TestBalancerWithoutHealthyUpstream(t *testing.T) {
+	u, _ := url.Parse("http://127.0.0.1:1")
+
+	lb := NewBalancer(BalancerConfig{Upstreams: []*url.URL{u}})
+	defer lb.Close()
+
+	resp := httptest.NewRecorder()
+	lb.ServeHTTP(resp, httptest.NewRequest("GET", "http://example.org/", nil))
+
+	if want, got := http.StatusBadGateway, resp.Code; want != got {
+		t.Fatalf("expected %d for an unreachable upstream, got %d", want, got)
+	}
+}
+
+type closeCounter struct {
+	mu             sync.Mutex
+	opened, closed int
+}
+
+func (c *closeCounter) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.opened++
+	c.mu.Unlock()
+	resp.Body = &countedBody{ReadCloser: resp.Body, counter: c}
+	return resp, nil
+}
+
+type countedBody struct {
+	io.ReadCloser
+	counter *closeCounter
+}
+
+func (b *countedBody) Close() error {
+	b.counter.mu.Lock()
+	b.counter.closed++
+	b.counter.mu.Unlock()
+	return b.ReadCloser.Close()
+}
+
+func TestBalancerProxiesLastResponse(t *testing.T) {
+	var urls []*url.URL
+	for i := 0; i < 2; i++ {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("busy"))
+		}))
+		defer s.Close()
+		u, _ := url.Parse(s.URL)
+		urls = append(urls, u)
+	}
+
+	counter := &closeCounter{}
+	lb := NewBalancer(BalancerConfig{Upstreams: urls, Next: counter})
+	defer lb.Close()
+
+	resp := httptest.NewRecorder()
+	lb.ServeHTTP(resp, httptest.NewRequest("GET", "http://example.org/", nil))
+
+	if want, got := http.StatusServiceUnavailable, resp.Code; want != got {
+		t.Fatalf("expected the upstream status %d, got %d", want, got)
+	}
+	if want, got := "busy", resp.Body.String(); want != got {
+		t.Fatalf("expected the upstream body %q, got %q", want, got)
+	}
+	if counter.opened != 2 || counter.closed != 2 {
+		t.Fatalf("expected every upstream body to be closed, opened %d, closed %d", counter.opened, counter.closed)
+	}
+}
+
+func TestBalancerReportsCopierProgress(t *testing.T) {
+	payload := bytes.Repeat([]byte("x"), 64<<10)
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(payload)))
+		w.Write(payload)
+	}))
+	defer up.Close()
+
+	u, _ := url.Parse(up.URL)
+
+	var last stream.Progress
+	lb := NewBalancer(BalancerConfig{
+		Upstreams: []*url.URL{u},
+		Copier:    &stream.Copier{OnProgress: func(p stream.Progress) { last = p }},
+	})
+	defer lb.Close()
+
+	resp := httptest.NewRecorder()
+	lb.ServeHTTP(resp, httptest.NewRequest("GET", "http://example.org/", nil))
+
+	if want, got := len(payload), resp.Body.Len(); want != got {
+		t.Fatalf("expected %d bytes proxied, got %d", want, got)
+	}
+	if want, got := int64(len(payload)), last.Written; want != got {
+		t.Fatalf("expected progress to report %d bytes, got %d", want, got)
+	}
+	if want, got := int64(len(payload)), last.Total; want != got {
+		t.Fatalf("expected progress to report a total of %d bytes, got %d", want, got)
+	}
+}
+
+func TestBalancerPassesEarlyHints(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", "</style.css>; rel=preload; as=style")
+		w.WriteHeader(http.StatusEarlyHints)
+		w.Header().Del("Link")
+		w.Write([]byte("page"))
+	}))
+	defer upstream.Close()
+
+	u, _ := url.Parse(upstream.URL)
+	lb := NewBalancer(BalancerConfig{Upstreams: []*url.URL{u}})
+	defer lb.Close()
+
+	front := httptest.NewServer(lb)
+	defer front.Close()
+
+	var hints []string
+	ctx := httptrace.WithClientTrace(context.Background(), &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			hints = append(hints, strconv.Itoa(code)+" "+header.Get("Link"))
+			return nil
+		},
+	})
+	req, _ := http.NewRequestWithContext(ctx, "GET", front.URL, nil)
+
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if want, got := []string{"103 </style.css>; rel=preload; as=style"}, hints; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expected the early hints to pass, got %q", got)
+	}
+	if want, got := 200, resp.StatusCode; want != got {
+		t.Fatalf("expected the final status %d, got %d", want, got)
+	}
+}
+
+func TestSingleJoiningSlash(t *testing.T) {
+	for _, it := range []struct{ a, b, want string }{
+		{"", "/path", "/path"},
+		{"/base/", "/path", "/base/path"},
+		{"/base", "path", "/base/path"},
+		{"/base", "/path", "/base/path"},
+	} {
+		if got := singleJoiningSlash(it.a, it.b); it.want != got {
+			t.Errorf("singleJoiningSlash(%q, %q): want %q, got %q", it.a, it.b, it.want, got)
+		}
+	}
+}
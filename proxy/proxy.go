@@ -4,13 +4,16 @@
 // Source code and contact info at http://github.com/streadway/handy
 
 /*
-Package proxy contains a proxying HTTP transport.
+Package proxy contains a proxying HTTP transport and a load balancing reverse
+proxy.
 */
 package proxy
 
 import (
 	"net/http"
 	"net/url"
+
+	"github.com/streadway/handy/header"
 )
 
 // Transport is an implementation of the http.RoundTripper that uses a user
@@ -27,7 +30,8 @@ type Transport struct {
 	Next http.RoundTripper
 }
 
-// RoundTrip implements the RoundTripper interface.
+// RoundTrip implements the RoundTripper interface.  Hop-by-hop headers are
+// stripped from the forwarded request and the returned response.
 func (t Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 	if t.Proxy != nil {
 		url, err := t.Proxy(req)
@@ -36,8 +40,18 @@ func (t Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 		}
 		req.URL = url
 	}
-	if t.Next != nil {
-		return t.Next.RoundTrip(req)
+
+	out := req.Clone(req.Context())
+	header.Sanitize(out.Header)
+
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	resp, err := next.RoundTrip(out)
+	if resp != nil {
+		header.Sanitize(resp.Header)
 	}
-	return http.DefaultTransport.RoundTrip(req)
+	return resp, err
 }
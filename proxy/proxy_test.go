@@ -72,3 +72,31 @@ func TestNilValues(t *testing.T) {
 		t.Errorf("expected request count %d, got %d", expected, got)
 	}
 }
+
+func TestStripsHopByHopHeaders(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("X-Private"); got != "" {
+			t.Errorf("expected Connection listed header to be stripped, got: %q", got)
+		}
+		if got := r.Header.Get("X-Public"); got != "forwarded" {
+			t.Errorf("expected end-to-end header to be forwarded, got: %q", got)
+		}
+		w.Header().Set("Keep-Alive", "timeout=5")
+	}))
+	defer s.Close()
+
+	req, _ := http.NewRequest("GET", s.URL, nil)
+	req.Header.Set("Connection", "X-Private")
+	req.Header.Set("X-Private", "secret")
+	req.Header.Set("X-Public", "forwarded")
+
+	resp, err := Transport{}.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if got := resp.Header.Get("Keep-Alive"); got != "" {
+		t.Fatalf("expected hop-by-hop response header to be stripped, got: %q", got)
+	}
+}
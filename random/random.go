@@ -0,0 +1,70 @@
+/*
+Package random provides the sources of randomness used by the jitter of
+handy components, so that tests can make them deterministic and
+security-sensitive users can supply crypto/rand.
+*/
+package random
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"math/rand"
+	"sync"
+)
+
+// Source returns pseudo-random numbers in [0.0,1.0).  *rand.Rand implements
+// Source, but is not safe for concurrent use unlike the Sources of this
+// package.
+type Source interface {
+	Float64() float64
+}
+
+// SourceFunc adapts a function to a Source.
+type SourceFunc func() float64
+
+// Float64 calls f.
+func (f SourceFunc) Float64() float64 {
+	return f()
+}
+
+// Default is the Source used by components without one, the top-level
+// functions of math/rand.
+var Default Source = SourceFunc(rand.Float64)
+
+// Or returns s, or Default when s is nil.
+func Or(s Source) Source {
+	if s == nil {
+		return Default
+	}
+	return s
+}
+
+// Seeded returns a deterministic Source for tests, safe for concurrent use.
+func Seeded(seed int64) Source {
+	var (
+		mu sync.Mutex
+		r  = rand.New(rand.NewSource(seed))
+	)
+	return SourceFunc(func() float64 {
+		mu.Lock()
+		defer mu.Unlock()
+		return r.Float64()
+	})
+}
+
+// Crypto returns a Source reading from crypto/rand.
+func Crypto() Source {
+	return SourceFunc(func() float64 {
+		var b [8]byte
+		if _, err := crand.Read(b[:]); err != nil {
+			panic("random: crypto/rand failed: " + err.Error())
+		}
+		// 53 random bits, the precision of a float64 mantissa
+		return float64(binary.BigEndian.Uint64(b[:])>>11) / (1 << 53)
+	})
+}
+
+// Fixed returns a Source always returning f, for tests.
+func Fixed(f float64) Source {
+	return SourceFunc(func() float64 { return f })
+}
@@ -0,0 +1,26 @@
+package random
+
+import "testing"
+
+func TestSeededIsDeterministic(t *testing.T) {
+	a, b := Seeded(42), Seeded(42)
+	for i := 0; i < 10; i++ {
+		if x, y := a.Float64(), b.Float64(); x != y {
+			t.Fatalf("expected equal sequences, got %v and %v", x, y)
+		}
+	}
+}
+
+func TestSourcesInRange(t *testing.T) {
+	for name, src := range map[string]Source{
+		"default": Or(nil),
+		"seeded":  Seeded(1),
+		"crypto":  Crypto(),
+	} {
+		for i := 0; i < 1000; i++ {
+			if f := src.Float64(); f < 0 || f >= 1 {
+				t.Fatalf("%s: expected [0, 1), got %v", name, f)
+			}
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBucketRefills(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := NewBucket(2, 2)
+	b.now = func() time.Time { return now }
+
+	if !b.Allow() || !b.Allow() {
+		t.Fatalf("expected the burst to be available")
+	}
+	if b.Allow() {
+		t.Fatalf("expected the bucket to be empty after the burst")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+
+	if !b.Allow() {
+		t.Fatalf("expected a token after refilling")
+	}
+	if b.Allow() {
+		t.Fatalf("expected only one token after refilling")
+	}
+}
+
+func TestBucketWaitRespectsContext(t *testing.T) {
+	b := NewBucket(0.1, 1)
+	b.Allow()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := b.Wait(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected to give up before the deadline, got: %v", err)
+	}
+}
@@ -0,0 +1,32 @@
+package ratelimit
+
+import (
+	"net/http"
+
+	"github.com/streadway/handy/problem"
+)
+
+// Middleware produces an http.Handler factory like Handler to be composed.
+func Middleware(bucket *Bucket) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return Handler(bucket, next)
+	}
+}
+
+// Handler produces an http.Handler taking a token from bucket for each
+// request.  Without a token the request is refused with HTTP 429 written by
+// problem.Write, asking to retry once the next token is available.
+func Handler(bucket *Bucket, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ok, wait := bucket.take(); !ok {
+			problem.Write(w, r, problem.Problem{
+				Status:     http.StatusTooManyRequests,
+				Detail:     "request rate limit exceeded",
+				Reason:     problem.ReasonRateLimited,
+				RetryAfter: wait,
+			})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,36 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandlerRefusesWithProblem(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := NewBucket(0.5, 1)
+	b.now = func() time.Time { return now }
+
+	h := Handler(b, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest("GET", "/", nil))
+
+	if want, got := 200, resp.Code; want != got {
+		t.Fatalf("expected the first request to pass with %d, got %d", want, got)
+	}
+
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest("GET", "/", nil))
+
+	if want, got := http.StatusTooManyRequests, resp.Code; want != got {
+		t.Fatalf("expected %d once the bucket is empty, got %d", want, got)
+	}
+	if want, got := "2", resp.Header().Get("Retry-After"); want != got {
+		t.Fatalf("expected to retry after the next token %q, got %q", want, got)
+	}
+	if want, got := "application/problem+json", resp.Header().Get("Content-Type"); want != got {
+		t.Fatalf("expected content type %q, got %q", want, got)
+	}
+}
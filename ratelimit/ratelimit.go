@@ -0,0 +1,130 @@
+/*
+Package ratelimit limits the rate of client requests with token buckets, to
+respect upstream quotas instead of running into their 429 responses, and
+limits the rate of requests served with a Handler.
+*/
+package ratelimit
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// LimitError is returned by a fail fast Transport when no token is
+// available.
+type LimitError struct {
+	// Host is the limited host, empty when the global limit was reached.
+	Host string
+
+	// RetryAfter is the time until a token is expected to be available.
+	RetryAfter time.Duration
+}
+
+func (e LimitError) Error() string {
+	if e.Host == "" {
+		return fmt.Sprintf("rate limited, retry after %s", e.RetryAfter)
+	}
+	return fmt.Sprintf("rate limited for %s, retry after %s", e.Host, e.RetryAfter)
+}
+
+// Temporary reports the error as temporary, so retry.Temporary retries it.
+func (e LimitError) Temporary() bool {
+	return true
+}
+
+// Transport is an http.RoundTripper taking a token from the Global bucket
+// and from the bucket of the request host before forwarding a request.
+type Transport struct {
+	// Global limits all requests when set.
+	Global *Bucket
+
+	// PerHostRate and PerHostBurst configure a bucket for each host when
+	// PerHostRate is positive.  Buckets of idle hosts that have refilled
+	// are dropped as new hosts are added.
+	PerHostRate  float64
+	PerHostBurst int
+
+	// FailFast returns a LimitError instead of waiting for a token.
+	FailFast bool
+
+	// Next is the http.RoundTripper to which requests are forwarded.  If Next
+	// is nil, http.DefaultTransport is used.
+	Next http.RoundTripper
+
+	mu      sync.Mutex
+	hosts   map[string]*Bucket
+	sweepAt int
+}
+
+// minSweep is the number of hosts from which idle buckets are dropped.
+const minSweep = 64
+
+func (t *Transport) host(host string) *Bucket {
+	if t.PerHostRate <= 0 {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.hosts == nil {
+		t.hosts = make(map[string]*Bucket)
+	}
+	b, ok := t.hosts[host]
+	if !ok {
+		t.sweep()
+		b = NewBucket(t.PerHostRate, t.PerHostBurst)
+		t.hosts[host] = b
+	}
+	return b
+}
+
+// sweep drops the full buckets once the hosts have doubled since the last
+// sweep, so that the cost is amortized over the added hosts.
+func (t *Transport) sweep() {
+	if len(t.hosts) < t.sweepAt || len(t.hosts) < minSweep {
+		return
+	}
+	for host, b := range t.hosts {
+		if b.full() {
+			delete(t.hosts, host)
+		}
+	}
+	t.sweepAt = 2 * len(t.hosts)
+}
+
+// RoundTrip implements the RoundTripper interface.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.acquire(req, t.Global, ""); err != nil {
+		return nil, err
+	}
+
+	if err := t.acquire(req, t.host(req.URL.Host), req.URL.Host); err != nil {
+		// The request is not sent, so it does not count against the global
+		// limit
+		if t.Global != nil {
+			t.Global.refund()
+		}
+		return nil, err
+	}
+
+	if t.Next != nil {
+		return t.Next.RoundTrip(req)
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func (t *Transport) acquire(req *http.Request, b *Bucket, host string) error {
+	if b == nil {
+		return nil
+	}
+	if !t.FailFast {
+		return b.Wait(req.Context())
+	}
+	if ok, wait := b.take(); !ok {
+		return LimitError{Host: host, RetryAfter: wait}
+	}
+	return nil
+}
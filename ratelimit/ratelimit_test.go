@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/streadway/handy/retry"
+)
+
+func TestFailFastPerHost(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer s.Close()
+
+	trans := &Transport{PerHostRate: 1, PerHostBurst: 1, FailFast: true}
+
+	req, _ := http.NewRequest("GET", s.URL, nil)
+	resp, err := trans.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("expected the first request to pass, got: %v", err)
+	}
+	resp.Body.Close()
+
+	_, err = trans.RoundTrip(req)
+	limited, ok := err.(LimitError)
+	if !ok {
+		t.Fatalf("expected a LimitError, got: %v", err)
+	}
+	if limited.Host != req.URL.Host || limited.RetryAfter <= 0 {
+		t.Fatalf("expected the host and retry after to be reported, got: %+v", limited)
+	}
+
+	if decision, _ := retry.Temporary()(retry.Attempt{Err: err}); decision != retry.Retry {
+		t.Fatalf("expected retry.Temporary to retry a LimitError, got: %v", decision)
+	}
+}
+
+func TestFailFastRefundsGlobal(t *testing.T) {
+	var (
+		next  = roundTripFunc(func(*http.Request) (*http.Response, error) { return &http.Response{StatusCode: 200}, nil })
+		trans = &Transport{Global: NewBucket(0, 2), PerHostRate: 0.001, PerHostBurst: 1, FailFast: true, Next: next}
+	)
+
+	for i, test := range []struct {
+		url     string
+		limited bool
+	}{
+		{"http://a/", false},
+		{"http://a/", true},
+		{"http://b/", false},
+		{"http://c/", true},
+	} {
+		req, _ := http.NewRequest("GET", test.url, nil)
+		_, err := trans.RoundTrip(req)
+		if limited := err != nil; test.limited != limited {
+			t.Fatalf("request %d to %s: expected limited %v, got: %v", i, test.url, test.limited, err)
+		}
+	}
+}
+
+func TestDropsIdleHosts(t *testing.T) {
+	trans := &Transport{PerHostRate: 1e9, PerHostBurst: 1}
+
+	for i := 0; i < 1000; i++ {
+		trans.host(fmt.Sprintf("host-%d", i))
+	}
+
+	if n := len(trans.hosts); n > 2*minSweep {
+		t.Fatalf("expected the buckets of idle hosts to be dropped, got %d", n)
+	}
+}
+
+func TestGlobalWaits(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer s.Close()
+
+	trans := &Transport{Global: NewBucket(50, 1)}
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest("GET", s.URL, nil)
+		resp, err := trans.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Fatalf("expected requests to wait for tokens, took %s", elapsed)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
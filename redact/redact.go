@@ -0,0 +1,37 @@
+/*
+Package redact strips credentials from URLs, so that requests can be logged
+and reported without leaking them.
+*/
+package redact
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Redacted replaces the values of redacted query parameters.
+const Redacted = "REDACTED"
+
+// Query are the query parameters whose values are redacted by default.
+var Query = []string{"access_token", "api_key", "apikey", "key", "password", "secret", "signature", "token"}
+
+// URL returns u without user information, and with the values of the
+// query parameters named in params, case insensitively, replaced by
+// Redacted.
+func URL(u *url.URL, params []string) string {
+	out := *u
+	out.User = nil
+
+	if out.RawQuery != "" {
+		q := out.Query()
+		for k := range q {
+			for _, name := range params {
+				if strings.EqualFold(name, k) {
+					q.Set(k, Redacted)
+				}
+			}
+		}
+		out.RawQuery = q.Encode()
+	}
+	return out.String()
+}
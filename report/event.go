@@ -9,8 +9,14 @@ Package report organizes textual reporting from the HTTP context.
 package report
 
 import (
+	"context"
+	"net"
 	"net/http"
+	"strings"
 	"time"
+
+	"github.com/streadway/handy/reqctx"
+	"github.com/streadway/handy/semconv"
 )
 
 // Event contains significant fields from the request or response to report
@@ -35,6 +41,63 @@ type Event struct {
 	Country        string    `json:"country,omitempty"`
 	City           string    `json:"city,omitempty"`
 	RequestId      string    `json:"request_id,omitempty"`
+	Route          string    `json:"route,omitempty"`
+	Tenant         string    `json:"tenant,omitempty"`
+	Attempts       uint      `json:"attempts,omitempty"`
+}
+
+// Attributes describes the event with the OpenTelemetry semantic
+// conventions.
+func (e Event) Attributes() []semconv.Attribute {
+	var b semconv.Builder
+	if e.Method != "" {
+		b.Method(e.Method)
+	}
+	b.Add(semconv.URLPath, e.Path)
+	b.Add(semconv.HTTPRoute, e.Route)
+	if e.Status != 0 {
+		b.Add(semconv.HTTPResponseStatusCode, e.Status)
+	}
+	b.Add(semconv.HTTPResponseBodySize, e.Size)
+	if e.RemoteAddr != "" {
+		b.Client(e.RemoteAddr)
+	}
+	b.Add(semconv.UserAgentOriginal, e.UserAgent)
+	b.Add(semconv.NetworkProtocolVersion, strings.TrimPrefix(e.Proto, "HTTP/"))
+	return b.Attempt(e.Attempts).Attributes()
+}
+
+// fromContext completes the event with the values shared by other
+// components in the request context.
+func (e *Event) fromContext(ctx context.Context) {
+	if id := reqctx.RequestID(ctx); id != "" {
+		e.RequestId = id
+	}
+	e.Route = reqctx.Route(ctx)
+	e.Tenant = reqctx.Tenant(ctx)
+}
+
+// share returns r with the request ID of its X-Request-Id header and the
+// client IP of its RemoteAddr in its context, unless already set, for the
+// components serving it.  The context tracks the route set by inner
+// handlers.
+func share(r *http.Request) *http.Request {
+	ctx := reqctx.TrackRoute(r.Context())
+	if reqctx.RequestID(ctx) == "" {
+		if id := r.Header.Get("X-Request-Id"); id != "" {
+			ctx = reqctx.WithRequestID(ctx, id)
+		}
+	}
+	if reqctx.ClientIP(ctx) == nil {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		if ip := net.ParseIP(host); ip != nil {
+			ctx = reqctx.WithClientIP(ctx, ip)
+		}
+	}
+	return r.WithContext(ctx)
 }
 
 type eventRecorder struct {
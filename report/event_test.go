@@ -0,0 +1,28 @@
+// Copyright (c) 2013, SoundCloud Ltd.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the README file.
+// Source code and contact info at http://github.com/streadway/handy
+
+package report
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestEventAttributes(t *testing.T) {
+	e := Event{
+		Method:     "GET",
+		Path:       "/items",
+		Proto:      "HTTP/1.1",
+		Status:     200,
+		Size:       42,
+		RemoteAddr: "10.0.0.1:5678",
+		UserAgent:  "curl/8.0",
+	}
+
+	want := "[http.request.method=GET url.path=/items http.response.status_code=200 http.response.body.size=42 client.address=10.0.0.1 user_agent.original=curl/8.0 network.protocol.version=1.1]"
+	if got := fmt.Sprint(e.Attributes()); want != got {
+		t.Fatalf("expected attributes\n%s\ngot\n%s", want, got)
+	}
+}
@@ -46,10 +46,14 @@ func JSONMiddleware(writer io.Writer) func(http.Handler) http.Handler {
 				},
 			}
 
+			r = share(r)
+
 			start := time.Now()
 
 			next.ServeHTTP(writer, r)
 
+			writer.event.fromContext(r.Context())
+
 			writer.event.Ms = int(time.Since(start) / time.Millisecond)
 
 			mu.Lock()
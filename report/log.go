@@ -0,0 +1,149 @@
+// Copyright (c) 2013, SoundCloud Ltd.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the README file.
+// Source code and contact info at http://github.com/streadway/handy
+
+package report
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/streadway/handy/redact"
+	"github.com/streadway/handy/retry"
+)
+
+// Logger is the logging abstraction shared with retry.Logger, like a
+// *log.Logger.
+type Logger interface {
+	Printf(string, ...interface{})
+}
+
+func logEvent(logger Logger, event Event) {
+	b, err := json.Marshal(event)
+	if err != nil {
+		logger.Printf("error: could not encode report: %v", err)
+		return
+	}
+	logger.Printf("%s", b)
+}
+
+// LogMiddleware returns a composable handler factory implementing the Log
+// handler.
+func LogMiddleware(logger Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			writer := &eventRecorder{
+				ResponseWriter: w,
+				event: Event{
+					// Size & Status possiblly overwritten by the ResponseWriter interface
+					Status: 200,
+					Time:   time.Now().UTC(),
+					Method: r.Method,
+					Url:    r.RequestURI,
+					Path:   r.URL.Path,
+					Proto:  r.Proto,
+					Host:   r.Host,
+				},
+			}
+
+			r = share(r)
+
+			start := time.Now()
+
+			next.ServeHTTP(writer, r)
+
+			writer.event.fromContext(r.Context())
+
+			writer.event.Ms = int(time.Since(start) / time.Millisecond)
+
+			logEvent(logger, writer.event)
+		})
+	}
+}
+
+// Log writes a JSON encoded Event to the logger at the completion of each
+// request.
+func Log(logger Logger, next http.Handler) http.Handler {
+	return LogMiddleware(logger)(next)
+}
+
+// Transport produces an http.RoundTripper that logs a JSON encoded Event for
+// each request once its response body is closed.  When next is a
+// retry.Transport, the Event includes the number of attempts.  The request
+// ID, route and tenant are taken from the request context, see reqctx, and
+// the URL is redacted, see redact.Query.  If next is nil,
+// http.DefaultTransport is used.
+func Transport(logger Logger, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{logger: logger, next: next}
+}
+
+type transport struct {
+	logger Logger
+	next   http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var (
+		counter retry.Counter
+		start   = time.Now()
+		event   = Event{
+			Time:   start.UTC(),
+			Method: req.Method,
+			Url:    redact.URL(req.URL, redact.Query),
+			Path:   req.URL.Path,
+			Host:   req.URL.Host,
+		}
+	)
+	event.fromContext(req.Context())
+
+	resp, err := t.next.RoundTrip(req.WithContext(retry.WithCounter(req.Context(), &counter)))
+
+	event.Attempts = counter.Attempts()
+
+	if err != nil {
+		event.Ms = int(time.Since(start) / time.Millisecond)
+		logEvent(t.logger, event)
+		return resp, err
+	}
+
+	event.Proto = resp.Proto
+	event.Status = resp.StatusCode
+	resp.Body = &bodyRecorder{
+		ReadCloser: resp.Body,
+		done: func(size int64) {
+			event.Size = size
+			event.Ms = int(time.Since(start) / time.Millisecond)
+			logEvent(t.logger, event)
+		},
+	}
+
+	return resp, nil
+}
+
+// bodyRecorder sums the reads of a response body and reports the total once
+// closed.
+type bodyRecorder struct {
+	io.ReadCloser
+	size int64
+	once sync.Once
+	done func(int64)
+}
+
+func (b *bodyRecorder) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += int64(n)
+	return n, err
+}
+
+func (b *bodyRecorder) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.done(b.size) })
+	return err
+}
@@ -0,0 +1,164 @@
+// Copyright (c) 2013, SoundCloud Ltd.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the README file.
+// Source code and contact info at http://github.com/streadway/handy
+
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/streadway/handy/reqctx"
+	"github.com/streadway/handy/retry"
+)
+
+type lines []string
+
+func (l *lines) Printf(format string, v ...interface{}) {
+	*l = append(*l, fmt.Sprintf(format, v...))
+}
+
+func (l lines) event(t *testing.T, i int) Event {
+	var e Event
+	if err := json.Unmarshal([]byte(l[i]), &e); err != nil {
+		t.Fatalf("expected to decode json report, got: %q", err)
+	}
+	return e
+}
+
+func TestLogHandler(t *testing.T) {
+	var logged lines
+
+	h := Log(&logged, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(201)
+		w.Write([]byte("created"))
+	}))
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "http://example.org/foo", nil)
+	h.ServeHTTP(res, req)
+
+	if len(logged) != 1 {
+		t.Fatalf("expected one report, got %d", len(logged))
+	}
+
+	e := logged.event(t, 0)
+	if e.Method != "POST" || e.Path != "/foo" || e.Status != 201 || e.Size != 7 {
+		t.Fatalf("unexpected report: %+v", e)
+	}
+}
+
+func TestLogTransportWithRetry(t *testing.T) {
+	var (
+		logged lines
+		calls  int
+	)
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 2 {
+			w.WriteHeader(503)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer s.Close()
+
+	c := http.Client{
+		Transport: Transport(&logged, retry.Transport{
+			Retry: retry.Limit(retry.Over(500), retry.Max(3)),
+			Next:  http.DefaultTransport,
+		}),
+	}
+
+	resp, err := c.Get(s.URL + "/bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.ReadAll(resp.Body)
+
+	if len(logged) != 0 {
+		t.Fatalf("expected to report after the body is closed, got %d reports", len(logged))
+	}
+
+	resp.Body.Close()
+
+	if len(logged) != 1 {
+		t.Fatalf("expected one report, got %d", len(logged))
+	}
+
+	e := logged.event(t, 0)
+	if e.Method != "GET" || e.Path != "/bar" || e.Status != 200 || e.Size != 2 || e.Attempts != 2 {
+		t.Fatalf("unexpected report: %+v", e)
+	}
+}
+
+func TestLogHandlerFromContext(t *testing.T) {
+	var logged lines
+
+	req, _ := http.NewRequest("GET", "http://example.org/users/1", nil)
+	ctx := reqctx.WithRequestID(req.Context(), "abc")
+	ctx = reqctx.WithRoute(ctx, "/users/{id}")
+	ctx = reqctx.WithTenant(ctx, "acme")
+
+	Log(&logged, http.NotFoundHandler()).ServeHTTP(httptest.NewRecorder(), req.WithContext(ctx))
+
+	e := logged.event(t, 0)
+	if e.RequestId != "abc" || e.Route != "/users/{id}" || e.Tenant != "acme" {
+		t.Fatalf("expected the values of the request context, got: %+v", e)
+	}
+}
+
+func TestLogHandlerSharesRequestValues(t *testing.T) {
+	var (
+		logged lines
+		shared *http.Request
+	)
+
+	router := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		shared = r.WithContext(reqctx.WithRoute(r.Context(), "/users/{id}"))
+	})
+
+	req := httptest.NewRequest("GET", "http://example.org/users/1", nil)
+	req.Header.Set("X-Request-Id", "abc")
+	req.RemoteAddr = "10.0.0.1:1234"
+
+	Log(&logged, router).ServeHTTP(httptest.NewRecorder(), req)
+
+	if want, got := "abc", reqctx.RequestID(shared.Context()); want != got {
+		t.Fatalf("expected the request ID %q to be shared, got %q", want, got)
+	}
+	if want, got := "10.0.0.1", reqctx.ClientIP(shared.Context()).String(); want != got {
+		t.Fatalf("expected the client IP %s to be shared, got %s", want, got)
+	}
+
+	e := logged.event(t, 0)
+	if e.RequestId != "abc" || e.Route != "/users/{id}" {
+		t.Fatalf("expected the request ID and the route set by the router, got: %+v", e)
+	}
+}
+
+func TestLogTransportDefaultsAndRedacts(t *testing.T) {
+	var logged lines
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer s.Close()
+
+	u := strings.Replace(s.URL, "http://", "http://user:pass@", 1) + "/?token=s3cret"
+	resp, err := (&http.Client{Transport: Transport(&logged, nil)}).Get(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	e := logged.event(t, 0)
+	if want, got := s.URL+"/?token=REDACTED", e.Url; want != got {
+		t.Fatalf("expected the redacted URL %s, got %s", want, got)
+	}
+}
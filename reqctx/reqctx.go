@@ -0,0 +1,180 @@
+/*
+Package reqctx carries the data handy middlewares and transports share about
+a request in its context, so that a value set by one component, like the
+request ID read by a server middleware, is found by every other one, like
+the transports of the outgoing requests made while serving it.
+
+Each common value has a With function returning a context carrying it, and a
+function of the same name returning it, or its zero value when the context
+carries none.  Values owned by a single package, like the Trace of retry,
+are stored with a Key of their type.
+
+The report middlewares set the request ID and client IP of the requests
+they serve, and track the route set by the handlers they wrap.
+*/
+package reqctx
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+// Key is a typed context key, unique per NewKey call.
+type Key[T any] struct {
+	name string
+}
+
+// NewKey returns a key for values of type T, named for debugging.
+func NewKey[T any](name string) *Key[T] {
+	return &Key[T]{name: name}
+}
+
+// With returns a context carrying v.
+func (k *Key[T]) With(ctx context.Context, v T) context.Context {
+	return context.WithValue(ctx, k, v)
+}
+
+// Value returns the value carried by ctx, and whether there is one.
+func (k *Key[T]) Value(ctx context.Context) (T, bool) {
+	v, ok := ctx.Value(k).(T)
+	return v, ok
+}
+
+func (k *Key[T]) String() string {
+	return "reqctx." + k.name
+}
+
+var (
+	requestIDKey = NewKey[string]("RequestID")
+	clientIPKey  = NewKey[net.IP]("ClientIP")
+	routeKey     = NewKey[string]("Route")
+	trackerKey   = NewKey[*tracker]("RouteTracker")
+	tenantKey    = NewKey[string]("Tenant")
+	counterKey   = NewKey[*Counter]("Counter")
+)
+
+// WithRequestID returns a context carrying the ID correlating the logs of a
+// request, like the value of its X-Request-Id header.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return requestIDKey.With(ctx, id)
+}
+
+// RequestID returns the request ID carried by ctx.
+func RequestID(ctx context.Context) string {
+	id, _ := requestIDKey.Value(ctx)
+	return id
+}
+
+// WithClientIP returns a context carrying the address of the client that
+// originated a request, after resolving any proxies in front of the server.
+func WithClientIP(ctx context.Context, ip net.IP) context.Context {
+	return clientIPKey.With(ctx, ip)
+}
+
+// ClientIP returns the client address carried by ctx.
+func ClientIP(ctx context.Context) net.IP {
+	ip, _ := clientIPKey.Value(ctx)
+	return ip
+}
+
+// WithRoute returns a context carrying the template of the route matching a
+// request, like "/users/{id}", a low cardinality name for metrics.  The
+// route is also recorded for the middlewares that called TrackRoute on a
+// parent context.
+func WithRoute(ctx context.Context, route string) context.Context {
+	if t, ok := trackerKey.Value(ctx); ok {
+		t.set(route)
+	}
+	return routeKey.With(ctx, route)
+}
+
+// Route returns the route template carried by ctx, or else the last one
+// set by the handlers of a context returned by TrackRoute.
+func Route(ctx context.Context) string {
+	if route, ok := routeKey.Value(ctx); ok {
+		return route
+	}
+	if t, ok := trackerKey.Value(ctx); ok {
+		return t.get()
+	}
+	return ""
+}
+
+// TrackRoute returns a context recording the routes set with WithRoute on
+// its children, so that middlewares wrapping the router learn from Route
+// the route matched after serving the request.
+func TrackRoute(ctx context.Context) context.Context {
+	parent, _ := trackerKey.Value(ctx)
+	return trackerKey.With(ctx, &tracker{parent: parent})
+}
+
+// tracker records routes for its context and the tracked parents.
+type tracker struct {
+	parent *tracker
+	mu     sync.Mutex
+	route  string
+}
+
+func (t *tracker) set(route string) {
+	for ; t != nil; t = t.parent {
+		t.mu.Lock()
+		t.route = route
+		t.mu.Unlock()
+	}
+}
+
+func (t *tracker) get() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.route
+}
+
+// WithTenant returns a context carrying the tenant a request is made for.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return tenantKey.With(ctx, tenant)
+}
+
+// Tenant returns the tenant carried by ctx.
+func Tenant(ctx context.Context) string {
+	tenant, _ := tenantKey.Value(ctx)
+	return tenant
+}
+
+// Counter records the number of attempts made for a request, safe for
+// concurrent use.
+type Counter struct {
+	attempts uint32
+}
+
+// Attempts returns the number of attempts made so far.
+func (c *Counter) Attempts() uint {
+	return uint(atomic.LoadUint32(&c.attempts))
+}
+
+// Set records the number of the current attempt.
+func (c *Counter) Set(count uint) {
+	atomic.StoreUint32(&c.attempts, uint32(count))
+}
+
+// WithCounter returns a context carrying c, updated by the transport
+// retrying requests with this context.
+func WithCounter(ctx context.Context, c *Counter) context.Context {
+	return counterKey.With(ctx, c)
+}
+
+// CounterFrom returns the Counter carried by ctx, or nil.
+func CounterFrom(ctx context.Context) *Counter {
+	c, _ := counterKey.Value(ctx)
+	return c
+}
+
+// Attempt returns the number of the current attempt of the request with
+// ctx, which is the first when ctx carries no Counter.
+func Attempt(ctx context.Context) uint {
+	if c := CounterFrom(ctx); c != nil {
+		return c.Attempts()
+	}
+	return 1
+}
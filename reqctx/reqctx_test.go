@@ -0,0 +1,79 @@
+package reqctx
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+func TestValues(t *testing.T) {
+	ctx := context.Background()
+	ctx = WithRequestID(ctx, "abc")
+	ctx = WithClientIP(ctx, net.IPv4(10, 0, 0, 1))
+	ctx = WithRoute(ctx, "/users/{id}")
+	ctx = WithTenant(ctx, "acme")
+
+	if want, got := "abc", RequestID(ctx); want != got {
+		t.Fatalf("expected request ID %q, got %q", want, got)
+	}
+	if want, got := "10.0.0.1", ClientIP(ctx).String(); want != got {
+		t.Fatalf("expected client IP %s, got %s", want, got)
+	}
+	if want, got := "/users/{id}", Route(ctx); want != got {
+		t.Fatalf("expected route %q, got %q", want, got)
+	}
+	if want, got := "acme", Tenant(ctx); want != got {
+		t.Fatalf("expected tenant %q, got %q", want, got)
+	}
+}
+
+func TestZeroValues(t *testing.T) {
+	ctx := context.Background()
+
+	if RequestID(ctx) != "" || ClientIP(ctx) != nil || Route(ctx) != "" || Tenant(ctx) != "" || CounterFrom(ctx) != nil {
+		t.Fatalf("expected zero values from an empty context")
+	}
+	if want, got := uint(1), Attempt(ctx); want != got {
+		t.Fatalf("expected attempt %d without counter, got %d", want, got)
+	}
+}
+
+func TestCounter(t *testing.T) {
+	var c Counter
+	ctx := WithCounter(context.Background(), &c)
+
+	c.Set(3)
+	if want, got := uint(3), Attempt(ctx); want != got {
+		t.Fatalf("expected attempt %d, got %d", want, got)
+	}
+}
+
+func TestTrackRoute(t *testing.T) {
+	outer := TrackRoute(context.Background())
+	ctx := TrackRoute(outer)
+
+	// An inner router sets the route on a child context
+	inner := WithRoute(WithTenant(ctx, "acme"), "/users/{id}")
+
+	if want, got := "/users/{id}", Route(outer); want != got {
+		t.Fatalf("expected the route tracked by the outer context %q, got %q", want, got)
+	}
+	if want, got := "/users/{id}", Route(ctx); want != got {
+		t.Fatalf("expected the route from the parent context %q, got %q", want, got)
+	}
+	if want, got := "/users/{id}", Route(inner); want != got {
+		t.Fatalf("expected route %q, got %q", want, got)
+	}
+}
+
+func TestKey(t *testing.T) {
+	a, b := NewKey[int]("a"), NewKey[int]("b")
+	ctx := a.With(context.Background(), 1)
+
+	if v, ok := a.Value(ctx); !ok || v != 1 {
+		t.Fatalf("expected the value of a, got %d, %v", v, ok)
+	}
+	if _, ok := b.Value(ctx); ok {
+		t.Fatalf("expected keys of the same type to be distinct")
+	}
+}
@@ -0,0 +1,83 @@
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/streadway/handy/redact"
+)
+
+// Redacted replaces the values of redacted query parameters.
+const Redacted = redact.Redacted
+
+// RedactQuery are the query parameters whose values are redacted by
+// Attempt.String and the logs of the Transport.
+var RedactQuery = redact.Query
+
+// String describes the attempt by method, URL, count, outcome and the time
+// elapsed since the first attempt.  The URL is stripped of user information
+// and of the values of the RedactQuery parameters, so that attempts can be
+// logged without leaking credentials.
+func (a Attempt) String() string {
+	var b strings.Builder
+
+	if a.Request != nil {
+		b.WriteString(redactedRequest{a.Request}.String())
+		b.WriteByte(' ')
+	}
+	fmt.Fprintf(&b, "attempt %d", a.Count)
+
+	switch {
+	case a.Err != nil:
+		b.WriteString(": ")
+		b.WriteString(errorString(a.Err))
+	case a.Response != nil:
+		b.WriteString(": ")
+		b.WriteString(a.Response.Status)
+		if a.Response.Status == "" {
+			fmt.Fprint(&b, a.Response.StatusCode)
+		}
+	}
+
+	if !a.Start.IsZero() {
+		fmt.Fprintf(&b, " after %s", now().Sub(a.Start).Round(time.Millisecond))
+	}
+
+	return b.String()
+}
+
+// redactedRequest describes a request by method and redacted URL when
+// formatted, so that logs not written cost no allocations.
+type redactedRequest struct{ *http.Request }
+
+func (r redactedRequest) String() string {
+	if r.URL == nil {
+		return r.Method
+	}
+	return r.Method + " " + redactURL(r.URL)
+}
+
+// redactedError describes an error, redacting the URL of a *url.Error.
+type redactedError struct{ err error }
+
+func (e redactedError) String() string {
+	return errorString(e.err)
+}
+
+func errorString(err error) string {
+	var uerr *url.Error
+	if errors.As(err, &uerr) {
+		if u, perr := url.Parse(uerr.URL); perr == nil {
+			return fmt.Sprintf("%s %q: %s", uerr.Op, redactURL(u), uerr.Err)
+		}
+	}
+	return err.Error()
+}
+
+func redactURL(u *url.URL) string {
+	return redact.URL(u, RedactQuery)
+}
@@ -0,0 +1,30 @@
+package retry
+
+import (
+	"context"
+
+	"github.com/streadway/handy/reqctx"
+)
+
+// Counter records the number of attempts RoundTrip has made for a request.
+type Counter = reqctx.Counter
+
+// WithCounter returns a context carrying c.  RoundTrip updates c with the
+// attempts made for requests with this context, so that wrapping transports
+// can learn how many attempts a request took.  Without a Counter, RoundTrip
+// adds one to the context of retried requests passed to Next, so that inner
+// transports can learn the number of the current attempt with AttemptFrom.
+func WithCounter(ctx context.Context, c *Counter) context.Context {
+	return reqctx.WithCounter(ctx, c)
+}
+
+// CounterFrom returns the Counter carried by ctx, or nil.
+func CounterFrom(ctx context.Context) *Counter {
+	return reqctx.CounterFrom(ctx)
+}
+
+// AttemptFrom returns the number of the current attempt of the request with
+// ctx, which is the first when ctx carries no Counter.
+func AttemptFrom(ctx context.Context) uint {
+	return reqctx.Attempt(ctx)
+}
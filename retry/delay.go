@@ -3,33 +3,90 @@ package retry
 import (
 	"math"
 	"time"
+
+	"github.com/streadway/handy/failure"
+	"github.com/streadway/handy/random"
 )
 
-// Constant sleeps for delta duration
-func Constant(delta time.Duration) Delayer {
+// Backoff chooses the amount of time to wait after an attempt before the
+// next one is issued.
+type Backoff func(Attempt) time.Duration
+
+// Sleep sleeps for the duration chosen by backoff, or until the request ends.
+func Sleep(backoff Backoff) Delayer {
 	return func(a Attempt) {
-		time.Sleep(delta)
+		if a.Request == nil {
+			time.Sleep(backoff(a))
+			return
+		}
+		failure.Sleep(a.Request.Context(), backoff(a))
 	}
 }
 
+// Constant sleeps for delta duration
+func Constant(delta time.Duration) Delayer {
+	return Sleep(ConstantBackoff(delta))
+}
+
 // Linear sleeps for delta * the number of attempts
 func Linear(delta time.Duration) Delayer {
-	return func(a Attempt) {
-		time.Sleep(delta * time.Duration(a.Count))
-	}
+	return Sleep(LinearBackoff(delta))
 }
 
 // Linear sleeps for delta * 2^attempts
 func Exponential(base time.Duration) Delayer {
-	return func(a Attempt) {
-		time.Sleep(time.Duration(float64(base) * math.Exp(float64(a.Count))))
-	}
+	return Sleep(ExponentialBackoff(base))
 }
 
 // Fibonacci sleeps for delta * fib(attempts)
 func Fibonacci(delta time.Duration) Delayer {
-	return func(a Attempt) {
-		time.Sleep(delta * time.Duration(fib(a.Count)))
+	return Sleep(FibonacciBackoff(delta))
+}
+
+// ConstantBackoff waits for delta duration
+func ConstantBackoff(delta time.Duration) Backoff {
+	return func(a Attempt) time.Duration {
+		return delta
+	}
+}
+
+// LinearBackoff waits for delta * the number of attempts
+func LinearBackoff(delta time.Duration) Backoff {
+	return func(a Attempt) time.Duration {
+		return delta * time.Duration(a.Count)
+	}
+}
+
+// ExponentialBackoff waits for base * e^attempts
+func ExponentialBackoff(base time.Duration) Backoff {
+	return func(a Attempt) time.Duration {
+		return time.Duration(float64(base) * math.Exp(float64(a.Count)))
+	}
+}
+
+// FibonacciBackoff waits for delta * fib(attempts)
+func FibonacciBackoff(delta time.Duration) Backoff {
+	return func(a Attempt) time.Duration {
+		return delta * time.Duration(fib(a.Count))
+	}
+}
+
+// Jitter spreads the waits of backoff by up to fraction of them in either
+// direction, drawing from src.  A nil src uses random.Default.
+func Jitter(backoff Backoff, fraction float64, src random.Source) Backoff {
+	src = random.Or(src)
+	return func(a Attempt) time.Duration {
+		d := backoff(a)
+		return time.Duration(float64(d) * (1 + fraction*(2*src.Float64()-1)))
+	}
+}
+
+// FullJitter waits for a random duration up to the wait of backoff, drawing
+// from src.  A nil src uses random.Default.
+func FullJitter(backoff Backoff, src random.Source) Backoff {
+	src = random.Or(src)
+	return func(a Attempt) time.Duration {
+		return time.Duration(float64(backoff(a)) * src.Float64())
 	}
 }
 
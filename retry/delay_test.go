@@ -1,6 +1,11 @@
 package retry
 
-import "testing"
+import (
+	"testing"
+	"time"
+
+	"github.com/streadway/handy/random"
+)
 
 func TestFib(t *testing.T) {
 	for i, want := range []int64{0, 1, 1, 2, 3, 5, 8, 13, 21} {
@@ -9,3 +14,38 @@ func TestFib(t *testing.T) {
 		}
 	}
 }
+
+func TestBackoffDurations(t *testing.T) {
+	for name, it := range map[string]struct {
+		backoff Backoff
+		want    []time.Duration
+	}{
+		"constant":  {ConstantBackoff(time.Second), []time.Duration{time.Second, time.Second, time.Second}},
+		"linear":    {LinearBackoff(time.Second), []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}},
+		"fibonacci": {FibonacciBackoff(time.Second), []time.Duration{time.Second, time.Second, 2 * time.Second}},
+	} {
+		for i, want := range it.want {
+			if got := it.backoff(Attempt{Count: uint(i + 1)}); want != got {
+				t.Fatalf("%s: for attempt %d, want: %v, got: %v", name, i+1, want, got)
+			}
+		}
+	}
+}
+
+func TestJitter(t *testing.T) {
+	backoff := ConstantBackoff(time.Second)
+
+	for name, test := range map[string]struct {
+		backoff Backoff
+		want    time.Duration
+	}{
+		"jitter low":       {Jitter(backoff, 0.2, random.Fixed(0)), 800 * time.Millisecond},
+		"jitter high":      {Jitter(backoff, 0.2, random.Fixed(0.75)), 1100 * time.Millisecond},
+		"full jitter":      {FullJitter(backoff, random.Fixed(0.25)), 250 * time.Millisecond},
+		"full jitter zero": {FullJitter(backoff, random.Fixed(0)), 0},
+	} {
+		if got := test.backoff(Attempt{Count: 1}); test.want != got {
+			t.Errorf("%s: want %s, got %s", name, test.want, got)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package retry
+
+import (
+	"fmt"
+	"time"
+)
+
+// Window is a period of maintenance from Start until End.
+type Window struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Contains reports whether t is within the window.
+func (w Window) Contains(t time.Time) bool {
+	return !t.Before(w.Start) && t.Before(w.End)
+}
+
+// Schedule returns the maintenance window containing a time, if any.
+type Schedule interface {
+	Maintenance(time.Time) (Window, bool)
+}
+
+// ScheduleFunc adapts a function to a Schedule, for example to consult the
+// maintenance windows published by an upstream.
+type ScheduleFunc func(time.Time) (Window, bool)
+
+// Maintenance implements Schedule.
+func (f ScheduleFunc) Maintenance(t time.Time) (Window, bool) {
+	return f(t)
+}
+
+// Windows is a Schedule of one-off maintenance windows.
+type Windows []Window
+
+// Maintenance implements Schedule.
+func (ws Windows) Maintenance(t time.Time) (Window, bool) {
+	for _, w := range ws {
+		if w.Contains(t) {
+			return w, true
+		}
+	}
+	return Window{}, false
+}
+
+// Daily is a Schedule of maintenance windows recurring at the same time of
+// day.
+type Daily struct {
+	// Start is the time of day the window starts, as the time since
+	// midnight.
+	Start time.Duration
+
+	// Duration of the window, which may extend into the next day.
+	Duration time.Duration
+
+	// Weekdays the window starts on.  If empty, it starts every day.
+	Weekdays []time.Weekday
+
+	// Location of the time of day, UTC when nil.
+	Location *time.Location
+}
+
+// Maintenance implements Schedule.
+func (d Daily) Maintenance(t time.Time) (Window, bool) {
+	loc := d.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+
+	// Windows that started yesterday may still be running
+	for _, days := range []int{0, -1} {
+		y, m, day := t.AddDate(0, 0, days).Date()
+		start := time.Date(y, m, day, 0, 0, 0, 0, loc).Add(d.Start)
+		w := Window{Start: start, End: start.Add(d.Duration)}
+
+		if d.on(start.Weekday()) && w.Contains(t) {
+			return w, true
+		}
+	}
+	return Window{}, false
+}
+
+func (d Daily) on(day time.Weekday) bool {
+	if len(d.Weekdays) == 0 {
+		return true
+	}
+	for _, w := range d.Weekdays {
+		if w == day {
+			return true
+		}
+	}
+	return false
+}
+
+// MaintenanceError is returned from RoundTrip when a retry is suppressed
+// during a maintenance window.
+type MaintenanceError struct {
+	Window Window
+}
+
+func (e MaintenanceError) Error() string {
+	return fmt.Sprintf("retry suppressed during maintenance until %s", e.Window.End.Format(time.RFC3339))
+}
+
+// Maintenance aborts the retries of retryer while the schedule is in a
+// maintenance window, so that requests fail or fall back immediately rather
+// than retrying against an upstream known to be down.  Outside of windows
+// the decisions of retryer are kept.
+func Maintenance(retryer Retryer, schedule Schedule) Retryer {
+	return func(a Attempt) (Decision, error) {
+		decision, err := retryer(a)
+		if decision != Retry {
+			return decision, err
+		}
+		if w, ok := schedule.Maintenance(now()); ok {
+			return Abort, MaintenanceError{w}
+		}
+		return Retry, nil
+	}
+}
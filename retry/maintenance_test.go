@@ -0,0 +1,71 @@
+package retry
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDaily(t *testing.T) {
+	nightly := Daily{
+		Start:    23 * time.Hour,
+		Duration: 2 * time.Hour,
+		Weekdays: []time.Weekday{time.Saturday},
+	}
+
+	for at, want := range map[string]bool{
+		"2024-06-01T22:59:00Z": false, // Saturday before the window
+		"2024-06-01T23:30:00Z": true,
+		"2024-06-02T00:30:00Z": true, // Sunday, started on Saturday
+		"2024-06-02T01:00:00Z": false,
+		"2024-06-02T23:30:00Z": false, // Sunday
+	} {
+		tm, _ := time.Parse(time.RFC3339, at)
+		if _, got := nightly.Maintenance(tm); want != got {
+			t.Errorf("%s: want maintenance %v, got %v", at, want, got)
+		}
+	}
+}
+
+func TestMaintenanceSuppressesRetries(t *testing.T) {
+	defer func(f func() time.Time) { now = f }(now)
+
+	current := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return current }
+
+	window := Window{Start: current.Add(-time.Minute), End: current.Add(time.Hour)}
+
+	var (
+		req, _ = http.NewRequest("GET", "http://example/test", nil)
+		next   = &testRoundTrip{err: fmt.Errorf("next")}
+		trans  = Transport{
+			Retry: Maintenance(Limit(Errors(), Max(3)), Windows{window}),
+			Next:  next,
+			Fallback: func(a Attempt) (*http.Response, error) {
+				return &http.Response{StatusCode: 503, Body: http.NoBody}, nil
+			},
+		}
+	)
+
+	resp, err := trans.RoundTrip(req)
+	if err != nil || resp.StatusCode != 503 {
+		t.Fatalf("expected the fallback during maintenance, got: %v, %v", resp, err)
+	}
+	if want, got := 1, next.count; want != got {
+		t.Fatalf("expected %d attempt during maintenance, got %d", want, got)
+	}
+
+	trans.Fallback = nil
+	if _, err := trans.RoundTrip(req); err != (MaintenanceError{window}) {
+		t.Fatalf("expected a MaintenanceError, got: %v", err)
+	}
+
+	current = window.End
+	next.count = 0
+	trans.RoundTrip(req)
+
+	if want, got := 3, next.count; want != got {
+		t.Fatalf("expected %d attempts after maintenance, got %d", want, got)
+	}
+}
@@ -0,0 +1,288 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/streadway/handy/random"
+)
+
+// Defaults used by New when the corresponding option is not given.
+const (
+	DefaultMaxAttempts = 3
+	DefaultTimeout     = 30 * time.Second
+	DefaultBackoffBase = 100 * time.Millisecond
+)
+
+// Option configures a Transport constructed by New.
+type Option func(*options)
+
+type options struct {
+	maxAttempts uint
+	timeout     time.Duration
+	statuses    []int
+	backoffs    []Backoff
+	retryer     Retryer
+	attempt     time.Duration
+	logger      Logger
+	next        http.RoundTripper
+	dial        func(ctx context.Context, network, addr string) (net.Conn, error)
+	jitter      float64
+	expect      int64
+	rand        random.Source
+
+	policy bool // set when an option shapes the built-in policy
+	errs   []error
+}
+
+func (o *options) fail(format string, v ...interface{}) {
+	o.errs = append(o.errs, fmt.Errorf(format, v...))
+}
+
+// WithMaxAttempts limits the number of round trips issued per request,
+// including the first one.
+func WithMaxAttempts(n uint) Option {
+	return func(o *options) {
+		if n == 0 {
+			o.fail("max attempts must be at least 1")
+		}
+		o.maxAttempts = n
+		o.policy = true
+	}
+}
+
+// WithTimeout aborts retrying once limit has passed since the first attempt.
+func WithTimeout(limit time.Duration) Option {
+	return func(o *options) {
+		if limit <= 0 {
+			o.fail("timeout must be positive, got %s", limit)
+		}
+		o.timeout = limit
+		o.policy = true
+	}
+}
+
+// WithRetryStatuses replaces the response status codes that are retried.
+func WithRetryStatuses(codes ...int) Option {
+	return func(o *options) {
+		for _, c := range codes {
+			if c < 100 || c > 599 {
+				o.fail("invalid retry status code %d", c)
+			}
+		}
+		o.statuses = append([]int{}, codes...)
+		o.policy = true
+	}
+}
+
+// WithAttemptTimeout limits the time of each attempt, see
+// Transport.AttemptTimeout.
+func WithAttemptTimeout(limit time.Duration) Option {
+	return func(o *options) {
+		if limit <= 0 {
+			o.fail("attempt timeout must be positive, got %s", limit)
+		}
+		o.attempt = limit
+	}
+}
+
+// WithExpectContinue asks servers to accept retried bodies of at least size
+// bytes before they are resent, see Transport.ExpectContinue.
+func WithExpectContinue(size int64) Option {
+	return func(o *options) {
+		if size <= 0 {
+			o.fail("expect continue size must be positive, got %d", size)
+		}
+		o.expect = size
+	}
+}
+
+// WithBackoff waits for the duration chosen by backoff between attempts.
+func WithBackoff(backoff Backoff) Option {
+	return func(o *options) {
+		if backoff == nil {
+			o.fail("backoff must not be nil")
+		}
+		o.backoffs = append(o.backoffs, backoff)
+	}
+}
+
+// WithConstantBackoff waits for delta between attempts.
+func WithConstantBackoff(delta time.Duration) Option {
+	return func(o *options) {
+		if delta < 0 {
+			o.fail("constant backoff must not be negative, got %s", delta)
+		}
+		WithBackoff(ConstantBackoff(delta))(o)
+	}
+}
+
+// WithExponentialBackoff waits exponentially longer from base between
+// attempts.
+func WithExponentialBackoff(base time.Duration) Option {
+	return func(o *options) {
+		if base <= 0 {
+			o.fail("exponential backoff base must be positive, got %s", base)
+		}
+		WithBackoff(ExponentialBackoff(base))(o)
+	}
+}
+
+// WithJitter spreads the waits of the backoff by up to fraction of them in
+// either direction, drawing from src.  A nil src uses random.Default.
+func WithJitter(fraction float64, src random.Source) Option {
+	return func(o *options) {
+		if fraction <= 0 || fraction > 1 {
+			o.fail("jitter must be within (0, 1], got %v", fraction)
+		}
+		o.jitter = fraction
+		o.rand = src
+	}
+}
+
+// WithRetryer replaces the built-in policy with retryer.  It cannot be
+// combined with the options shaping the built-in policy.
+func WithRetryer(retryer Retryer) Option {
+	return func(o *options) {
+		if retryer == nil {
+			o.fail("retryer must not be nil")
+		}
+		o.retryer = retryer
+	}
+}
+
+// WithLogger logs the progress of retried requests to logger.
+func WithLogger(logger Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithNext issues the attempts through next instead of http.DefaultTransport.
+func WithNext(next http.RoundTripper) Option {
+	return func(o *options) {
+		if next == nil {
+			o.fail("next round tripper must not be nil")
+		}
+		o.next = next
+	}
+}
+
+// WithDialContext issues the attempts through a copy of
+// http.DefaultTransport dialing connections with dial, which may learn the
+// attempt from its context with AttemptFrom.  It cannot be combined with
+// WithNext.
+func WithDialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) Option {
+	return func(o *options) {
+		if dial == nil {
+			o.fail("dial context must not be nil")
+		}
+		o.dial = dial
+	}
+}
+
+// New constructs a Transport from opts.  Without options the Transport
+// follows the DefaultPolicy with an exponential backoff from
+// DefaultBackoffBase.  The Transport collects Statistics.
+//
+// New panics when the options are invalid or contradict each other.
+func New(opts ...Option) *Transport {
+	o := options{
+		maxAttempts: DefaultMaxAttempts,
+		timeout:     DefaultTimeout,
+		statuses:    DefaultRetryStatuses,
+		next:        http.DefaultTransport,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.retryer != nil && o.policy {
+		o.fail("WithRetryer cannot be combined with WithMaxAttempts, WithTimeout or WithRetryStatuses")
+	}
+
+	if o.dial != nil && o.next != http.DefaultTransport {
+		o.fail("WithDialContext cannot be combined with WithNext")
+	}
+
+	if len(o.backoffs) > 1 {
+		o.fail("only one backoff option may be given, got %d", len(o.backoffs))
+	}
+
+	if len(o.errs) > 0 {
+		panic(fmt.Sprintf("retry: invalid options: %v", o.errs))
+	}
+
+	retryer := o.retryer
+	if retryer == nil {
+		retryer = DefaultPolicy{
+			MaxAttempts: o.maxAttempts,
+			Timeout:     o.timeout,
+			Statuses:    o.statuses,
+		}.Retryer()
+	}
+
+	if o.dial != nil {
+		next := http.DefaultTransport.(*http.Transport).Clone()
+		next.DialContext = o.dial
+		o.next = next
+	}
+
+	backoff := ExponentialBackoff(DefaultBackoffBase)
+	if len(o.backoffs) == 1 {
+		backoff = o.backoffs[0]
+	}
+	if o.jitter > 0 {
+		backoff = Jitter(backoff, o.jitter, o.rand)
+	}
+
+	t := &Transport{
+		Delay:          Sleep(backoff),
+		Retry:          retryer,
+		Next:           o.next,
+		Logger:         o.logger,
+		Statistics:     NewStatistics(),
+		AttemptTimeout: o.attempt,
+		ExpectContinue: o.expect,
+	}
+	if err := t.Validate(); err != nil {
+		panic(err.Error())
+	}
+	return t
+}
+
+// NewClient constructs an http.Client using a Transport from New.
+func NewClient(opts ...Option) *http.Client {
+	return &http.Client{Transport: New(opts...)}
+}
+
+// PerAttemptTimeout returns a copy of client applying its Timeout to each
+// attempt of its retry Transport instead of to the whole call, which would
+// otherwise include all retries and delays.  Clients without a Timeout or
+// without a retry Transport are returned unchanged.
+func PerAttemptTimeout(client *http.Client) *http.Client {
+	if client.Timeout <= 0 {
+		return client
+	}
+
+	var trans Transport
+	switch t := client.Transport.(type) {
+	case *Transport:
+		trans = *t
+	case Transport:
+		trans = t
+	default:
+		return client
+	}
+
+	trans.AttemptTimeout = client.Timeout
+
+	c := *client
+	c.Timeout = 0
+	c.Transport = &trans
+	return &c
+}
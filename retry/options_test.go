@@ -0,0 +1,119 @@
+package retry
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNewRetriesDefaultStatuses(t *testing.T) {
+	var (
+		req, _ = http.NewRequest("GET", "http://example/test", nil)
+		next   = &testRoundTrip{resp: &http.Response{StatusCode: 503, Body: http.NoBody}}
+		trans  = New(WithNext(next), WithConstantBackoff(0))
+	)
+
+	_, err := trans.RoundTrip(req)
+
+	if _, isMax := err.(MaxError); !isMax {
+		t.Fatalf("expected MaxError after exhausting attempts, got: %v", err)
+	}
+	if want, got := DefaultMaxAttempts, next.count; want != got {
+		t.Fatalf("expected to make %d attempts, got %d", want, got)
+	}
+}
+
+func TestNewDoesNotRetryNonIdempotent(t *testing.T) {
+	var (
+		req, _ = http.NewRequest("POST", "http://example/test", nil)
+		next   = &testRoundTrip{err: fmt.Errorf("next")}
+		trans  = New(WithNext(next), WithConstantBackoff(0))
+	)
+
+	trans.RoundTrip(req)
+
+	if want, got := 1, next.count; want != got {
+		t.Fatalf("expected to make %d attempts, got %d", want, got)
+	}
+}
+
+func TestNewWithMaxAttemptsAndStatuses(t *testing.T) {
+	var (
+		req, _ = http.NewRequest("GET", "http://example/test", nil)
+		next   = &testRoundTrip{resp: &http.Response{StatusCode: 429, Body: http.NoBody}}
+		trans  = New(
+			WithNext(next),
+			WithMaxAttempts(5),
+			WithRetryStatuses(429),
+			WithConstantBackoff(0),
+		)
+	)
+
+	trans.RoundTrip(req)
+
+	if want, got := 5, next.count; want != got {
+		t.Fatalf("expected to make %d attempts, got %d", want, got)
+	}
+}
+
+func TestNewPanicsOnInvalidOptions(t *testing.T) {
+	for name, opts := range map[string][]Option{
+		"zero attempts":      {WithMaxAttempts(0)},
+		"invalid status":     {WithRetryStatuses(42)},
+		"two backoffs":       {WithConstantBackoff(time.Second), WithExponentialBackoff(time.Second)},
+		"retryer and policy": {WithRetryer(Errors()), WithMaxAttempts(2)},
+		"nil next":           {WithNext(nil)},
+		"nil dial":           {WithDialContext(nil)},
+		"excessive jitter":   {WithJitter(2, nil)},
+		"dial and next":      {WithDialContext((&net.Dialer{}).DialContext), WithNext(&http.Transport{})},
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("expected New to panic")
+				}
+			}()
+			New(opts...)
+		})
+	}
+}
+
+func TestNewClientUsesTransport(t *testing.T) {
+	c := NewClient(WithLogger(nil))
+	if _, ok := c.Transport.(*Transport); !ok {
+		t.Fatalf("expected client to use a retry Transport, got: %T", c.Transport)
+	}
+}
+
+func TestPerAttemptTimeout(t *testing.T) {
+	var attempts int
+	next := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		if attempts < 3 {
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		}
+		return &http.Response{StatusCode: 200, Body: http.NoBody}, nil
+	})
+
+	client := PerAttemptTimeout(&http.Client{
+		Timeout:   20 * time.Millisecond,
+		Transport: New(WithNext(next), WithConstantBackoff(0)),
+	})
+
+	if client.Timeout != 0 {
+		t.Fatalf("expected the client timeout to move to the transport, got %s", client.Timeout)
+	}
+
+	resp, err := client.Get("http://example/test")
+	if err != nil {
+		t.Fatalf("expected timed out attempts to be retried, got: %v", err)
+	}
+	resp.Body.Close()
+
+	if want, got := 3, attempts; want != got {
+		t.Fatalf("expected to make %d attempts, got %d", want, got)
+	}
+}
@@ -0,0 +1,132 @@
+package retry
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// DefaultRetryer is used by a Transport without a Retry policy.
+var DefaultRetryer = DefaultPolicy{}.Retryer()
+
+// DefaultRetryStatuses are the response status codes retried by the
+// DefaultPolicy: 429 and all 5xx codes except 501 Not Implemented.
+var DefaultRetryStatuses = []int{
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+	http.StatusHTTPVersionNotSupported,
+	http.StatusVariantAlsoNegotiates,
+	http.StatusInsufficientStorage,
+	http.StatusLoopDetected,
+	http.StatusNotExtended,
+	http.StatusNetworkAuthenticationRequired,
+}
+
+// IdempotentMethods are the methods expected to be idempotent according to
+// RFC 2616, section 9.1.2.
+var IdempotentMethods = []string{"GET", "HEAD", "PUT", "DELETE", "OPTIONS", "TRACE"}
+
+// DefaultPolicy retries requests with one of Methods that fail with a
+// connection error, run out of their attempt timeout or respond with one
+// of Statuses, up to MaxAttempts within Timeout since the first attempt.
+// Zero fields take the package defaults, so DefaultPolicy{} is the policy
+// of the DefaultRetryer.
+type DefaultPolicy struct {
+	// MaxAttempts limits the round trips per request, DefaultMaxAttempts
+	// when zero.
+	MaxAttempts uint
+
+	// Timeout limits the time spent retrying, DefaultTimeout when zero.
+	Timeout time.Duration
+
+	// Methods are the request methods that may be retried,
+	// IdempotentMethods when nil.
+	Methods []string
+
+	// Statuses are the response status codes that are retried,
+	// DefaultRetryStatuses when nil.
+	Statuses []int
+}
+
+// Retryer returns the Retryer implementing the policy.
+func (p DefaultPolicy) Retryer() Retryer {
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = DefaultMaxAttempts
+	}
+	if p.Timeout == 0 {
+		p.Timeout = DefaultTimeout
+	}
+	if p.Methods == nil {
+		p.Methods = IdempotentMethods
+	}
+	if p.Statuses == nil {
+		p.Statuses = DefaultRetryStatuses
+	}
+
+	method := Method(p.Methods...)
+	retryable := All(Connection(), AttemptTimedOut(), Status(p.Statuses...))
+
+	return Limit(func(a Attempt) (Decision, error) {
+		if decision, _ := method(a); decision != Retry {
+			return Ignore, nil
+		}
+		return retryable(a)
+	}, Max(p.MaxAttempts), Timeout(p.Timeout))
+}
+
+// Connection retries errors establishing or keeping a connection: dial and
+// DNS failures, refused and reset connections and connections closed before
+// a response was read.
+func Connection() Retryer {
+	return func(a Attempt) (Decision, error) {
+		if isConnectionError(a.Err) {
+			return Retry, nil
+		}
+		return Ignore, nil
+	}
+}
+
+// AttemptTimedOut retries attempts that failed with an AttemptTimeoutError.
+func AttemptTimedOut() Retryer {
+	return func(a Attempt) (Decision, error) {
+		if a.Err == nil {
+			return Ignore, nil
+		}
+		var timeout *AttemptTimeoutError
+		if errors.As(a.Err, &timeout) {
+			return Retry, nil
+		}
+		return Ignore, nil
+	}
+}
+
+func isConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var (
+		dnsErr *net.DNSError
+		opErr  *net.OpError
+	)
+
+	switch {
+	case errors.As(err, &dnsErr):
+		return true
+	case errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.ECONNABORTED),
+		errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF):
+		return true
+	case errors.As(err, &opErr):
+		return opErr.Op == "dial"
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package retry
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestDefaultPolicyDecisions(t *testing.T) {
+	retryer := DefaultPolicy{}.Retryer()
+
+	for _, it := range []struct {
+		name   string
+		method string
+		status int
+		err    error
+		want   Decision
+	}{
+		{"success", "GET", 200, nil, Ignore},
+		{"too many requests", "GET", 429, nil, Retry},
+		{"internal server error", "GET", 500, nil, Retry},
+		{"not implemented", "GET", 501, nil, Ignore},
+		{"service unavailable", "PUT", 503, nil, Retry},
+		{"not idempotent", "POST", 503, nil, Ignore},
+		{"connection reset", "GET", 0, &net.OpError{Op: "read", Err: syscall.ECONNRESET}, Retry},
+		{"dns failure", "GET", 0, &net.DNSError{Err: "no such host", Name: "missing"}, Retry},
+		{"dial failure", "GET", 0, &net.OpError{Op: "dial", Err: fmt.Errorf("refused")}, Retry},
+		{"closed connection", "GET", 0, io.EOF, Retry},
+		{"other error", "GET", 0, fmt.Errorf("other"), Ignore},
+	} {
+		t.Run(it.name, func(t *testing.T) {
+			req, _ := http.NewRequest(it.method, "http://example/test", nil)
+			a := Attempt{Start: time.Now(), Count: 1, Request: req, Err: it.err}
+			if it.status != 0 {
+				a.Response = &http.Response{StatusCode: it.status}
+			}
+
+			if got, _ := retryer(a); it.want != got {
+				t.Fatalf("expected to %v, got: %v", it.want, got)
+			}
+		})
+	}
+}
+
+func TestDefaultPolicyCustomized(t *testing.T) {
+	retryer := DefaultPolicy{MaxAttempts: 2, Statuses: []int{418}}.Retryer()
+	req, _ := http.NewRequest("GET", "http://example/test", nil)
+
+	if got, _ := retryer(Attempt{Start: time.Now(), Count: 1, Request: req, Response: &http.Response{StatusCode: 503}}); got != Ignore {
+		t.Fatalf("expected to ignore statuses outside the list, got: %v", got)
+	}
+
+	if got, _ := retryer(Attempt{Start: time.Now(), Count: 1, Request: req, Response: &http.Response{StatusCode: 418}}); got != Retry {
+		t.Fatalf("expected to retry listed statuses, got: %v", got)
+	}
+
+	decision, err := retryer(Attempt{Start: time.Now(), Count: 2, Request: req, Response: &http.Response{StatusCode: 418}})
+	if decision != Abort {
+		t.Fatalf("expected to abort after MaxAttempts, got: %v", decision)
+	}
+	if _, isMax := err.(MaxError); !isMax {
+		t.Fatalf("expected error to be of type MaxError, got: %v", err)
+	}
+}
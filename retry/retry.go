@@ -2,10 +2,17 @@
 package retry
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"time"
+
+	"github.com/streadway/handy/failure"
+	"github.com/streadway/handy/semconv"
 )
 
 var (
@@ -51,14 +58,103 @@ type Transport struct {
 	// Delay is called for attempts that are retried.  If nil, no delay will be used.
 	Delay Delayer
 
-	// Retry is called for every attempt
+	// Retry is called for every attempt.  If nil, the DefaultRetryer is used.
 	Retry Retryer
 
-	// Next is called for every attempt
+	// Next is called for every attempt.  RoundTrip fails with ErrNoNext when
+	// Next is nil.
 	Next http.RoundTripper
 
 	// Customer logger instance.
 	Logger Logger
+
+	// Fallback is called with the last attempt when Retry aborts, to provide
+	// a substitute response such as a cached or default value.  When nil, the
+	// last response and the abort error are returned.  A Fallback that does
+	// not return the attempt's response is responsible for closing its body.
+	Fallback func(Attempt) (*http.Response, error)
+
+	// ReturnLastResponse guarantees the response of the last attempt is
+	// returned un-drained when Retry aborts with an error.  The error is then
+	// a *ResponseError holding the same response, so that it stays reachable
+	// through an http.Client, which discards responses returned with an
+	// error.  The caller must close the response body.
+	ReturnLastResponse bool
+
+	// Statistics collects the live retry statistics returned by Stats.  If
+	// nil, no statistics are collected.
+	Statistics *Statistics
+
+	// AttemptTimeout limits the time of each attempt, until its response
+	// body is closed.  Attempts running out of time fail with an
+	// AttemptTimeoutError.  If zero, attempts are only limited by the
+	// context of the request.
+	AttemptTimeout time.Duration
+
+	// ExpectContinue is the body size from which retried attempts ask for
+	// Expect: 100-continue, so that bodies are not resent to servers
+	// rejecting them.  Bodies of unknown size count as large.  If zero,
+	// bodies are resent without asking.  The http.Transport in Next waits
+	// for the interim response up to its ExpectContinueTimeout.
+	ExpectContinue int64
+}
+
+// AttemptTimeoutError is the error of an attempt that ran out of its
+// AttemptTimeout while the request context was still live.
+type AttemptTimeoutError struct {
+	Limit time.Duration
+	Err   error
+}
+
+func (e *AttemptTimeoutError) Error() string {
+	return fmt.Sprintf("attempt timed out after %s: %s", e.Limit, e.Err)
+}
+
+// Unwrap returns the error of the attempt.
+func (e *AttemptTimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// Is matches failure.ErrConnectTimeout when the attempt timed out dialing,
+// and failure.ErrTTFBTimeout otherwise, as the response had not arrived.
+func (e *AttemptTimeoutError) Is(target error) bool {
+	var op *net.OpError
+	dialing := errors.As(e.Err, &op) && op.Op == "dial"
+	return (dialing && target == failure.ErrConnectTimeout) || (!dialing && target == failure.ErrTTFBTimeout)
+}
+
+// Temporary reports the error as temporary, as the next attempt gets a new
+// AttemptTimeout.
+func (e *AttemptTimeoutError) Temporary() bool {
+	return true
+}
+
+// cancelBody releases the context of an attempt once its body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// ResponseError carries the response of the last attempt alongside the error
+// Retry aborted with.
+type ResponseError struct {
+	Err      error
+	Response *http.Response
+}
+
+func (e *ResponseError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error Retry aborted with.
+func (e *ResponseError) Unwrap() error {
+	return e.Err
 }
 
 // RoundTrip delegates a RoundTrip, then determines via Retry whether to retry
@@ -67,21 +163,68 @@ func (t Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 	var (
 		retryer = t.Retry
 		start   = now()
+		counter = CounterFrom(req.Context())
+		trace   = TraceFrom(req.Context())
 	)
 	if retryer == nil {
 		retryer = DefaultRetryer
 	}
+	if t.Next == nil {
+		return nil, ErrNoNext
+	}
 
 	for count := uint(1); ; count++ {
+		begin := start
+
 		if count > 1 {
-			t.logf("[DEBUG] retrying %s %v, attempt: %d", req.Method, req.URL, count)
+			t.logf("[DEBUG] retrying %s, attempt: %d", redactedRequest{req}, count)
+
+			// Expose the attempt number to the transports in Next, which
+			// is left to the caller on the first attempt to save allocations
+			if counter == nil {
+				counter = &Counter{}
+				req = req.WithContext(WithCounter(req.Context(), counter))
+			}
+
+			// Rewind the body consumed by the previous attempt
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				rewound := *req
+				rewound.Body = body
+				req = &rewound
+
+				// Let servers reject the request before the body is resent
+				if t.expectContinue(req) {
+					req.Header = req.Header.Clone()
+					req.Header.Set("Expect", "100-continue")
+				}
+			}
+
+			begin = now()
+		}
+
+		if counter != nil {
+			counter.Set(count)
 		}
 
 		// Perform request
-		resp, err := t.Next.RoundTrip(req)
+		resp, err := t.attempt(req)
+
+		// The connection of a protocol switch belongs to the caller
+		if err == nil && resp.StatusCode == http.StatusSwitchingProtocols {
+			return resp, nil
+		}
+
+		var latency time.Duration
+		if t.Statistics != nil || trace != nil {
+			latency = now().Sub(begin)
+		}
 
 		if err != nil {
-			t.logf("[INFO] %s %v, request error: %s", req.Method, req.URL, err)
+			t.logf("[INFO] %s, request error: %s", redactedRequest{req}, redactedError{err})
 		}
 
 		// Collect result of attempt
@@ -95,9 +238,14 @@ func (t Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 
 		// Evaluate attempt
 		retry, retryErr := retryer(attempt)
+		t.Statistics.attempt(attempt, latency, retry)
+
+		if trace != nil {
+			trace.record(step(attempt, begin, latency, retry, retryErr))
+		}
 
 		if retryErr != nil {
-			t.logf("[INFO] %s %v, retryer error: %s", req.Method, req.URL, retryErr)
+			t.logf("[INFO] %s, retryer error: %s", redactedRequest{req}, retryErr)
 		}
 
 		// Returns either the valid response or an error coming from the underlying Transport
@@ -108,9 +256,16 @@ func (t Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 		// Return the error explaining why we aborted and nil as response
 		if retry == Abort {
 			if retryErr != nil {
-			    t.logf("[ERROR] aborting request %s %v, error: %s", req.Method, req.URL, retryErr)
+				t.logf("[ERROR] aborting %s, error: %s", attempt, retryErr)
 			} else {
-				t.logf("[ERROR] aborting request %s %v", req.Method, req.URL)
+				t.logf("[ERROR] aborting %s", attempt)
+			}
+			if t.Fallback != nil {
+				t.logf("[INFO] falling back for request %s", redactedRequest{req})
+				return t.Fallback(attempt)
+			}
+			if t.ReturnLastResponse && resp != nil && retryErr != nil {
+				return resp, &ResponseError{Err: retryErr, Response: resp}
 			}
 			return resp, retryErr
 		}
@@ -120,7 +275,7 @@ func (t Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 		if resp != nil {
 			_, err := io.Copy(ioutil.Discard, io.LimitReader(resp.Body, bodyReadLimit))
 			if err != nil {
-				t.logf("[ERROR] error reading response body: %s", req.Method, req.URL, retryErr)
+				t.logf("[ERROR] %s, error reading response body: %s", redactedRequest{req}, err)
 			}
 
 			resp.Body.Close()
@@ -130,14 +285,82 @@ func (t Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 
 		// Delay next attempt
 		if t.Delay != nil {
-			t.logf("[DEBUG] delaying before retry %s %v", req.Method, req.URL)
+			t.logf("[DEBUG] delaying before retry %s", redactedRequest{req})
+
+			if trace != nil {
+				before := now()
+				t.Delay(attempt)
+				trace.delayed(now().Sub(before))
+			} else {
+				t.Delay(attempt)
+			}
 
-			t.Delay(attempt)
+			// Stop when the request ended during the delay
+			if err := req.Context().Err(); err != nil {
+				t.logf("[INFO] %s, delay interrupted: %s", redactedRequest{req}, err)
+				return nil, failure.Interrupted(err)
+			}
 		}
 	}
 	panic("unreachable")
 }
 
+// expectContinue reports whether the body of req is large enough to ask
+// for Expect: 100-continue.
+func (t Transport) expectContinue(req *http.Request) bool {
+	if t.ExpectContinue <= 0 || req.Body == nil || req.Body == http.NoBody {
+		return false
+	}
+	return req.ContentLength < 0 || req.ContentLength >= t.ExpectContinue
+}
+
+// step describes an attempt for a Trace.
+func step(a Attempt, begin time.Time, latency time.Duration, decision Decision, reason error) Step {
+	s := Step{
+		Attempt:  a.Count,
+		Start:    begin,
+		Latency:  latency,
+		Decision: decision,
+	}
+	if a.Err != nil {
+		s.Error = a.Err.Error()
+		s.ErrorType = semconv.ErrorTypeOf(a.Err)
+	} else if a.Response != nil {
+		s.Status = a.Response.StatusCode
+	}
+	if reason != nil {
+		s.Reason = reason.Error()
+	}
+	if deadline, ok := a.Request.Context().Deadline(); ok {
+		s.Remaining = deadline.Sub(now())
+	}
+	return s
+}
+
+// attempt issues one attempt through Next, within the AttemptTimeout.
+func (t Transport) attempt(req *http.Request) (*http.Response, error) {
+	if t.AttemptTimeout <= 0 {
+		return t.Next.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.AttemptTimeout)
+	resp, err := t.Next.RoundTrip(req.WithContext(ctx))
+
+	if err != nil && ctx.Err() == context.DeadlineExceeded && req.Context().Err() == nil {
+		err = &AttemptTimeoutError{Limit: t.AttemptTimeout, Err: err}
+	}
+
+	// The connection of a protocol switch is released from the context, and
+	// its body must stay writable
+	if resp != nil && resp.Body != nil && resp.StatusCode != http.StatusSwitchingProtocols {
+		resp.Body = &cancelBody{resp.Body, cancel}
+	} else {
+		cancel()
+	}
+
+	return resp, err
+}
+
 func (t Transport) logf(format string, v ...interface{}) {
 	if t.Logger != nil {
 		t.Logger.Printf(format, v...)
@@ -1,10 +1,19 @@
 package retry
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/streadway/handy/failure"
 )
 
 type testRoundTrip struct {
@@ -18,6 +27,12 @@ func (rt *testRoundTrip) RoundTrip(*http.Request) (*http.Response, error) {
 	return rt.resp, rt.err
 }
 
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
 // this actually tests the internal counter of the retry loop
 func TestRetryAfterCount(t *testing.T) {
 	const attempts = 2
@@ -128,3 +143,399 @@ func TestIdempotent(t *testing.T) {
 		})
 	}
 }
+
+func TestFallbackOnAbort(t *testing.T) {
+	const attempts = 2
+
+	var (
+		req, _   = http.NewRequest("GET", "http://example/test", nil)
+		next     = &testRoundTrip{err: fmt.Errorf("next")}
+		fallback = &http.Response{StatusCode: 203}
+		called   Attempt
+		trans    = Transport{
+			Retry: All(Errors(), Max(attempts)),
+			Next:  next,
+			Fallback: func(a Attempt) (*http.Response, error) {
+				called = a
+				return fallback, nil
+			},
+		}
+	)
+
+	resp, err := trans.RoundTrip(req)
+
+	if err != nil {
+		t.Fatalf("expected fallback to replace the error, got: %v", err)
+	}
+	if resp != fallback {
+		t.Fatalf("expected the fallback response, got: %v", resp)
+	}
+	if want, got := uint(attempts), called.Count; want != got {
+		t.Fatalf("expected fallback to receive attempt %d, got %d", want, got)
+	}
+}
+
+func TestFallbackNotCalledOnSuccess(t *testing.T) {
+	var (
+		req, _ = http.NewRequest("GET", "http://example/test", nil)
+		next   = &testRoundTrip{resp: &http.Response{StatusCode: 200}}
+		trans  = Transport{
+			Retry: All(Errors(), Max(2)),
+			Next:  next,
+			Fallback: func(a Attempt) (*http.Response, error) {
+				t.Fatalf("expected fallback not to be called on success")
+				return nil, nil
+			},
+		}
+	)
+
+	if _, err := trans.RoundTrip(req); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+}
+
+func TestReturnLastResponseOnExhaustion(t *testing.T) {
+	const attempts = 3
+
+	var (
+		req, _ = http.NewRequest("GET", "http://example/test", nil)
+		last   *http.Response
+		count  int
+		trans  = Transport{
+			Retry: Limit(Over(500), Max(attempts)),
+			Next: roundTripFunc(func(*http.Request) (*http.Response, error) {
+				count++
+				last = &http.Response{
+					StatusCode: 503,
+					Body:       ioutil.NopCloser(strings.NewReader(fmt.Sprint("attempt ", count))),
+				}
+				return last, nil
+			}),
+			ReturnLastResponse: true,
+		}
+	)
+
+	resp, err := trans.RoundTrip(req)
+
+	respErr, ok := err.(*ResponseError)
+	if !ok {
+		t.Fatalf("expected a *ResponseError, got: %v", err)
+	}
+	if _, isMax := respErr.Err.(MaxError); !isMax {
+		t.Fatalf("expected to wrap a MaxError, got: %v", respErr.Err)
+	}
+	if resp != last || respErr.Response != last {
+		t.Fatalf("expected the last response to be returned")
+	}
+	if body, _ := ioutil.ReadAll(resp.Body); string(body) != "attempt 3" {
+		t.Fatalf("expected the last response body to be intact, got: %q", body)
+	}
+}
+
+func TestCounterRecordsAttempts(t *testing.T) {
+	const attempts = 3
+
+	var (
+		counter Counter
+		req, _  = http.NewRequest("GET", "http://example/test", nil)
+		next    = &testRoundTrip{err: fmt.Errorf("next")}
+		trans   = Transport{
+			Retry: Limit(Errors(), Max(attempts)),
+			Next:  next,
+		}
+	)
+
+	trans.RoundTrip(req.WithContext(WithCounter(req.Context(), &counter)))
+
+	if want, got := uint(attempts), counter.Attempts(); want != got {
+		t.Fatalf("expected counter to record %d attempts, got %d", want, got)
+	}
+}
+
+func TestCounterExposedToNext(t *testing.T) {
+	var (
+		seen   []uint
+		req, _ = http.NewRequest("GET", "http://example/test", nil)
+		trans  = Transport{
+			Retry: Limit(Errors(), Max(3)),
+			Next: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+				seen = append(seen, AttemptFrom(r.Context()))
+				return nil, fmt.Errorf("next")
+			}),
+		}
+	)
+
+	trans.RoundTrip(req)
+
+	if want, got := []uint{1, 2, 3}, seen; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expected Next to see attempts %v, got %v", want, got)
+	}
+}
+
+func TestRetryRewindsBody(t *testing.T) {
+	var (
+		bodies []string
+		req, _ = http.NewRequest("PUT", "http://example/test", strings.NewReader("payload"))
+		trans  = Transport{
+			Retry: Limit(Errors(), Max(2)),
+			Next: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+				b, _ := ioutil.ReadAll(r.Body)
+				bodies = append(bodies, string(b))
+				return nil, fmt.Errorf("next")
+			}),
+		}
+	)
+
+	trans.RoundTrip(req)
+
+	if want, got := []string{"payload", "payload"}, bodies; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expected every attempt to send the body %v, got %v", want, got)
+	}
+}
+
+func TestAttemptTimeoutLastsUntilBodyClose(t *testing.T) {
+	var ctx context.Context
+	trans := Transport{
+		AttemptTimeout: time.Minute,
+		Next: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			ctx = req.Context()
+			return &http.Response{StatusCode: 200, Body: http.NoBody}, nil
+		}),
+	}
+
+	req, _ := http.NewRequest("GET", "http://example/test", nil)
+	resp, err := trans.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ctx.Err() != nil {
+		t.Fatalf("expected the attempt to stay live while the body is read")
+	}
+
+	resp.Body.Close()
+
+	if ctx.Err() != context.Canceled {
+		t.Fatalf("expected closing the body to release the attempt, got: %v", ctx.Err())
+	}
+}
+
+func TestAttemptTimeoutError(t *testing.T) {
+	trans := Transport{
+		AttemptTimeout: time.Millisecond,
+		Next: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		}),
+	}
+
+	req, _ := http.NewRequest("GET", "http://example/test", nil)
+	trans.Retry = func(a Attempt) (Decision, error) {
+		var timeout *AttemptTimeoutError
+		if !errors.As(a.Err, &timeout) || timeout.Limit != time.Millisecond {
+			t.Fatalf("expected an AttemptTimeoutError, got: %v", a.Err)
+		}
+		if !errors.Is(a.Err, context.DeadlineExceeded) {
+			t.Fatalf("expected to unwrap the attempt error, got: %v", a.Err)
+		}
+		return Ignore, nil
+	}
+
+	trans.RoundTrip(req)
+}
+
+// staticRoundTrip responds with the same response without allocating.
+type staticRoundTrip struct{ resp *http.Response }
+
+func (rt staticRoundTrip) RoundTrip(*http.Request) (*http.Response, error) {
+	return rt.resp, nil
+}
+
+func TestRoundTripFirstAttemptDoesNotAllocate(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://example/test", nil)
+	next := staticRoundTrip{&http.Response{StatusCode: 200, Body: http.NoBody}}
+
+	for name, trans := range map[string]Transport{
+		"default":    {Next: next},
+		"statistics": {Next: next, Statistics: NewStatistics(), Delay: Constant(0)},
+		"new":        *New(WithNext(next)),
+	} {
+		if allocs := testing.AllocsPerRun(100, func() { trans.RoundTrip(req) }); allocs != 0 {
+			t.Errorf("%s: expected no allocations for a successful first attempt, got %v", name, allocs)
+		}
+	}
+}
+
+func BenchmarkRoundTripFirstAttempt(b *testing.B) {
+	req, _ := http.NewRequest("GET", "http://example/test", nil)
+	trans := New(WithNext(staticRoundTrip{&http.Response{StatusCode: 200, Body: http.NoBody}}))
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		trans.RoundTrip(req)
+	}
+}
+
+func BenchmarkRoundTripRetried(b *testing.B) {
+	req, _ := http.NewRequest("GET", "http://example/test", nil)
+	trans := New(
+		WithNext(staticRoundTrip{&http.Response{StatusCode: 503, Body: http.NoBody}}),
+		WithConstantBackoff(0),
+	)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		trans.RoundTrip(req)
+	}
+}
+
+func TestDelayInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "GET", "http://example/test", nil)
+
+	var count int
+	trans := Transport{
+		Retry: Limit(Errors(), Max(3)),
+		Delay: Sleep(func(Attempt) time.Duration {
+			cancel()
+			return time.Minute
+		}),
+		Next: roundTripFunc(func(*http.Request) (*http.Response, error) {
+			count++
+			return nil, fmt.Errorf("next")
+		}),
+	}
+
+	_, err := trans.RoundTrip(req)
+	if !errors.Is(err, failure.ErrDelayInterrupted) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected an interrupted delay, got: %v", err)
+	}
+	if want, got := 1, count; want != got {
+		t.Fatalf("expected %d attempt, got %d", want, got)
+	}
+}
+
+// readsBody records whether the body of an attempt was read.
+type readsBody struct {
+	io.Reader
+	read *bool
+}
+
+func (b readsBody) Read(p []byte) (int, error) {
+	*b.read = true
+	return b.Reader.Read(p)
+}
+
+func (b readsBody) Close() error { return nil }
+
+func TestExpectContinueOnRetriedUploads(t *testing.T) {
+	var expects []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		expects = append(expects, r.Header.Get("Expect"))
+		if len(expects) == 1 {
+			ioutil.ReadAll(r.Body)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+	}))
+	defer s.Close()
+
+	var reads []bool
+	req, _ := http.NewRequest("PUT", s.URL, strings.NewReader(strings.Repeat("x", 1024)))
+	req.GetBody = func() (io.ReadCloser, error) {
+		reads = append(reads, false)
+		return readsBody{strings.NewReader(strings.Repeat("x", 1024)), &reads[len(reads)-1]}, nil
+	}
+
+	trans := Transport{
+		Retry:          Limit(Over(500), Max(2)),
+		Next:           http.DefaultTransport,
+		ExpectContinue: 1024,
+	}
+
+	resp, err := trans.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if want, got := []string{"", "100-continue"}, expects; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expected Expect headers %q, got %q", want, got)
+	}
+	if want, got := []bool{false}, reads; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expected the rejected body not to be resent, got reads %v", got)
+	}
+	if req.Header.Get("Expect") != "" {
+		t.Fatalf("expected the caller's request to stay unmodified")
+	}
+}
+
+func TestSwitchingProtocolsKeepsWritableBody(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+		rw.Flush()
+		io.Copy(conn, rw)
+	}))
+	defer s.Close()
+
+	trans := Transport{
+		Retry:          DefaultRetryer,
+		AttemptTimeout: 10 * time.Millisecond,
+		Next:           http.DefaultTransport,
+	}
+
+	req, _ := http.NewRequest("GET", s.URL, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "echo")
+
+	resp, err := trans.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	conn, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		t.Fatalf("expected a writable body, got %T", resp.Body)
+	}
+
+	// Outlive the AttemptTimeout
+	time.Sleep(20 * time.Millisecond)
+
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatal(err)
+	}
+	if want, got := "ping", string(buf); want != got {
+		t.Fatalf("expected the echo %q, got %q", want, got)
+	}
+}
+
+func TestSwitchingProtocolsIsNotRetried(t *testing.T) {
+	var (
+		req, _ = http.NewRequest("GET", "http://example/test", nil)
+		next   = &testRoundTrip{resp: &http.Response{StatusCode: http.StatusSwitchingProtocols}}
+		trans  = Transport{
+			Retry: func(Attempt) (Decision, error) { return Retry, nil },
+			Next:  next,
+		}
+	)
+
+	resp, err := trans.RoundTrip(req)
+	if err != nil || resp != next.resp {
+		t.Fatalf("expected the protocol switch to be returned, got: %v", err)
+	}
+	if want, got := 1, next.count; want != got {
+		t.Fatalf("expected %d attempt, got %d", want, got)
+	}
+}
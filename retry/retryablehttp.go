@@ -0,0 +1,93 @@
+package retry
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// CheckRetry has the signature of the CheckRetry policies of
+// github.com/hashicorp/go-retryablehttp, which convert to and from it
+// without importing the library.
+type CheckRetry func(ctx context.Context, resp *http.Response, err error) (bool, error)
+
+// BackoffFunc has the signature of the Backoff policies of
+// github.com/hashicorp/go-retryablehttp.  The attemptNum starts from 0 for
+// the wait after the first attempt.
+type BackoffFunc func(min, max time.Duration, attemptNum int, resp *http.Response) time.Duration
+
+// FromCheckRetry adapts a go-retryablehttp CheckRetry to a Retryer.  Retried
+// attempts Retry, attempts not retried with an error Abort with it and all
+// others are Ignored.  The check has no attempt limit of its own, so combine
+// it with Max like:
+//
+//	Limit(FromCheckRetry(retryablehttp.DefaultRetryPolicy), Max(4))
+func FromCheckRetry(check CheckRetry) Retryer {
+	return func(a Attempt) (Decision, error) {
+		ctx := context.Background()
+		if a.Request != nil {
+			ctx = a.Request.Context()
+		}
+
+		retry, err := check(ctx, a.Response, a.Err)
+		switch {
+		case retry:
+			return Retry, nil
+		case err != nil:
+			return Abort, err
+		}
+		return Ignore, nil
+	}
+}
+
+// ToCheckRetry adapts a Retryer to a go-retryablehttp CheckRetry.  The
+// attempt count and start are not known to go-retryablehttp, so the
+// Retryer sees attempts without a Count starting now; limit the attempts
+// with the RetryMax of the retryablehttp.Client instead.  The Request is
+// only known from the response, so method checks like those of the
+// DefaultRetryer do not retry connection errors.
+func ToCheckRetry(retryer Retryer) CheckRetry {
+	return func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		a := Attempt{Start: now(), Err: err, Response: resp}
+		if resp != nil {
+			a.Request = resp.Request
+		}
+
+		decision, retryErr := retryer(a)
+		switch decision {
+		case Retry:
+			return true, nil
+		case Abort:
+			return false, retryErr
+		}
+		return false, nil
+	}
+}
+
+// FromBackoff adapts a go-retryablehttp Backoff to a Backoff, waiting
+// between min and max like a retryablehttp.Client with RetryWaitMin and
+// RetryWaitMax.  Use it with Sleep for a Delayer.
+func FromBackoff(backoff BackoffFunc, min, max time.Duration) Backoff {
+	return func(a Attempt) time.Duration {
+		n := 0
+		if a.Count > 0 {
+			n = int(a.Count) - 1
+		}
+		return backoff(min, max, n, a.Response)
+	}
+}
+
+// ToBackoff adapts a Backoff to a go-retryablehttp Backoff, limiting its
+// waits to between min and max.
+func ToBackoff(backoff Backoff) BackoffFunc {
+	return func(min, max time.Duration, attemptNum int, resp *http.Response) time.Duration {
+		wait := backoff(Attempt{Count: uint(attemptNum) + 1, Response: resp})
+		if wait < min {
+			return min
+		}
+		if wait > max {
+			return max
+		}
+		return wait
+	}
+}
@@ -0,0 +1,105 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// retryablePolicy mimics retryablehttp.DefaultRetryPolicy for 5xx and errors.
+func retryablePolicy(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+	if err != nil {
+		return true, err
+	}
+	return resp.StatusCode >= 500, nil
+}
+
+func TestFromCheckRetry(t *testing.T) {
+	var (
+		req, _ = http.NewRequest("GET", "http://example/test", nil)
+		next   = &testRoundTrip{resp: &http.Response{StatusCode: 503, Body: http.NoBody}}
+		trans  = Transport{
+			Retry: Limit(FromCheckRetry(retryablePolicy), Max(4)),
+			Next:  next,
+		}
+	)
+
+	_, err := trans.RoundTrip(req)
+
+	if _, isMax := err.(MaxError); !isMax {
+		t.Fatalf("expected MaxError, got: %v", err)
+	}
+	if want, got := 4, next.count; want != got {
+		t.Fatalf("expected to make %d attempts, got %d", want, got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	decision, err := FromCheckRetry(retryablePolicy)(Attempt{Request: req.WithContext(ctx)})
+	if decision != Abort || err != context.Canceled {
+		t.Fatalf("expected an error without retry to abort, got: %v, %v", decision, err)
+	}
+}
+
+func TestToCheckRetry(t *testing.T) {
+	check := ToCheckRetry(All(Errors(), Status(503)))
+
+	for _, test := range []struct {
+		resp  *http.Response
+		err   error
+		retry bool
+	}{
+		{nil, errors.New("reset"), true},
+		{&http.Response{StatusCode: 503}, nil, true},
+		{&http.Response{StatusCode: 200}, nil, false},
+	} {
+		if got, _ := check(context.Background(), test.resp, test.err); test.retry != got {
+			t.Errorf("%v %v: expected retry %v, got %v", test.resp, test.err, test.retry, got)
+		}
+	}
+
+	if retry, err := ToCheckRetry(Timeout(time.Minute))(context.Background(), nil, nil); retry || err != nil {
+		t.Fatalf("expected time limits not to trip on unknown starts, got: %v, %v", retry, err)
+	}
+}
+
+func TestToCheckRetryWithoutRequest(t *testing.T) {
+	check := ToCheckRetry(DefaultRetryer)
+
+	if retry, err := check(context.Background(), nil, errors.New("reset")); retry || err != nil {
+		t.Fatalf("expected errors without request not to be retried, got: %v, %v", retry, err)
+	}
+
+	req, _ := http.NewRequest("GET", "http://example/test", nil)
+	if retry, _ := check(context.Background(), &http.Response{StatusCode: 503, Request: req}, nil); !retry {
+		t.Fatalf("expected the request of the response to be checked")
+	}
+}
+
+func TestBackoffRoundTrip(t *testing.T) {
+	linear := func(min, max time.Duration, attemptNum int, resp *http.Response) time.Duration {
+		return min * time.Duration(attemptNum+1)
+	}
+
+	backoff := FromBackoff(linear, time.Second, time.Minute)
+	if want, got := 2*time.Second, backoff(Attempt{Count: 2}); want != got {
+		t.Fatalf("expected the second attempt to wait %s, got %s", want, got)
+	}
+
+	back := ToBackoff(backoff)
+	for attemptNum, want := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second} {
+		if got := back(time.Second, time.Minute, attemptNum, nil); want != got {
+			t.Errorf("attempt %d: expected %s, got %s", attemptNum, want, got)
+		}
+	}
+
+	if want, got := 90*time.Second, ToBackoff(ConstantBackoff(time.Hour))(0, 90*time.Second, 0, nil); want != got {
+		t.Fatalf("expected waits limited to %s, got %s", want, got)
+	}
+}
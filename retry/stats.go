@@ -0,0 +1,104 @@
+package retry
+
+import (
+	"sync"
+	"time"
+)
+
+// latencyWeight is the weight of the latest attempt in the rolling average
+// attempt latency.
+const latencyWeight = 0.1
+
+// Stats is a snapshot of the counters collected by Statistics.
+type Stats struct {
+	Attempts  uint64 // round trips issued
+	Retries   uint64 // attempts followed by another attempt
+	Aborts    uint64 // requests aborted by the Retryer
+	Successes uint64 // requests completed without an error
+
+	// StatusClasses counts attempts by the class of their response status
+	// code, indexed by code/100.  Index 0 counts attempts without response.
+	StatusClasses [6]uint64
+
+	// AvgLatency is the exponentially weighted moving average of the
+	// attempt latency.
+	AvgLatency time.Duration
+}
+
+// Statistics collects Stats from a Transport, safe for concurrent use.
+type Statistics struct {
+	mu    sync.Mutex
+	stats Stats
+}
+
+// NewStatistics constructs empty Statistics.
+func NewStatistics() *Statistics {
+	return &Statistics{}
+}
+
+// Snapshot returns the current Stats.
+func (s *Statistics) Snapshot() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// Reset clears all counters.
+func (s *Statistics) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats = Stats{}
+}
+
+func (s *Statistics) attempt(a Attempt, latency time.Duration, decision Decision) {
+	if s == nil {
+		return
+	}
+
+	class := 0
+	if a.Err == nil && a.Response != nil {
+		class = a.Response.StatusCode / 100
+		if class < 0 || class >= len(s.stats.StatusClasses) {
+			class = 0
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stats.Attempts++
+	s.stats.StatusClasses[class]++
+
+	if s.stats.Attempts == 1 {
+		s.stats.AvgLatency = latency
+	} else {
+		s.stats.AvgLatency += time.Duration(latencyWeight * float64(latency-s.stats.AvgLatency))
+	}
+
+	switch decision {
+	case Retry:
+		s.stats.Retries++
+	case Abort:
+		s.stats.Aborts++
+	case Ignore:
+		if a.Err == nil {
+			s.stats.Successes++
+		}
+	}
+}
+
+// Stats returns a snapshot of the Statistics of the Transport, or empty Stats
+// when the Transport has no Statistics.
+func (t Transport) Stats() Stats {
+	if t.Statistics == nil {
+		return Stats{}
+	}
+	return t.Statistics.Snapshot()
+}
+
+// ResetStats clears the Statistics of the Transport.
+func (t Transport) ResetStats() {
+	if t.Statistics != nil {
+		t.Statistics.Reset()
+	}
+}
@@ -0,0 +1,63 @@
+package retry
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestStatsCountsAttempts(t *testing.T) {
+	var (
+		req, _ = http.NewRequest("GET", "http://example/test", nil)
+		fail   = true
+		trans  = Transport{
+			Retry: Limit(Errors(), Max(3)),
+			Next: roundTripFunc(func(*http.Request) (*http.Response, error) {
+				if fail {
+					return nil, fmt.Errorf("next")
+				}
+				return &http.Response{StatusCode: 200, Body: http.NoBody}, nil
+			}),
+			Statistics: NewStatistics(),
+		}
+	)
+
+	trans.RoundTrip(req)
+
+	fail = false
+	trans.RoundTrip(req)
+
+	stats := trans.Stats()
+
+	for name, it := range map[string]struct{ want, got uint64 }{
+		"attempts":  {4, stats.Attempts},
+		"retries":   {2, stats.Retries},
+		"aborts":    {1, stats.Aborts},
+		"successes": {1, stats.Successes},
+		"errors":    {3, stats.StatusClasses[0]},
+		"2xx":       {1, stats.StatusClasses[2]},
+	} {
+		if it.want != it.got {
+			t.Errorf("expected %d %s, got %d", it.want, name, it.got)
+		}
+	}
+
+	trans.ResetStats()
+
+	if stats := trans.Stats(); stats != (Stats{}) {
+		t.Fatalf("expected stats to be reset, got: %+v", stats)
+	}
+}
+
+func TestStatsWithoutStatistics(t *testing.T) {
+	var (
+		req, _ = http.NewRequest("GET", "http://example/test", nil)
+		trans  = Transport{Next: &testRoundTrip{resp: &http.Response{StatusCode: 200}}}
+	)
+
+	trans.RoundTrip(req)
+
+	if stats := trans.Stats(); stats != (Stats{}) {
+		t.Fatalf("expected empty stats without Statistics, got: %+v", stats)
+	}
+}
@@ -5,9 +5,9 @@ import (
 	"io"
 	"net"
 	"time"
-)
 
-var DefaultRetryer = All(Max(10), Timeout(30*time.Second), EOF(), Over(300))
+	"github.com/streadway/handy/failure"
+)
 
 // All aggregates decisions from Retryers for an attempt.  All returns Abort
 // and the error on the first Abort.  If at least one returns Retry All returns
@@ -29,6 +29,24 @@ func All(conditions ...Retryer) Retryer {
 	}
 }
 
+// Limit consults the limits only for attempts that retryer decides to
+// Retry, so that a successful final attempt is not aborted by limits like
+// Max.  Limit returns Abort and the error on the first Abort from limits.
+func Limit(retryer Retryer, limits ...Retryer) Retryer {
+	return func(a Attempt) (Decision, error) {
+		decision, err := retryer(a)
+		if decision != Retry {
+			return decision, err
+		}
+		for _, limit := range limits {
+			if decision, err := limit(a); decision == Abort {
+				return Abort, err
+			}
+		}
+		return Retry, nil
+	}
+}
+
 // "Forbidders" (return Abort or Ignore)
 
 // TimeoutError is returned from RoundTrip when the time limit has been reached.
@@ -40,6 +58,11 @@ func (e TimeoutError) Error() string {
 	return fmt.Sprintf("retry timed out after %s", e.limit)
 }
 
+// Is matches failure.ErrOverallDeadline.
+func (e TimeoutError) Is(target error) bool {
+	return target == failure.ErrOverallDeadline
+}
+
 // Timeout errors after a duration of time passes since the first attempt.
 func Timeout(limit time.Duration) Retryer {
 	return func(a Attempt) (Decision, error) {
@@ -59,6 +82,11 @@ func (e MaxError) Error() string {
 	return fmt.Sprintf("retry limit exceeded after %d attempts", e.limit)
 }
 
+// Is matches failure.ErrBudgetExhausted.
+func (e MaxError) Is(target error) bool {
+	return target == failure.ErrBudgetExhausted
+}
+
 // Max errors after a limited number of attempts
 func Max(limit uint) Retryer {
 	return func(a Attempt) (Decision, error) {
@@ -69,6 +97,50 @@ func Max(limit uint) Retryer {
 	}
 }
 
+// DeadlineError is returned from RoundTrip when the next attempt cannot
+// complete before the deadline of the request context.
+type DeadlineError struct {
+	remaining time.Duration
+	needed    time.Duration
+}
+
+func (e DeadlineError) Error() string {
+	return fmt.Sprintf("retry abandoned: next attempt needs %s but only %s remain until the deadline", e.needed, e.remaining)
+}
+
+// Is matches failure.ErrOverallDeadline.
+func (e DeadlineError) Is(target error) bool {
+	return target == failure.ErrOverallDeadline
+}
+
+// FitsDeadline turns a Retry from retryer into an Abort when the request
+// context deadline leaves less time than the upcoming backoff plus the
+// expected duration of an attempt.  Requests without a deadline are not
+// affected.
+func FitsDeadline(retryer Retryer, backoff Backoff, expected time.Duration) Retryer {
+	return func(a Attempt) (Decision, error) {
+		decision, err := retryer(a)
+		if decision != Retry || a.Request == nil {
+			return decision, err
+		}
+
+		deadline, ok := a.Request.Context().Deadline()
+		if !ok {
+			return decision, err
+		}
+
+		needed := expected
+		if backoff != nil {
+			needed += backoff(a)
+		}
+
+		if remaining := deadline.Sub(now()); remaining < needed {
+			return Abort, DeadlineError{remaining, needed}
+		}
+		return decision, err
+	}
+}
+
 // "Validators" (return Retry or Ignore)
 
 // Errors returns Retry when the attempt produced an error.
@@ -131,13 +203,35 @@ func Over(statusCode int) Retryer {
 	}
 }
 
-// Method retries when the request method is one of the given methods
+// Status retries when a response is present with one of the given status codes
+func Status(statusCodes ...int) Retryer {
+	codes := make(map[int]struct{}, len(statusCodes))
+	for _, c := range statusCodes {
+		codes[c] = struct{}{}
+	}
+	return func(a Attempt) (Decision, error) {
+		if a.Response == nil {
+			return Ignore, nil
+		}
+		if _, ok := codes[a.Response.StatusCode]; ok {
+			return Retry, nil
+		}
+		return Ignore, nil
+	}
+}
+
+// Method retries when the request method is one of the given methods.
+// Attempts without a Request, like those of connection errors adapted by
+// ToCheckRetry, are Ignored.
 func Method(methods ...string) Retryer {
 	ms := make(map[string]struct{}, len(methods))
 	for _, m := range methods {
 		ms[m] = struct{}{}
 	}
 	return func(a Attempt) (Decision, error) {
+		if a.Request == nil {
+			return Ignore, nil
+		}
 		if _, ok := ms[a.Request.Method]; ok {
 			return Retry, nil
 		}
@@ -149,5 +243,5 @@ func Method(methods ...string) Retryer {
 // RFC 2616, section 9.1.2.
 // https://www.w3.org/Protocols/rfc2616/rfc2616-sec9.html#sec9.1.2
 func Idempotent() Retryer {
-	return Method("GET", "HEAD", "PUT", "DELETE", "OPTIONS", "TRACE")
+	return Method(IdempotentMethods...)
 }
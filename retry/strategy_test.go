@@ -1,12 +1,16 @@
 package retry
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
 	"testing"
 	"time"
+
+	"github.com/streadway/handy/failure"
 )
 
 // conditional implements a Temporary method like http.tlsHandshakeError
@@ -163,3 +167,117 @@ func TestNotTemporaryAborts(t *testing.T) {
 		t.Fatalf("expected temporary errors not to error, got: %v", got)
 	}
 }
+
+func TestFitsDeadlineAbortsWhenBackoffExceedsDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	req, _ := http.NewRequest("GET", "http://example/test", nil)
+	retry, err := FitsDeadline(Errors(), ConstantBackoff(time.Second), 0)(Attempt{
+		Start:   time.Now(),
+		Count:   1,
+		Err:     fmt.Errorf("some error"),
+		Request: req.WithContext(ctx),
+	})
+
+	if want, got := Abort, retry; want != got {
+		t.Fatalf("expected to %v when the backoff exceeds the deadline, got: %v", want, got)
+	}
+	if _, isDeadline := err.(DeadlineError); !isDeadline {
+		t.Fatalf("expected error to be of type DeadlineError, got: %v", err)
+	}
+}
+
+func TestFitsDeadlineRetriesWithinDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	req, _ := http.NewRequest("GET", "http://example/test", nil)
+	retry, err := FitsDeadline(Errors(), ConstantBackoff(time.Second), time.Second)(Attempt{
+		Start:   time.Now(),
+		Count:   1,
+		Err:     fmt.Errorf("some error"),
+		Request: req.WithContext(ctx),
+	})
+
+	if want, got := Retry, retry; want != got {
+		t.Fatalf("expected to %v within the deadline, got: %v", want, got)
+	}
+	if err != nil {
+		t.Fatalf("expected no error within the deadline, got: %v", err)
+	}
+}
+
+func TestFitsDeadlineIgnoresRequestsWithoutDeadline(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://example/test", nil)
+	retry, _ := FitsDeadline(Errors(), ConstantBackoff(time.Hour), time.Hour)(Attempt{
+		Start:   time.Now(),
+		Count:   1,
+		Err:     fmt.Errorf("some error"),
+		Request: req,
+	})
+
+	if want, got := Retry, retry; want != got {
+		t.Fatalf("expected to %v without a deadline, got: %v", want, got)
+	}
+}
+
+func TestLimitDoesNotAbortSuccess(t *testing.T) {
+	retry, err := Limit(Errors(), Max(2))(Attempt{
+		Start: time.Now(),
+		Count: 2,
+	})
+	if want, got := Ignore, retry; want != got {
+		t.Fatalf("expected to %v a successful final attempt, got: %v", want, got)
+	}
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+}
+
+func TestLimitAbortsRetry(t *testing.T) {
+	retry, err := Limit(Errors(), Max(2))(Attempt{
+		Start: time.Now(),
+		Count: 2,
+		Err:   fmt.Errorf("some error"),
+	})
+	if want, got := Abort, retry; want != got {
+		t.Fatalf("expected to %v at the limit, got: %v", want, got)
+	}
+	if _, isMax := err.(MaxError); !isMax {
+		t.Fatalf("expected error to be of type MaxError, got: %v", err)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	retry, _ := Status(429, 503)(Attempt{Response: &http.Response{StatusCode: 503}})
+	if want, got := Retry, retry; want != got {
+		t.Fatalf("expected to %v on a listed status, got: %v", want, got)
+	}
+
+	retry, _ = Status(429, 503)(Attempt{Response: &http.Response{StatusCode: 500}})
+	if want, got := Ignore, retry; want != got {
+		t.Fatalf("expected to %v on other statuses, got: %v", want, got)
+	}
+}
+
+func TestErrorsMatchFailureClasses(t *testing.T) {
+	for name, test := range map[string]struct {
+		err   error
+		class error
+	}{
+		"max":      {MaxError{3}, failure.ErrBudgetExhausted},
+		"timeout":  {TimeoutError{time.Second}, failure.ErrOverallDeadline},
+		"deadline": {DeadlineError{time.Second, time.Minute}, failure.ErrOverallDeadline},
+		"ttfb":     {&AttemptTimeoutError{time.Second, context.DeadlineExceeded}, failure.ErrTTFBTimeout},
+		"connect":  {&AttemptTimeoutError{time.Second, &net.OpError{Op: "dial", Err: context.DeadlineExceeded}}, failure.ErrConnectTimeout},
+	} {
+		if !errors.Is(wrapped(test.err), test.class) {
+			t.Errorf("%s: expected %v to match %v", name, test.err, test.class)
+		}
+	}
+}
+
+func wrapped(e error) error {
+	return fmt.Errorf("get: %w", e)
+}
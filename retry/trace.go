@@ -0,0 +1,175 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/streadway/handy/reqctx"
+	"github.com/streadway/handy/semconv"
+)
+
+// String returns the name of the decision.
+func (d Decision) String() string {
+	switch d {
+	case Ignore:
+		return "ignore"
+	case Retry:
+		return "retry"
+	case Abort:
+		return "abort"
+	}
+	return fmt.Sprintf("Decision(%d)", int(d))
+}
+
+// MarshalText encodes the decision by its name.
+func (d Decision) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// Step records an attempt of a traced request and the decisions made about
+// it.
+type Step struct {
+	Attempt uint          `json:"attempt"`
+	Start   time.Time     `json:"start"`
+	Latency time.Duration `json:"latency"`
+
+	// Status is the response status code, zero without response.
+	Status int `json:"status,omitempty"`
+
+	// Error is the error of the attempt, and ErrorType its low cardinality
+	// type from semconv.ErrorTypeOf.
+	Error     string `json:"error,omitempty"`
+	ErrorType string `json:"error_type,omitempty"`
+
+	// Decision and Reason are the outcome of the Retryer.
+	Decision Decision `json:"decision"`
+	Reason   string   `json:"reason,omitempty"`
+
+	// Remaining is the time left until the deadline of the request context
+	// when the decision was made, zero without deadline.
+	Remaining time.Duration `json:"remaining,omitempty"`
+
+	// Delay is the time waited before the next attempt.
+	Delay time.Duration `json:"delay,omitempty"`
+
+	// Notes were added with Annotate by the transports in Next, like the
+	// state of a circuit breaker.
+	Notes []string `json:"notes,omitempty"`
+}
+
+// Attributes describes the step with the OpenTelemetry semantic
+// conventions.
+func (s Step) Attributes() []semconv.Attribute {
+	var b semconv.Builder
+	if s.Status != 0 {
+		b.Add(semconv.HTTPResponseStatusCode, s.Status)
+	}
+	return b.Add(semconv.ErrorType, s.ErrorType).Attempt(s.Attempt).Attributes()
+}
+
+// Trace records the Steps of the requests with its context, safe for
+// concurrent use.
+type Trace struct {
+	mu    sync.Mutex
+	steps []Step
+	notes []string
+}
+
+// NewTrace constructs an empty Trace.
+func NewTrace() *Trace {
+	return &Trace{}
+}
+
+var traceKey = reqctx.NewKey[*Trace]("retry.Trace")
+
+// WithTrace returns a context carrying t, so that RoundTrip records the
+// Steps of requests with this context.
+func WithTrace(ctx context.Context, t *Trace) context.Context {
+	return traceKey.With(ctx, t)
+}
+
+// TraceFrom returns the Trace carried by ctx, or nil.
+func TraceFrom(ctx context.Context) *Trace {
+	t, _ := traceKey.Value(ctx)
+	return t
+}
+
+// Annotate adds a note to the current attempt when ctx carries a Trace.
+func Annotate(ctx context.Context, format string, v ...interface{}) {
+	t := TraceFrom(ctx)
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.notes = append(t.notes, fmt.Sprintf(format, v...))
+}
+
+// Steps returns the recorded Steps.
+func (t *Trace) Steps() []Step {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Step(nil), t.steps...)
+}
+
+// Elapsed returns the time from the start of the first attempt to the end
+// of the last attempt or delay.
+func (t *Trace) Elapsed() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.steps) == 0 {
+		return 0
+	}
+	first, last := t.steps[0], t.steps[len(t.steps)-1]
+	return last.Start.Add(last.Latency + last.Delay).Sub(first.Start)
+}
+
+// String formats the Steps one per line.
+func (t *Trace) String() string {
+	var b strings.Builder
+	for _, s := range t.Steps() {
+		fmt.Fprintf(&b, "attempt %d: ", s.Attempt)
+		if s.Error != "" {
+			fmt.Fprintf(&b, "error %q", s.Error)
+		} else {
+			fmt.Fprintf(&b, "status %d", s.Status)
+		}
+		fmt.Fprintf(&b, " in %s, %s", s.Latency, s.Decision)
+		if s.Reason != "" {
+			fmt.Fprintf(&b, " (%s)", s.Reason)
+		}
+		if s.Remaining != 0 {
+			fmt.Fprintf(&b, ", %s remaining", s.Remaining)
+		}
+		if s.Delay != 0 {
+			fmt.Fprintf(&b, ", waited %s", s.Delay)
+		}
+		for _, n := range s.Notes {
+			fmt.Fprintf(&b, "; %s", n)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func (t *Trace) record(s Step) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s.Notes, t.notes = t.notes, nil
+	t.steps = append(t.steps, s)
+}
+
+func (t *Trace) delayed(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.steps) > 0 {
+		t.steps[len(t.steps)-1].Delay = d
+	}
+}
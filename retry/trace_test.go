@@ -0,0 +1,96 @@
+package retry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTraceRecordsDecisions(t *testing.T) {
+	var (
+		attempts int
+		trace    = NewTrace()
+		trans    = Transport{
+			Retry: Limit(Errors(), Max(3)),
+			Delay: Constant(5 * time.Millisecond),
+			Next: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				attempts++
+				if attempts < 3 {
+					Annotate(req.Context(), "upstream %d", attempts)
+					return nil, fmt.Errorf("reset")
+				}
+				return &http.Response{StatusCode: 200, Body: http.NoBody}, nil
+			}),
+		}
+	)
+
+	ctx, cancel := context.WithTimeout(WithTrace(context.Background(), trace), time.Minute)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", "http://example/test", nil)
+	if _, err := trans.RoundTrip(req); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := trace.Steps()
+	if want, got := 3, len(steps); want != got {
+		t.Fatalf("expected %d steps, got %d", want, got)
+	}
+
+	for i, want := range []Decision{Retry, Retry, Ignore} {
+		if got := steps[i].Decision; want != got {
+			t.Errorf("step %d: expected decision %s, got %s", i, want, got)
+		}
+	}
+
+	if want, got := "reset", steps[0].Error; want != got {
+		t.Errorf("expected the attempt error %q, got %q", want, got)
+	}
+	if want, got := 200, steps[2].Status; want != got {
+		t.Errorf("expected the last status %d, got %d", want, got)
+	}
+	if steps[0].Delay < 5*time.Millisecond || steps[2].Delay != 0 {
+		t.Errorf("expected delays only between attempts, got %s and %s", steps[0].Delay, steps[2].Delay)
+	}
+	if steps[0].Remaining <= 0 || steps[0].Remaining > time.Minute {
+		t.Errorf("expected the remaining deadline to be recorded, got %s", steps[0].Remaining)
+	}
+	if want, got := "upstream 2", strings.Join(steps[1].Notes, ","); want != got {
+		t.Errorf("expected the notes of the second attempt %q, got %q", want, got)
+	}
+	if trace.Elapsed() < 10*time.Millisecond {
+		t.Errorf("expected the elapsed time to include the delays, got %s", trace.Elapsed())
+	}
+
+	doc, err := json.Marshal(steps[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(doc), `"decision":"retry"`) {
+		t.Errorf("expected the decision by name, got %s", doc)
+	}
+
+	if lines := strings.Count(trace.String(), "\n"); lines != 3 {
+		t.Errorf("expected a line per step, got:\n%s", trace)
+	}
+
+	if want, got := "[http.response.status_code=200 http.request.resend_count=2 retry.count=2]", fmt.Sprint(steps[2].Attributes()); want != got {
+		t.Errorf("expected attributes %s, got %s", want, got)
+	}
+	if want, got := "[error.type=*errors.errorString]", fmt.Sprint(steps[0].Attributes()); want != got {
+		t.Errorf("expected attributes %s, got %s", want, got)
+	}
+}
+
+func TestRoundTripWithoutTrace(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://example/test", nil)
+	Annotate(req.Context(), "ignored")
+
+	if TraceFrom(req.Context()) != nil {
+		t.Fatalf("expected no trace without WithTrace")
+	}
+}
@@ -0,0 +1,47 @@
+package retry
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNoNext is returned by RoundTrip when the Transport has no Next.
+var ErrNoNext = errors.New("retry: transport without Next")
+
+// ConfigError describes an invalid field of a Transport.
+type ConfigError struct {
+	Field  string
+	Reason string
+}
+
+func (e ConfigError) Error() string {
+	return fmt.Sprintf("retry: invalid %s: %s", e.Field, e.Reason)
+}
+
+// Validate checks the configuration of the Transport, returning all the
+// ConfigErrors found joined, or nil.  Call it once at startup to discover
+// misconfiguration before the first request does.
+func (t Transport) Validate() error {
+	var errs []error
+	invalid := func(field, format string, v ...interface{}) {
+		errs = append(errs, ConfigError{Field: field, Reason: fmt.Sprintf(format, v...)})
+	}
+
+	if t.Next == nil {
+		invalid("Next", "must not be nil")
+	}
+
+	if t.AttemptTimeout < 0 {
+		invalid("AttemptTimeout", "must not be negative, got %s", t.AttemptTimeout)
+	}
+
+	if t.ExpectContinue < 0 {
+		invalid("ExpectContinue", "must not be negative, got %d", t.ExpectContinue)
+	}
+
+	if t.Fallback != nil && t.ReturnLastResponse {
+		invalid("ReturnLastResponse", "has no effect with a Fallback")
+	}
+
+	return errors.Join(errs...)
+}
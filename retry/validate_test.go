@@ -0,0 +1,41 @@
+package retry
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	if err := (Transport{Next: http.DefaultTransport}).Validate(); err != nil {
+		t.Fatalf("expected a valid transport, got: %v", err)
+	}
+
+	err := Transport{
+		AttemptTimeout:     -time.Second,
+		Fallback:           func(Attempt) (*http.Response, error) { return nil, nil },
+		ReturnLastResponse: true,
+	}.Validate()
+
+	var fields []string
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var config ConfigError
+		if !errors.As(e, &config) {
+			t.Fatalf("expected a ConfigError, got: %v", e)
+		}
+		fields = append(fields, config.Field)
+	}
+
+	if want, got := []string{"Next", "AttemptTimeout", "ReturnLastResponse"}, fields; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expected invalid fields %v, got %v", want, got)
+	}
+}
+
+func TestRoundTripWithoutNext(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://example/test", nil)
+	if _, err := (Transport{}).RoundTrip(req); err != ErrNoNext {
+		t.Fatalf("expected ErrNoNext, got: %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+/*
+Package scenario describes the behavior of an upstream as JSON rules
+matching requests to scripted responses and faults, and plays them back
+through an http.RoundTripper in tests.
+
+A scenario looks like:
+
+	{
+	  "name": "flaky catalog",
+	  "rules": [
+	    {
+	      "match": {"method": "GET", "path": "/items*", "query": {"page": "1"}},
+	      "responses": [
+	        {"fault": "reset"},
+	        {"status": 503, "delay": "50ms"},
+	        {"status": 200, "json": {"items": []}}
+	      ]
+	    }
+	  ]
+	}
+
+The responses of a rule are played in order, repeating the last one.
+*/
+package scenario
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Faults replacing responses.
+const (
+	// FaultReset fails the round trip like a reset connection.
+	FaultReset = "reset"
+
+	// FaultTimeout blocks the round trip until the request context is done.
+	FaultTimeout = "timeout"
+)
+
+// Scenario is a named list of rules.
+type Scenario struct {
+	Name  string `json:"name"`
+	Rules []Rule `json:"rules"`
+}
+
+// Rule scripts the responses to the requests it matches.
+type Rule struct {
+	Match     Match      `json:"match"`
+	Responses []Response `json:"responses"`
+}
+
+// Match selects requests.  Empty fields match any request.
+type Match struct {
+	Method string `json:"method,omitempty"`
+
+	// Path matches the request path exactly, or by prefix when ending in *.
+	Path string `json:"path,omitempty"`
+
+	// Query and Header are the values required of query parameters and
+	// headers.
+	Query  map[string]string `json:"query,omitempty"`
+	Header map[string]string `json:"header,omitempty"`
+
+	// JSON matches the request body semantically, not comparing the values
+	// at the Ignore paths, see the match package.
+	JSON   json.RawMessage `json:"json,omitempty"`
+	Ignore []string        `json:"ignore,omitempty"`
+}
+
+// Response is a scripted response or fault.
+type Response struct {
+	// Status defaults to 200.
+	Status int               `json:"status,omitempty"`
+	Header map[string]string `json:"header,omitempty"`
+
+	// Body is sent as is, JSON with a JSON content type.
+	Body string          `json:"body,omitempty"`
+	JSON json.RawMessage `json:"json,omitempty"`
+
+	// Delay postpones the response or fault.
+	Delay Duration `json:"delay,omitempty"`
+
+	// Fault replaces the response, FaultReset or FaultTimeout.
+	Fault string `json:"fault,omitempty"`
+}
+
+// Duration is a time.Duration encoded like "50ms".
+type Duration time.Duration
+
+// UnmarshalJSON decodes a duration string.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	*d = Duration(v)
+	return err
+}
+
+// MarshalJSON encodes a duration string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Parse decodes and validates a scenario.
+func Parse(r io.Reader) (*Scenario, error) {
+	var s Scenario
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&s); err != nil {
+		return nil, fmt.Errorf("scenario: %w", err)
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Load parses the scenario in the file at path.
+func Load(path string) (*Scenario, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Validate checks that every rule scripts valid responses.
+func (s *Scenario) Validate() error {
+	for i, rule := range s.Rules {
+		if len(rule.Responses) == 0 {
+			return fmt.Errorf("scenario %q: rule %d has no responses", s.Name, i)
+		}
+		for j, resp := range rule.Responses {
+			switch {
+			case resp.Fault != "" && resp.Fault != FaultReset && resp.Fault != FaultTimeout:
+				return fmt.Errorf("scenario %q: rule %d response %d: unknown fault %q", s.Name, i, j, resp.Fault)
+			case resp.Status != 0 && (resp.Status < 100 || resp.Status > 599):
+				return fmt.Errorf("scenario %q: rule %d response %d: invalid status %d", s.Name, i, j, resp.Status)
+			case resp.Body != "" && resp.JSON != nil:
+				return fmt.Errorf("scenario %q: rule %d response %d: both body and json", s.Name, i, j)
+			}
+		}
+	}
+	return nil
+}
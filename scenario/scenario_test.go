@@ -0,0 +1,74 @@
+package scenario
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const catalog = `{
+  "name": "catalog",
+  "rules": [
+    {
+      "match": {"method": "GET", "path": "/items*", "query": {"page": "1"}},
+      "responses": [
+        {"fault": "reset"},
+        {"status": 503, "delay": "10ms"},
+        {"json": {"items": []}}
+      ]
+    },
+    {
+      "match": {"method": "POST", "path": "/items", "json": {"name": "a", "id": 0}, "ignore": ["$.id"]},
+      "responses": [{"status": 201, "header": {"Location": "/items/1"}}]
+    }
+  ]
+}`
+
+func TestParse(t *testing.T) {
+	s, err := Parse(strings.NewReader(catalog))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := "catalog", s.Name; want != got {
+		t.Fatalf("expected name %q, got %q", want, got)
+	}
+	if want, got := 2, len(s.Rules); want != got {
+		t.Fatalf("expected %d rules, got %d", want, got)
+	}
+	if want, got := 10*time.Millisecond, time.Duration(s.Rules[0].Responses[1].Delay); want != got {
+		t.Fatalf("expected delay %s, got %s", want, got)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, doc := range []string{
+		`{"rules": [{"match": {}}]}`,
+		`{"rules": [{"responses": [{"fault": "explode"}]}]}`,
+		`{"rules": [{"responses": [{"status": 42}]}]}`,
+		`{"rules": [{"responses": [{"body": "a", "json": 1}]}]}`,
+		`{"rules": [{"responses": [{"delay": "soon"}]}]}`,
+		`{"rules": [], "unknown": true}`,
+	} {
+		if _, err := Parse(strings.NewReader(doc)); err == nil {
+			t.Errorf("expected an error for %s", doc)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "catalog.json")
+	if err := os.WriteFile(path, []byte(catalog), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := "catalog", s.Name; want != got {
+		t.Fatalf("expected name %q, got %q", want, got)
+	}
+}
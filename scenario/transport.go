@@ -0,0 +1,169 @@
+package scenario
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/streadway/handy/match"
+)
+
+// UnmatchedError is returned for requests no rule matches.
+type UnmatchedError struct {
+	Method string
+	URL    string
+}
+
+func (e *UnmatchedError) Error() string {
+	return fmt.Sprintf("scenario: no rule matches %s %s", e.Method, e.URL)
+}
+
+// Transport is an http.RoundTripper playing a Scenario back, safe for
+// concurrent use.
+type Transport struct {
+	scenario *Scenario
+
+	mu    sync.Mutex
+	calls []int // per rule
+}
+
+// NewTransport plays s back, once validated.
+func NewTransport(s *Scenario) (*Transport, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return &Transport{scenario: s, calls: make([]int, len(s.Rules))}, nil
+}
+
+// Calls returns the number of requests matched by the rule at index i.
+func (t *Transport) Calls(i int) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.calls[i]
+}
+
+// RoundTrip responds to req with the next response of the first rule
+// matching it.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, rule := range t.scenario.Rules {
+		if !rule.Match.matches(req, body) {
+			continue
+		}
+
+		t.mu.Lock()
+		n := t.calls[i]
+		t.calls[i]++
+		t.mu.Unlock()
+
+		if n >= len(rule.Responses) {
+			n = len(rule.Responses) - 1
+		}
+		return rule.Responses[n].play(req)
+	}
+
+	return nil, &UnmatchedError{Method: req.Method, URL: req.URL.String()}
+}
+
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	defer req.Body.Close()
+	return io.ReadAll(req.Body)
+}
+
+func (m Match) matches(req *http.Request, body []byte) bool {
+	if m.Method != "" && m.Method != req.Method {
+		return false
+	}
+
+	if prefix := strings.TrimSuffix(m.Path, "*"); prefix != m.Path {
+		if !strings.HasPrefix(req.URL.Path, prefix) {
+			return false
+		}
+	} else if m.Path != "" && m.Path != req.URL.Path {
+		return false
+	}
+
+	query := req.URL.Query()
+	for k, v := range m.Query {
+		if query.Get(k) != v {
+			return false
+		}
+	}
+	for k, v := range m.Header {
+		if req.Header.Get(k) != v {
+			return false
+		}
+	}
+
+	if m.JSON != nil {
+		return match.JSON{Ignore: m.Ignore}.Equal(m.JSON, body)
+	}
+	return true
+}
+
+func (r Response) play(req *http.Request) (*http.Response, error) {
+	if r.Delay > 0 {
+		timer := time.NewTimer(time.Duration(r.Delay))
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+
+	switch r.Fault {
+	case FaultReset:
+		return nil, &connError{syscall.ECONNRESET}
+	case FaultTimeout:
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	}
+
+	status := r.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	header := make(http.Header)
+	body := []byte(r.Body)
+	if r.JSON != nil {
+		header.Set("Content-Type", "application/json")
+		body = r.JSON
+	}
+	for k, v := range r.Header {
+		header.Set(k, v)
+	}
+
+	return &http.Response{
+		Status:        strconv.Itoa(status) + " " + http.StatusText(status),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// connError is a temporary connection failure, retried like a real one.
+type connError struct{ err error }
+
+func (e *connError) Error() string   { return "scenario: " + e.err.Error() }
+func (e *connError) Unwrap() error   { return e.err }
+func (e *connError) Temporary() bool { return true }
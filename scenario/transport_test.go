@@ -0,0 +1,124 @@
+package scenario
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func newTransport(t *testing.T) *Transport {
+	s, err := Parse(strings.NewReader(catalog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	trans, err := NewTransport(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return trans
+}
+
+func TestTransportScriptsResponses(t *testing.T) {
+	trans := newTransport(t)
+	req, _ := http.NewRequest("GET", "http://example/items/recent?page=1", nil)
+
+	_, err := trans.RoundTrip(req)
+	if !errors.Is(err, syscall.ECONNRESET) {
+		t.Fatalf("expected a connection reset, got %v", err)
+	}
+
+	start := time.Now()
+	resp, err := trans.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 503, resp.StatusCode; want != got {
+		t.Fatalf("expected status %d, got %d", want, got)
+	}
+	if elapsed := time.Since(start); elapsed < 10*time.Millisecond {
+		t.Fatalf("expected the response to be delayed, took %s", elapsed)
+	}
+
+	for i := 0; i < 2; i++ {
+		resp, err := trans.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if want, got := `{"items": []}`, string(body); want != got {
+			t.Fatalf("expected the last response to repeat with body %s, got %s", want, got)
+		}
+		if want, got := "application/json", resp.Header.Get("Content-Type"); want != got {
+			t.Fatalf("expected content type %q, got %q", want, got)
+		}
+	}
+
+	if want, got := 4, trans.Calls(0); want != got {
+		t.Fatalf("expected %d calls, got %d", want, got)
+	}
+}
+
+func TestTransportMatchesJSONBody(t *testing.T) {
+	trans := newTransport(t)
+
+	req, _ := http.NewRequest("POST", "http://example/items", strings.NewReader(`{"id": 7, "name": "a"}`))
+	resp, err := trans.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 201, resp.StatusCode; want != got {
+		t.Fatalf("expected status %d, got %d", want, got)
+	}
+	if want, got := "/items/1", resp.Header.Get("Location"); want != got {
+		t.Fatalf("expected location %q, got %q", want, got)
+	}
+
+	req, _ = http.NewRequest("POST", "http://example/items", strings.NewReader(`{"id": 7, "name": "b"}`))
+	if _, err := trans.RoundTrip(req); err == nil {
+		t.Fatalf("expected a different body not to match")
+	}
+}
+
+func TestTransportUnmatched(t *testing.T) {
+	trans := newTransport(t)
+
+	req, _ := http.NewRequest("GET", "http://example/items?page=2", nil)
+	_, err := trans.RoundTrip(req)
+
+	var unmatched *UnmatchedError
+	if !errors.As(err, &unmatched) {
+		t.Fatalf("expected an UnmatchedError, got %v", err)
+	}
+	if want, got := "http://example/items?page=2", unmatched.URL; want != got {
+		t.Fatalf("expected URL %q, got %q", want, got)
+	}
+}
+
+func TestTransportTimeoutFault(t *testing.T) {
+	s := &Scenario{Rules: []Rule{{Responses: []Response{{Fault: FaultTimeout}}}}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	trans, err := NewTransport(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", "http://example/", nil)
+	if _, err := trans.RoundTrip(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the deadline to be exceeded, got %v", err)
+	}
+}
+
+func TestNewTransportValidates(t *testing.T) {
+	s := &Scenario{Rules: []Rule{{Match: Match{Path: "/"}}}}
+	if _, err := NewTransport(s); err == nil {
+		t.Fatalf("expected a rule without responses to be rejected")
+	}
+}
@@ -0,0 +1,178 @@
+/*
+Package semconv builds telemetry attributes named after the OpenTelemetry
+HTTP semantic conventions, so that the telemetry of handy transports and
+handlers merges with that of instrumented services.
+*/
+package semconv
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/streadway/handy/failure"
+	"github.com/streadway/handy/redact"
+)
+
+// Attribute names.
+const (
+	HTTPRequestMethod         = "http.request.method"
+	HTTPRequestMethodOriginal = "http.request.method_original"
+	HTTPRequestResendCount    = "http.request.resend_count"
+	HTTPResponseStatusCode    = "http.response.status_code"
+	HTTPResponseBodySize      = "http.response.body.size"
+	HTTPRoute                 = "http.route"
+	ServerAddress             = "server.address"
+	ServerPort                = "server.port"
+	ClientAddress             = "client.address"
+	URLFull                   = "url.full"
+	URLScheme                 = "url.scheme"
+	URLPath                   = "url.path"
+	UserAgentOriginal         = "user_agent.original"
+	NetworkProtocolVersion    = "network.protocol.version"
+	ErrorType                 = "error.type"
+	RetryCount                = "retry.count"
+)
+
+// Attribute is a named value of type string, int, int64 or bool.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+func (a Attribute) String() string {
+	return fmt.Sprintf("%s=%v", a.Key, a.Value)
+}
+
+// Builder collects attributes in order.  The zero Builder is empty and
+// ready to use.
+type Builder struct {
+	attrs []Attribute
+}
+
+// Add appends an attribute.  Empty strings are left out.
+func (b *Builder) Add(key string, value interface{}) *Builder {
+	if s, ok := value.(string); ok && s == "" {
+		return b
+	}
+	b.attrs = append(b.attrs, Attribute{key, value})
+	return b
+}
+
+// Method adds the request method, replacing unknown methods by _OTHER as the
+// conventions require to bound cardinality.
+func (b *Builder) Method(method string) *Builder {
+	switch method {
+	case "CONNECT", "DELETE", "GET", "HEAD", "OPTIONS", "PATCH", "POST", "PUT", "TRACE":
+		return b.Add(HTTPRequestMethod, method)
+	}
+	return b.Add(HTTPRequestMethod, "_OTHER").Add(HTTPRequestMethodOriginal, method)
+}
+
+// Request adds the method, server and URL of an outbound request.  The URL
+// is stripped of user information and of the values of the redact.Query
+// parameters.
+func (b *Builder) Request(req *http.Request) *Builder {
+	b.Method(req.Method)
+	if req.URL == nil {
+		return b
+	}
+
+	b.Add(ServerAddress, req.URL.Hostname())
+	if port := port(req.URL); port != 0 {
+		b.Add(ServerPort, port)
+	}
+
+	return b.Add(URLFull, redact.URL(req.URL, redact.Query)).Add(URLScheme, req.URL.Scheme)
+}
+
+// Response adds the status code and known body size of a response.
+func (b *Builder) Response(resp *http.Response) *Builder {
+	b.Add(HTTPResponseStatusCode, resp.StatusCode)
+	if resp.ContentLength >= 0 {
+		b.Add(HTTPResponseBodySize, resp.ContentLength)
+	}
+	return b
+}
+
+// Error adds the type of err, see ErrorTypeOf.
+func (b *Builder) Error(err error) *Builder {
+	if err == nil {
+		return b
+	}
+	return b.Add(ErrorType, ErrorTypeOf(err))
+}
+
+// Client adds the address of the peer of an inbound request from its
+// RemoteAddr.
+func (b *Builder) Client(remoteAddr string) *Builder {
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		remoteAddr = host
+	}
+	return b.Add(ClientAddress, remoteAddr)
+}
+
+// Attempt adds the number of retries of the attempt numbered count, both as
+// the resend count of the conventions and as retry.count.
+func (b *Builder) Attempt(count uint) *Builder {
+	if count <= 1 {
+		return b
+	}
+	return b.Add(HTTPRequestResendCount, int(count-1)).Add(RetryCount, int(count-1))
+}
+
+// Attributes returns the attributes collected.
+func (b *Builder) Attributes() []Attribute {
+	return b.attrs
+}
+
+// Map returns the attributes collected by name.
+func (b *Builder) Map() map[string]interface{} {
+	m := make(map[string]interface{}, len(b.attrs))
+	for _, a := range b.attrs {
+		m[a.Key] = a.Value
+	}
+	return m
+}
+
+// ErrorTypeOf returns the low cardinality error.type of err: the name of
+// its failure class, like "connect_timeout", or its Go type.
+func ErrorTypeOf(err error) string {
+	if class := failure.Of(err); class != nil {
+		return strings.ReplaceAll(class.Error(), " ", "_")
+	}
+
+	// Look through the wrapping to the cause, the first of joined errors
+	for {
+		var next error
+		switch e := err.(type) {
+		case interface{ Unwrap() error }:
+			next = e.Unwrap()
+		case interface{ Unwrap() []error }:
+			if errs := e.Unwrap(); len(errs) > 0 {
+				next = errs[0]
+			}
+		}
+		if next == nil {
+			return fmt.Sprintf("%T", err)
+		}
+		err = next
+	}
+}
+
+func port(u *url.URL) int {
+	if p := u.Port(); p != "" {
+		n, _ := strconv.Atoi(p)
+		return n
+	}
+	switch u.Scheme {
+	case "http":
+		return 80
+	case "https":
+		return 443
+	}
+	return 0
+}
@@ -0,0 +1,415 @@
+/*
+Package shadow compares the responses of a backend with those of its
+replacement.  Requests are served by the primary backend while a copy is
+sent to the secondary in the background, and the differences of status,
+headers and body are reported.
+*/
+package shadow
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/streadway/handy/atomic"
+	"github.com/streadway/handy/header"
+	"github.com/streadway/handy/match"
+)
+
+const (
+	// DefaultMaxBody is the largest body compared when not configured.
+	DefaultMaxBody = 1 << 20
+
+	// DefaultTimeout limits each comparison when not configured.
+	DefaultTimeout = 10 * time.Second
+
+	// DefaultMaxInFlight is the number of concurrent comparisons when not
+	// configured.
+	DefaultMaxInFlight = 64
+)
+
+var (
+	// DefaultMethods are the methods shadowed when not configured, as other
+	// methods would repeat their side effects on the secondary.
+	DefaultMethods = []string{"GET", "HEAD"}
+
+	// DefaultHeaders are the response headers compared when not configured.
+	DefaultHeaders = []string{"Content-Type"}
+)
+
+// HeaderDiff is a response header differing between the backends.
+type HeaderDiff struct {
+	Name      string
+	Primary   string
+	Secondary string
+}
+
+// Diff describes the differences of the responses to a request.
+type Diff struct {
+	Method string
+	URL    string
+
+	PrimaryStatus   int
+	SecondaryStatus int
+
+	Headers []HeaderDiff
+
+	// Body describes the first difference of the bodies, empty when equal
+	// or when a body exceeded the MaxBody.
+	Body string
+
+	// Err is the error of the secondary.
+	Err error
+}
+
+// Equal reports whether no difference was found.
+func (d Diff) Equal() bool {
+	return d.Err == nil && d.PrimaryStatus == d.SecondaryStatus && len(d.Headers) == 0 && d.Body == ""
+}
+
+func (d Diff) String() string {
+	if d.Err != nil {
+		return fmt.Sprintf("%s %s: secondary error: %s", d.Method, d.URL, d.Err)
+	}
+	var diffs []string
+	if d.PrimaryStatus != d.SecondaryStatus {
+		diffs = append(diffs, fmt.Sprintf("status %d != %d", d.PrimaryStatus, d.SecondaryStatus))
+	}
+	for _, h := range d.Headers {
+		diffs = append(diffs, fmt.Sprintf("%s %q != %q", h.Name, h.Primary, h.Secondary))
+	}
+	if d.Body != "" {
+		diffs = append(diffs, "body "+d.Body)
+	}
+	if len(diffs) == 0 {
+		return fmt.Sprintf("%s %s: equal", d.Method, d.URL)
+	}
+	return fmt.Sprintf("%s %s: %s", d.Method, d.URL, strings.Join(diffs, ", "))
+}
+
+// Transport is an http.RoundTripper serving requests from Primary and
+// comparing the responses to copies sent to Secondary.
+type Transport struct {
+	// Primary serves the requests.  If Primary is nil, http.DefaultTransport
+	// is used.
+	Primary http.RoundTripper
+
+	// Secondary receives the copies of the requests.  If Secondary is nil,
+	// http.DefaultTransport is used.
+	Secondary http.RoundTripper
+
+	// Target replaces the scheme and host of the copies, to reach the
+	// secondary backend.  If nil, copies keep the request URL.
+	Target *url.URL
+
+	// Methods are shadowed, DefaultMethods when nil.  Requests with a body
+	// are only shadowed when their GetBody is set.
+	Methods []string
+
+	// Headers are the response headers compared, DefaultHeaders when nil.
+	Headers []string
+
+	// JSON compares JSON bodies semantically, with its ignored paths and
+	// patterns.  Other bodies are compared byte by byte.
+	JSON match.JSON
+
+	// MaxBody is the largest body compared, DefaultMaxBody when zero.
+	MaxBody int64
+
+	// Timeout limits the request to the secondary and the reading of its
+	// response, DefaultTimeout when zero.
+	Timeout time.Duration
+
+	// MaxInFlight limits the concurrent comparisons, DefaultMaxInFlight when
+	// zero.  Requests over the limit are not shadowed.
+	MaxInFlight int
+
+	// OnDiff receives the differing comparisons.
+	OnDiff func(Diff)
+
+	compared, mismatched, failed, skipped atomic.Int
+
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	inFlight int
+}
+
+// Compared returns the number of comparisons made.
+func (t *Transport) Compared() int64 { return t.compared.Get() }
+
+// Mismatched returns the number of comparisons that found differences.
+func (t *Transport) Mismatched() int64 { return t.mismatched.Get() }
+
+// Failed returns the number of copies the secondary failed to respond to.
+func (t *Transport) Failed() int64 { return t.failed.Get() }
+
+// Skipped returns the number of requests not shadowed because MaxInFlight
+// comparisons were in progress.
+func (t *Transport) Skipped() int64 { return t.skipped.Get() }
+
+// Wait waits for the comparisons in progress, which end once the primary
+// bodies were read or closed and the secondary responded or timed out.
+func (t *Transport) Wait() {
+	t.wg.Wait()
+}
+
+// RoundTrip implements the RoundTripper interface.  The response of the
+// primary is returned as it arrives, and its body is compared as the caller
+// reads it, once read to the end or closed.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	primary := t.Primary
+	if primary == nil {
+		primary = http.DefaultTransport
+	}
+
+	shadow, err := t.copy(req)
+	if err != nil || shadow == nil {
+		return primary.RoundTrip(req)
+	}
+
+	if !t.acquire() {
+		t.skipped.Add(1)
+		return primary.RoundTrip(req)
+	}
+
+	resp, err := primary.RoundTrip(req)
+	if err != nil {
+		t.release()
+		return resp, err
+	}
+
+	// The caller owns the response, so only copies are compared
+	var (
+		headers  = t.headers()
+		snapshot = make(map[string]string, len(headers)+1)
+		body     = &teeBody{ReadCloser: resp.Body, limit: t.maxBody(), done: make(chan struct{})}
+	)
+	for _, name := range headers {
+		snapshot[name] = resp.Header.Get(name)
+	}
+	snapshot["Content-Type"] = resp.Header.Get("Content-Type")
+	resp.Body = body
+
+	t.wg.Add(1)
+	go func(status int) {
+		defer t.wg.Done()
+		defer t.release()
+
+		ctx, cancel := context.WithTimeout(shadow.Context(), t.timeout())
+		defer cancel()
+		t.compare(shadow.WithContext(ctx), status, snapshot, body)
+	}(resp.StatusCode)
+
+	return resp, nil
+}
+
+// acquire reserves one of the MaxInFlight comparisons.
+func (t *Transport) acquire() bool {
+	limit := t.MaxInFlight
+	if limit <= 0 {
+		limit = DefaultMaxInFlight
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.inFlight >= limit {
+		return false
+	}
+	t.inFlight++
+	return true
+}
+
+func (t *Transport) release() {
+	t.mu.Lock()
+	t.inFlight--
+	t.mu.Unlock()
+}
+
+// copy returns the request to send to the secondary, or nil when req is not
+// shadowed.
+func (t *Transport) copy(req *http.Request) (*http.Request, error) {
+	methods := t.Methods
+	if methods == nil {
+		methods = DefaultMethods
+	}
+	if !contains(methods, req.Method) {
+		return nil, nil
+	}
+
+	shadow := req.Clone(context.WithoutCancel(req.Context()))
+	header.Sanitize(shadow.Header)
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return nil, nil
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		shadow.Body = body
+	}
+
+	if t.Target != nil {
+		shadow.URL.Scheme = t.Target.Scheme
+		shadow.URL.Host = t.Target.Host
+		shadow.Host = ""
+	}
+	return shadow, nil
+}
+
+// teeBody keeps up to limit bytes of the primary body read by the caller.
+// Its done channel is closed once the body was read to the end or closed,
+// after which the kept bytes are no longer written.
+type teeBody struct {
+	io.ReadCloser
+	limit    int64
+	kept     bytes.Buffer
+	complete bool
+	once     sync.Once
+	done     chan struct{}
+}
+
+func (b *teeBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	select {
+	case <-b.done:
+		return n, err
+	default:
+	}
+
+	if room := b.limit + 1 - int64(b.kept.Len()); room > 0 {
+		if int64(n) < room {
+			room = int64(n)
+		}
+		b.kept.Write(p[:room])
+	}
+	if err != nil {
+		b.finish(err == io.EOF)
+	}
+	return n, err
+}
+
+func (b *teeBody) Close() error {
+	b.finish(false)
+	return b.ReadCloser.Close()
+}
+
+func (b *teeBody) finish(eof bool) {
+	b.once.Do(func() {
+		b.complete = eof && int64(b.kept.Len()) <= b.limit
+		close(b.done)
+	})
+}
+
+func (t *Transport) compare(req *http.Request, status int, snapshot map[string]string, body *teeBody) {
+	secondary := t.Secondary
+	if secondary == nil {
+		secondary = http.DefaultTransport
+	}
+
+	diff := Diff{
+		Method:        req.Method,
+		URL:           req.URL.String(),
+		PrimaryStatus: status,
+	}
+
+	resp, err := secondary.RoundTrip(req)
+	if err != nil {
+		t.failed.Add(1)
+		diff.Err = err
+		t.report(diff)
+		return
+	}
+	defer resp.Body.Close()
+
+	t.compared.Add(1)
+	diff.SecondaryStatus = resp.StatusCode
+
+	for _, name := range t.headers() {
+		p, s := snapshot[name], resp.Header.Get(name)
+		if p != s {
+			diff.Headers = append(diff.Headers, HeaderDiff{Name: name, Primary: p, Secondary: s})
+		}
+	}
+
+	other, err := ioutil.ReadAll(io.LimitReader(resp.Body, t.maxBody()+1))
+
+	<-body.done
+	if err == nil && body.complete && int64(len(other)) <= t.maxBody() {
+		diff.Body = t.compareBody(snapshot["Content-Type"], body.kept.Bytes(), other)
+	}
+
+	if !diff.Equal() {
+		t.mismatched.Add(1)
+		t.report(diff)
+	}
+}
+
+// compareBody describes the first difference of the bodies, or returns "".
+func (t *Transport) compareBody(contentType string, primary, secondary []byte) string {
+	if isJSON(contentType) {
+		if err := t.JSON.Match(primary, bytes.NewReader(secondary)); err != nil {
+			return err.Error()
+		}
+		return ""
+	}
+
+	if bytes.Equal(primary, secondary) {
+		return ""
+	}
+	for i := range primary {
+		if i >= len(secondary) || primary[i] != secondary[i] {
+			return fmt.Sprintf("differs at byte %d", i)
+		}
+	}
+	return fmt.Sprintf("differs at byte %d", len(primary))
+}
+
+func (t *Transport) report(d Diff) {
+	if t.OnDiff != nil {
+		t.OnDiff(d)
+	}
+}
+
+func (t *Transport) headers() []string {
+	if t.Headers == nil {
+		return DefaultHeaders
+	}
+	return t.Headers
+}
+
+func (t *Transport) timeout() time.Duration {
+	if t.Timeout <= 0 {
+		return DefaultTimeout
+	}
+	return t.Timeout
+}
+
+func (t *Transport) maxBody() int64 {
+	if t.MaxBody <= 0 {
+		return DefaultMaxBody
+	}
+	return t.MaxBody
+}
+
+func isJSON(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,219 @@
+package shadow
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/streadway/handy/match"
+)
+
+func backend(contentType, body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Write([]byte(body))
+	}))
+}
+
+func shadowed(t *testing.T, trans *Transport, primary *httptest.Server, method string) string {
+	req, _ := http.NewRequest(method, primary.URL+"/items", nil)
+	resp, err := trans.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	trans.Wait()
+	return string(body)
+}
+
+func TestTransportComparesJSON(t *testing.T) {
+	primary := backend("application/json", `{"id": 1, "items": [1, 2], "at": "noon"}`)
+	defer primary.Close()
+	secondary := backend("application/json", `{"items":[1,2],"id":1.0,"at":"midnight"}`)
+	defer secondary.Close()
+
+	target, _ := url.Parse(secondary.URL)
+
+	var diffs []Diff
+	trans := &Transport{
+		Target: target,
+		JSON:   match.JSON{Ignore: []string{"$.at"}},
+		OnDiff: func(d Diff) { diffs = append(diffs, d) },
+	}
+
+	if body := shadowed(t, trans, primary, "GET"); !strings.Contains(body, "noon") {
+		t.Fatalf("expected the primary to serve the caller, got %s", body)
+	}
+
+	if want, got := int64(1), trans.Compared(); want != got {
+		t.Fatalf("expected %d comparison, got %d", want, got)
+	}
+	if len(diffs) != 0 || trans.Mismatched() != 0 {
+		t.Fatalf("expected equivalent JSON to match, got %v", diffs)
+	}
+}
+
+func TestTransportReportsDiffs(t *testing.T) {
+	primary := backend("application/json", `{"id": 1}`)
+	defer primary.Close()
+	secondary := backend("application/problem+json", `{"id": 2}`)
+	defer secondary.Close()
+
+	target, _ := url.Parse(secondary.URL)
+
+	var diffs []Diff
+	trans := &Transport{
+		Target: target,
+		OnDiff: func(d Diff) { diffs = append(diffs, d) },
+	}
+
+	shadowed(t, trans, primary, "GET")
+
+	if want, got := int64(1), trans.Mismatched(); want != got {
+		t.Fatalf("expected %d mismatch, got %d", want, got)
+	}
+
+	d := diffs[0]
+	if want, got := []HeaderDiff{{"Content-Type", "application/json", "application/problem+json"}}, d.Headers; len(got) != 1 || want[0] != got[0] {
+		t.Fatalf("expected header diff %v, got %v", want, got)
+	}
+	if !strings.HasPrefix(d.Body, "$.id") {
+		t.Fatalf("expected a body diff at $.id, got %q", d.Body)
+	}
+}
+
+func TestTransportSkipsUnsafeMethods(t *testing.T) {
+	primary := backend("text/plain", "ok")
+	defer primary.Close()
+
+	var called bool
+	trans := &Transport{
+		Secondary: roundTripFunc(func(*http.Request) (*http.Response, error) {
+			called = true
+			return nil, errors.New("unexpected")
+		}),
+	}
+
+	shadowed(t, trans, primary, "POST")
+
+	if called {
+		t.Fatalf("expected POST not to be shadowed")
+	}
+}
+
+func TestTransportCountsSecondaryFailures(t *testing.T) {
+	primary := backend("text/plain", "ok")
+	defer primary.Close()
+
+	var diffs []Diff
+	trans := &Transport{
+		Secondary: roundTripFunc(func(*http.Request) (*http.Response, error) {
+			return nil, errors.New("connection refused")
+		}),
+		OnDiff: func(d Diff) { diffs = append(diffs, d) },
+	}
+
+	shadowed(t, trans, primary, "GET")
+
+	if want, got := int64(1), trans.Failed(); want != got {
+		t.Fatalf("expected %d failure, got %d", want, got)
+	}
+	if len(diffs) != 1 || diffs[0].Err == nil {
+		t.Fatalf("expected the failure to be reported, got %v", diffs)
+	}
+}
+
+func TestTransportDoesNotBlockOnPrimaryBody(t *testing.T) {
+	release := make(chan struct{})
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("first "))
+		w.(http.Flusher).Flush()
+		<-release
+		w.Write([]byte("last"))
+	}))
+	defer primary.Close()
+	secondary := backend("text/plain", "first last")
+	defer secondary.Close()
+
+	target, _ := url.Parse(secondary.URL)
+
+	var diffs []Diff
+	trans := &Transport{
+		Target: target,
+		OnDiff: func(d Diff) { diffs = append(diffs, d) },
+	}
+
+	req, _ := http.NewRequest("GET", primary.URL, nil)
+	resp, err := trans.RoundTrip(req)
+	close(release)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Callers may modify the response while it is compared
+	resp.Header.Del("Content-Type")
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	trans.Wait()
+
+	if want, got := "first last", string(body); want != got {
+		t.Fatalf("expected body %q, got %q", want, got)
+	}
+	if want, got := int64(1), trans.Compared(); want != got {
+		t.Fatalf("expected %d comparison, got %d", want, got)
+	}
+	if len(diffs) != 0 {
+		t.Fatalf("expected the streamed body to match, got %v", diffs)
+	}
+}
+
+func TestTransportLimitsComparisons(t *testing.T) {
+	primary := backend("text/plain", "ok")
+	defer primary.Close()
+
+	trans := &Transport{
+		Secondary: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		}),
+		Timeout:     10 * time.Millisecond,
+		MaxInFlight: 1,
+	}
+
+	req, _ := http.NewRequest("GET", primary.URL, nil)
+	held, err := trans.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shadowed(t, trans, primary, "GET")
+	if want, got := int64(1), trans.Skipped(); want != got {
+		t.Fatalf("expected %d skipped comparison over the limit, got %d", want, got)
+	}
+
+	held.Body.Close()
+	trans.Wait()
+
+	if want, got := int64(1), trans.Failed(); want != got {
+		t.Fatalf("expected the hung secondary to time out %d time, got %d", want, got)
+	}
+
+	shadowed(t, trans, primary, "GET")
+	if want, got := int64(2), trans.Failed(); want != got {
+		t.Fatalf("expected requests to be shadowed again, got %d failures", got)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,69 @@
+package signed
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func request(method string, u *url.URL, remote string) *http.Request {
+	r := httptest.NewRequest(method, u.String(), nil)
+	r.RemoteAddr = remote
+	return r
+}
+
+func TestVerify(t *testing.T) {
+	var (
+		now    = time.Unix(1000, 0)
+		signer = Signer{Key: []byte("secret"), Now: func() time.Time { return now }}
+		u, _   = url.Parse("http://example.org/download/file.zip?version=2")
+		signed = signer.Sign(u, Options{Expires: now.Add(time.Minute), Method: "GET", IP: "10.0.0.1"})
+	)
+
+	tampered := *signed
+	tampered.Path = "/download/other.zip"
+
+	unsigned, _ := url.Parse("http://example.org/download/file.zip")
+
+	for name, it := range map[string]struct {
+		r    *http.Request
+		want error
+	}{
+		"valid":    {request("GET", signed, "10.0.0.1:1234"), nil},
+		"method":   {request("POST", signed, "10.0.0.1:1234"), ErrMethod},
+		"ip":       {request("GET", signed, "10.0.0.2:1234"), ErrIP},
+		"tampered": {request("GET", &tampered, "10.0.0.1:1234"), ErrInvalidSignature},
+		"unsigned": {request("GET", unsigned, "10.0.0.1:1234"), ErrUnsigned},
+	} {
+		if got := signer.Verify(it.r); it.want != got {
+			t.Errorf("%s: want %v, got %v", name, it.want, got)
+		}
+	}
+
+	now = now.Add(2 * time.Minute)
+	if got := signer.Verify(request("GET", signed, "10.0.0.1:1234")); got != ErrExpired {
+		t.Fatalf("expected %v after expiry, got %v", ErrExpired, got)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var (
+		signer  = Signer{Key: []byte("secret")}
+		u, _    = url.Parse("http://example.org/hook")
+		handler = Middleware(signer)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	)
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, request("GET", signer.Sign(u, Options{Expires: time.Now().Add(time.Minute)}), "10.0.0.1:1"))
+	if want, got := http.StatusOK, resp.Code; want != got {
+		t.Fatalf("expected %d for a signed URL, got %d", want, got)
+	}
+
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, request("GET", u, "10.0.0.1:1"))
+	if want, got := http.StatusForbidden, resp.Code; want != got {
+		t.Fatalf("expected %d for an unsigned URL, got %d", want, got)
+	}
+}
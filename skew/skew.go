@@ -0,0 +1,193 @@
+/*
+Package skew estimates the offset of upstream clocks from their Date response
+headers, so that signatures and expiring tokens are created with the
+upstream's idea of the time.  A Transport signs requests with the corrected
+time and signs once more when a rejection reveals the clocks drifted apart.
+
+The Now method of an Estimator fits time hooks like signed.Signer.Now.
+*/
+package skew
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultTolerance is the change of the offset that makes a Transport sign
+// a rejected request again, when not configured.  Date headers have a
+// resolution of a second.
+const DefaultTolerance = 2 * time.Second
+
+// ErrNoEstimator is returned by a Transport without Estimator.
+var ErrNoEstimator = errors.New("skew: Transport without Estimator")
+
+// smoothing is the weight of the latest sample in the estimated offset.
+const smoothing = 0.25
+
+// Estimator tracks the offset of an upstream clock from the local clock,
+// safe for concurrent use.
+type Estimator struct {
+	// Clock returns the local time, time.Now when nil.
+	Clock func() time.Time
+
+	mu      sync.Mutex
+	offset  time.Duration
+	samples int
+}
+
+func (e *Estimator) clock() time.Time {
+	if e.Clock != nil {
+		return e.Clock()
+	}
+	return time.Now()
+}
+
+// Observe adds the Date of a response to a request sent and received at the
+// local times, and reports whether there was a Date to observe.
+func (e *Estimator) Observe(sent, received time.Time, h http.Header) bool {
+	date, err := http.ParseTime(h.Get("Date"))
+	if err != nil {
+		return false
+	}
+
+	// The Date is truncated to the second, so compare it with the middle of
+	// the round trip plus half a second
+	local := sent.Add(received.Sub(sent) / 2)
+	sample := date.Add(500 * time.Millisecond).Sub(local)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.samples == 0 {
+		e.offset = sample
+	} else {
+		e.offset += time.Duration(smoothing * float64(sample-e.offset))
+	}
+	e.samples++
+
+	return true
+}
+
+// reset replaces the estimate with a single sample.
+func (e *Estimator) reset(sent, received time.Time, h http.Header) bool {
+	e.mu.Lock()
+	e.samples = 0
+	e.mu.Unlock()
+	return e.Observe(sent, received, h)
+}
+
+// Offset returns the estimated time the upstream clock is ahead.
+func (e *Estimator) Offset() time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.offset
+}
+
+// Now returns the estimated upstream time.
+func (e *Estimator) Now() time.Time {
+	return e.clock().Add(e.Offset())
+}
+
+// Transport is an http.RoundTripper signing requests at the estimated
+// upstream time and observing the Date of responses.  When a response is
+// Rejected and its Date moved the estimate by more than the Tolerance, the
+// request is signed and sent once more.
+type Transport struct {
+	// Estimator tracks the upstream clock.  It is required, as it is shared
+	// by the copies of the Transport; RoundTrip fails with ErrNoEstimator
+	// when Estimator is nil.
+	Estimator *Estimator
+
+	// Sign signs a copy of each request for the time.  If nil, requests are
+	// only observed.
+	Sign func(req *http.Request, now time.Time) error
+
+	// Rejected reports whether a response rejected the signature.  If nil,
+	// 401 and 403 responses are rejections.
+	Rejected func(*http.Response) bool
+
+	// Tolerance is the change of the offset making a rejected request to be
+	// signed again, DefaultTolerance when zero.
+	Tolerance time.Duration
+
+	// Next is the http.RoundTripper to which requests are forwarded.  If Next
+	// is nil, http.DefaultTransport is used.
+	Next http.RoundTripper
+}
+
+// RoundTrip implements the RoundTripper interface.
+func (t Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.Estimator == nil {
+		return nil, ErrNoEstimator
+	}
+	used := t.Estimator.Offset()
+
+	resp, err := t.send(req, false)
+	if err != nil || t.Sign == nil || !t.rejected(resp) {
+		return resp, err
+	}
+
+	// The estimate was refreshed by the rejection, retry when it moved
+	tolerance := t.Tolerance
+	if tolerance == 0 {
+		tolerance = DefaultTolerance
+	}
+	if drift := t.Estimator.Offset() - used; drift < tolerance && drift > -tolerance {
+		return resp, nil
+	}
+
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return resp, nil
+	}
+
+	resp.Body.Close()
+	return t.send(req, true)
+}
+
+// send signs a copy of req, sends it and observes the response Date.
+func (t Transport) send(req *http.Request, rewind bool) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	out := req
+	if t.Sign != nil {
+		out = req.Clone(req.Context())
+		if rewind && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			out.Body = body
+		}
+		if err := t.Sign(out, t.Estimator.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	sent := t.Estimator.clock()
+	resp, err := next.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+
+	received := t.Estimator.clock()
+	if t.Sign != nil && t.rejected(resp) {
+		// A rejection may be caused by the skew, trust its Date alone
+		t.Estimator.reset(sent, received, resp.Header)
+	} else {
+		t.Estimator.Observe(sent, received, resp.Header)
+	}
+
+	return resp, nil
+}
+
+func (t Transport) rejected(resp *http.Response) bool {
+	if t.Rejected != nil {
+		return t.Rejected(resp)
+	}
+	return resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden
+}
@@ -0,0 +1,112 @@
+package skew
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/streadway/handy/signed"
+)
+
+func TestEstimatorObserve(t *testing.T) {
+	local := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	e := &Estimator{Clock: func() time.Time { return local }}
+
+	if e.Observe(local, local, http.Header{}) {
+		t.Fatalf("expected responses without Date to be ignored")
+	}
+
+	h := http.Header{"Date": {local.Add(time.Minute).Format(http.TimeFormat)}}
+	e.Observe(local, local.Add(200*time.Millisecond), h)
+
+	if off := e.Offset(); off < 59*time.Second || off > 61*time.Second {
+		t.Fatalf("expected an offset of about a minute, got %s", off)
+	}
+	if now := e.Now(); now.Sub(local.Add(time.Minute)) > time.Second {
+		t.Fatalf("expected the corrected time about a minute ahead, got %s", now)
+	}
+}
+
+func TestTransportSignsAgainAfterSkewedRejection(t *testing.T) {
+	const ahead = 10 * time.Minute
+
+	key := []byte("secret")
+	server := signed.Signer{Key: key, Now: func() time.Time { return time.Now().Add(ahead) }}
+
+	var attempts int
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.Header().Set("Date", server.Now().UTC().Format(http.TimeFormat))
+		if err := server.Verify(r); err != nil {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer s.Close()
+
+	est := &Estimator{}
+	trans := Transport{
+		Estimator: est,
+		Sign: func(req *http.Request, now time.Time) error {
+			client := signed.Signer{Key: key, Now: func() time.Time { return now }}
+			req.URL = client.Sign(req.URL, signed.Options{Expires: now.Add(time.Minute)})
+			return nil
+		},
+	}
+
+	u, _ := url.Parse(s.URL + "/resource")
+	req, _ := http.NewRequest("GET", u.String(), nil)
+
+	resp, err := trans.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if want, got := 200, resp.StatusCode; want != got {
+		t.Fatalf("expected %d after correcting the skew, got %d", want, got)
+	}
+	if want, got := 2, attempts; want != got {
+		t.Fatalf("expected %d attempts, got %d", want, got)
+	}
+
+	resp, _ = trans.RoundTrip(req)
+	resp.Body.Close()
+
+	if want, got := 3, attempts; want != got || resp.StatusCode != 200 {
+		t.Fatalf("expected later requests to be signed with the estimate, got %d attempts and %d", got, resp.StatusCode)
+	}
+}
+
+func TestTransportKeepsRejectionsWithoutSkew(t *testing.T) {
+	var attempts int
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer s.Close()
+
+	trans := Transport{
+		Estimator: &Estimator{},
+		Sign:      func(*http.Request, time.Time) error { return nil },
+	}
+
+	req, _ := http.NewRequest("GET", s.URL, nil)
+	resp, err := trans.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if want, got := 1, attempts; want != got {
+		t.Fatalf("expected rejections without skew to be returned after %d attempt, got %d", want, got)
+	}
+}
+
+func TestTransportRequiresEstimator(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://example/", nil)
+	if _, err := (Transport{}).RoundTrip(req); err != ErrNoEstimator {
+		t.Fatalf("expected ErrNoEstimator, got: %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Package spool buffers response bodies so that they can be read more than
+once, keeping small bodies in memory and spooling large ones to temporary
+files.
+*/
+package spool
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"runtime"
+	"sync"
+)
+
+// DefaultThreshold is the largest body kept in memory when not configured.
+const DefaultThreshold = 1 << 20
+
+// Body is a buffered response body.  Seek rewinds it to read it again, and
+// Close releases its buffer, removing its temporary file.
+type Body struct {
+	io.ReadSeeker
+	size int64
+
+	once sync.Once
+	file *os.File
+}
+
+// Size returns the length of the body.
+func (b *Body) Size() int64 {
+	return b.size
+}
+
+// Spooled reports whether the body is held in a temporary file.
+func (b *Body) Spooled() bool {
+	return b.file != nil
+}
+
+// Close removes the temporary file of a spooled body.
+func (b *Body) Close() error {
+	var err error
+	b.once.Do(func() {
+		if b.file == nil {
+			return
+		}
+		runtime.SetFinalizer(b, nil)
+		err = b.file.Close()
+		if rerr := os.Remove(b.file.Name()); err == nil {
+			err = rerr
+		}
+	})
+	return err
+}
+
+// New reads r into a Body, spooling it to a temporary file in dir once it
+// exceeds threshold bytes.  An empty dir uses os.TempDir.  The temporary
+// file is also removed when the Body is garbage collected without Close.
+func New(r io.Reader, threshold int64, dir string) (*Body, error) {
+	var head bytes.Buffer
+	n, err := io.Copy(&head, io.LimitReader(r, threshold+1))
+	if err != nil {
+		return nil, err
+	}
+	if n <= threshold {
+		return &Body{ReadSeeker: bytes.NewReader(head.Bytes()), size: n}, nil
+	}
+
+	file, err := os.CreateTemp(dir, "spool-")
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Body{ReadSeeker: file, file: file}
+	runtime.SetFinalizer(b, (*Body).Close)
+
+	if b.size, err = io.Copy(file, io.MultiReader(&head, r)); err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		b.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+// Transport is an http.RoundTripper buffering the response bodies into
+// Bodies, so that callers can read them again, for example to verify a
+// checksum before parsing, by asserting them to an io.ReadSeeker.  The
+// whole body is read before RoundTrip returns.  Informational and protocol
+// switch responses and event streams, which do not end, are passed through
+// unbuffered.
+type Transport struct {
+	// Threshold is the largest body kept in memory, DefaultThreshold when
+	// zero.
+	Threshold int64
+
+	// Dir holds the temporary files, os.TempDir when empty.
+	Dir string
+
+	// Next is the http.RoundTripper to which requests are forwarded.  If Next
+	// is nil, http.DefaultTransport is used.
+	Next http.RoundTripper
+}
+
+// RoundTrip implements the RoundTripper interface.
+func (t Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	resp, err := next.RoundTrip(req)
+	if err != nil || resp.Body == nil || resp.Body == http.NoBody || streaming(resp) {
+		return resp, err
+	}
+
+	threshold := t.Threshold
+	if threshold == 0 {
+		threshold = DefaultThreshold
+	}
+
+	body, err := New(resp.Body, threshold, t.Dir)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = body
+	resp.ContentLength = body.Size()
+	return resp, nil
+}
+
+// streaming reports whether the body of resp may not end.
+func streaming(resp *http.Response) bool {
+	if resp.StatusCode < 200 {
+		return true
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return mediaType == "text/event-stream"
+}
@@ -0,0 +1,101 @@
+package spool
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestNewKeepsSmallBodiesInMemory(t *testing.T) {
+	b, err := New(strings.NewReader("small"), 16, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	if b.Spooled() || b.Size() != 5 {
+		t.Fatalf("expected an in-memory body of 5 bytes, got spooled %v, size %d", b.Spooled(), b.Size())
+	}
+}
+
+func TestTransportSpoolsLargeBodies(t *testing.T) {
+	payload := strings.Repeat("0123456789", 1000)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(payload))
+	}))
+	defer s.Close()
+
+	dir := t.TempDir()
+	c := http.Client{Transport: Transport{Threshold: 1024, Dir: dir}}
+
+	resp, err := c.Get(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, ok := resp.Body.(*Body)
+	if !ok || !body.Spooled() {
+		t.Fatalf("expected a spooled body, got %T", resp.Body)
+	}
+	if want, got := int64(len(payload)), resp.ContentLength; want != got {
+		t.Fatalf("expected content length %d, got %d", want, got)
+	}
+
+	sum := sha256.New()
+	io.Copy(sum, resp.Body)
+
+	resp.Body.(io.Seeker).Seek(0, io.SeekStart)
+	again, _ := ioutil.ReadAll(resp.Body)
+
+	if want, got := fmt.Sprintf("%x", sha256.Sum256([]byte(payload))), fmt.Sprintf("%x", sum.Sum(nil)); want != got {
+		t.Fatalf("expected checksum %s, got %s", want, got)
+	}
+	if string(again) != payload {
+		t.Fatalf("expected to read the payload again")
+	}
+
+	if files, _ := os.ReadDir(dir); len(files) != 1 {
+		t.Fatalf("expected one spool file, got %d", len(files))
+	}
+	resp.Body.Close()
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Fatalf("expected Close to remove the spool file, got %d files", len(files))
+	}
+}
+
+func TestTransportPassesStreamsThrough(t *testing.T) {
+	for name, resp := range map[string]*http.Response{
+		"switching protocols": {StatusCode: http.StatusSwitchingProtocols, Header: http.Header{}},
+		"event stream":        {StatusCode: http.StatusOK, Header: http.Header{"Content-Type": {"text/event-stream; charset=utf-8"}}},
+	} {
+		// The body never ends, so spooling it would block
+		pr, pw := io.Pipe()
+		resp.Body = pr
+
+		trans := Transport{Next: roundTripFunc(func(*http.Request) (*http.Response, error) {
+			return resp, nil
+		})}
+
+		req, _ := http.NewRequest("GET", "http://example/", nil)
+		got, err := trans.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if _, spooled := got.Body.(*Body); spooled {
+			t.Errorf("%s: expected the body to be passed through", name)
+		}
+		pw.Close()
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
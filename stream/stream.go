@@ -0,0 +1,240 @@
+/*
+Package stream copies large streamed bodies with buffers adapting to the
+observed throughput, paced flushing and progress reporting.
+*/
+package stream
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Buffer sizes used by a Copier when not configured.
+const (
+	DefaultMinBuffer = 4 << 10
+	DefaultMaxBuffer = 256 << 10
+)
+
+// Progress reports the state of a transfer.
+type Progress struct {
+	// Written is the number of bytes transferred so far.
+	Written int64
+
+	// Total is the expected number of bytes, -1 when unknown.
+	Total int64
+
+	// Elapsed is the time since the transfer started.
+	Elapsed time.Duration
+
+	// Buffer is the current buffer size.
+	Buffer int
+}
+
+// Rate returns the average throughput in bytes per second.
+func (p Progress) Rate() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Written) / p.Elapsed.Seconds()
+}
+
+// Copier copies streams with a buffer growing while reads fill it and
+// shrinking while reads return little, between MinBuffer and MaxBuffer.
+// The buffer size learned by a transfer is the starting size of the next.
+// A Copier must not be copied after first use.
+type Copier struct {
+	// MinBuffer and MaxBuffer bound the buffer size, DefaultMinBuffer and
+	// DefaultMaxBuffer when zero.
+	MinBuffer int
+	MaxBuffer int
+
+	// FlushInterval paces flushing of destinations implementing
+	// http.Flusher.  When zero, only the end of a copy is flushed.  When
+	// negative, every write is flushed.
+	FlushInterval time.Duration
+
+	// OnProgress is called after every write with the progress of the
+	// transfer.
+	OnProgress func(Progress)
+
+	size int64 // learned buffer size
+	pool sync.Pool
+}
+
+func (c *Copier) bounds() (min, max int) {
+	min, max = c.MinBuffer, c.MaxBuffer
+	if min <= 0 {
+		min = DefaultMinBuffer
+	}
+	if max <= 0 {
+		max = DefaultMaxBuffer
+	}
+	if max < min {
+		max = min
+	}
+	return min, max
+}
+
+// adapt returns the buffer size to use after a read of n bytes into a
+// buffer of size bytes, and stores it for the next transfer.
+func (c *Copier) adapt(size, n int) int {
+	min, max := c.bounds()
+
+	switch {
+	case n == size && size < max:
+		size *= 2
+	case n < size/4 && size > min:
+		size /= 2
+	}
+	if size > max {
+		size = max
+	}
+	if size < min {
+		size = min
+	}
+
+	atomic.StoreInt64(&c.size, int64(size))
+	return size
+}
+
+// Buffer returns the current buffer size.
+func (c *Copier) Buffer() int {
+	min, max := c.bounds()
+	size := int(atomic.LoadInt64(&c.size))
+	if size < min {
+		return min
+	}
+	if size > max {
+		return max
+	}
+	return size
+}
+
+// Get returns a buffer of the current size, implementing
+// httputil.BufferPool.
+func (c *Copier) Get() []byte {
+	size := c.Buffer()
+	if b, ok := c.pool.Get().([]byte); ok && cap(b) >= size {
+		return b[:size]
+	}
+	return make([]byte, size)
+}
+
+// Put returns a buffer from Get for reuse, implementing httputil.BufferPool.
+func (c *Copier) Put(b []byte) {
+	c.pool.Put(b[:cap(b)])
+}
+
+// Copy copies from src to dst until EOF or an error, returning the number
+// of bytes written.
+func (c *Copier) Copy(dst io.Writer, src io.Reader) (int64, error) {
+	return c.copy(dst, src, -1)
+}
+
+// CopyResponse copies the body of resp to w, reporting the Content-Length
+// of resp as the Total of the progress.
+func (c *Copier) CopyResponse(w io.Writer, resp *http.Response) (int64, error) {
+	return c.copy(w, resp.Body, resp.ContentLength)
+}
+
+func (c *Copier) copy(dst io.Writer, src io.Reader, total int64) (int64, error) {
+	var (
+		buf     = c.Get()
+		start   = time.Now()
+		flushed = start
+		written int64
+		err     error
+	)
+	defer func() { c.Put(buf) }()
+
+	flusher, _ := dst.(http.Flusher)
+
+	for {
+		n, rerr := src.Read(buf)
+		if n > 0 {
+			m, werr := dst.Write(buf[:n])
+			written += int64(m)
+			if werr == nil && m < n {
+				werr = io.ErrShortWrite
+			}
+			if werr != nil {
+				err = werr
+				break
+			}
+
+			if flusher != nil && c.FlushInterval != 0 {
+				if now := time.Now(); c.FlushInterval < 0 || now.Sub(flushed) >= c.FlushInterval {
+					flusher.Flush()
+					flushed = now
+				}
+			}
+
+			if size := c.adapt(len(buf), n); size != len(buf) {
+				c.Put(buf)
+				buf = c.Get()
+			}
+
+			if c.OnProgress != nil {
+				c.OnProgress(Progress{
+					Written: written,
+					Total:   total,
+					Elapsed: time.Since(start),
+					Buffer:  len(buf),
+				})
+			}
+		}
+		if rerr != nil {
+			if rerr != io.EOF {
+				err = rerr
+			}
+			break
+		}
+	}
+
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	return written, err
+}
+
+// Body wraps the body of resp to report the progress and adapt the buffer
+// size while it is read by a copy loop of its own, like the one of
+// httputil.ReverseProxy using the Copier as its BufferPool.
+func (c *Copier) Body(resp *http.Response) io.ReadCloser {
+	return &body{
+		ReadCloser: resp.Body,
+		copier:     c,
+		total:      resp.ContentLength,
+		start:      time.Now(),
+	}
+}
+
+type body struct {
+	io.ReadCloser
+	copier  *Copier
+	total   int64
+	start   time.Time
+	written int64
+}
+
+func (b *body) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.written += int64(n)
+		size := b.copier.adapt(len(p), n)
+
+		if b.copier.OnProgress != nil {
+			b.copier.OnProgress(Progress{
+				Written: b.written,
+				Total:   b.total,
+				Elapsed: time.Since(b.start),
+				Buffer:  size,
+			})
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,104 @@
+package stream
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// chunks reads at most n bytes per Read.
+type chunks struct {
+	r io.Reader
+	n int
+}
+
+func (c chunks) Read(p []byte) (int, error) {
+	if len(p) > c.n {
+		p = p[:c.n]
+	}
+	return c.r.Read(p)
+}
+
+func TestCopyGrowsBufferForFastSources(t *testing.T) {
+	var (
+		payload = bytes.Repeat([]byte("x"), 1<<20)
+		dst     bytes.Buffer
+		sizes   []int
+		c       = &Copier{MaxBuffer: 64 << 10, OnProgress: func(p Progress) { sizes = append(sizes, p.Buffer) }}
+	)
+
+	n, err := c.Copy(&dst, bytes.NewReader(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := int64(len(payload)), n; want != got {
+		t.Fatalf("expected to copy %d bytes, got %d", want, got)
+	}
+	if !bytes.Equal(payload, dst.Bytes()) {
+		t.Fatalf("expected the payload to be copied unchanged")
+	}
+
+	var max int
+	for _, size := range sizes {
+		if size > max {
+			max = size
+		}
+	}
+	if want, got := 64<<10, max; want != got {
+		t.Fatalf("expected the buffer to grow to %d, got %d", want, got)
+	}
+	if c.Buffer() <= DefaultMinBuffer {
+		t.Fatalf("expected the next copy to start with a grown buffer, got %d", c.Buffer())
+	}
+}
+
+func TestCopyShrinksBufferForSlowSources(t *testing.T) {
+	c := &Copier{MinBuffer: 1 << 10}
+	c.adapt(32<<10, 32<<10)
+
+	_, err := c.Copy(io.Discard, chunks{strings.NewReader(strings.Repeat("x", 8<<10)), 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := 1<<10, c.Buffer(); want != got {
+		t.Fatalf("expected the buffer to shrink to %d, got %d", want, got)
+	}
+}
+
+func TestCopyFlushes(t *testing.T) {
+	for name, test := range map[string]struct {
+		interval time.Duration
+		flushes  bool
+	}{
+		"end only":    {0, true},
+		"every write": {-1, true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c := &Copier{FlushInterval: test.interval}
+
+			c.Copy(w, strings.NewReader("payload"))
+
+			if want, got := test.flushes, w.Flushed; want != got {
+				t.Fatalf("expected flushed %v, got %v", want, got)
+			}
+		})
+	}
+}
+
+func TestCopyResponseReportsTotal(t *testing.T) {
+	var last Progress
+	c := &Copier{OnProgress: func(p Progress) { last = p }}
+
+	resp := &http.Response{Body: io.NopCloser(strings.NewReader("payload")), ContentLength: 7}
+	c.CopyResponse(io.Discard, resp)
+
+	if want, got := (Progress{Written: 7, Total: 7}), (Progress{Written: last.Written, Total: last.Total}); want != got {
+		t.Fatalf("expected progress %+v, got %+v", want, got)
+	}
+}
@@ -0,0 +1,116 @@
+package transform
+
+import (
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+// Redacted replaces the values of redacted fields.
+const Redacted = "REDACTED"
+
+// Redact replaces the values of the named object fields with Redacted, at
+// any depth of a JSON document.  Field names match case insensitively.
+func Redact(fields ...string) Transformer {
+	redact := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		redact[strings.ToLower(f)] = true
+	}
+
+	return JSON(func(r *http.Request, v interface{}) (interface{}, error) {
+		return walk(v, func(key string, value interface{}) (string, interface{}) {
+			if redact[strings.ToLower(key)] {
+				return key, Redacted
+			}
+			return key, value
+		}), nil
+	})
+}
+
+// Envelope wraps a JSON document in an object under key, like
+// {"data": ...}.
+func Envelope(key string) Transformer {
+	return JSON(func(r *http.Request, v interface{}) (interface{}, error) {
+		return map[string]interface{}{key: v}, nil
+	})
+}
+
+// SnakeCase converts the object keys of a JSON document to snake_case, like
+// "userID" to "user_id".
+func SnakeCase() Transformer {
+	return renameKeys(snake)
+}
+
+// CamelCase converts the object keys of a JSON document to camelCase, like
+// "user_id" to "userId".
+func CamelCase() Transformer {
+	return renameKeys(camel)
+}
+
+func renameKeys(rename func(string) string) Transformer {
+	return JSON(func(r *http.Request, v interface{}) (interface{}, error) {
+		return walk(v, func(key string, value interface{}) (string, interface{}) {
+			return rename(key), value
+		}), nil
+	})
+}
+
+// walk applies f to the fields of all objects in v, depth first.
+func walk(v interface{}, f func(key string, value interface{}) (string, interface{})) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, value := range v {
+			k, value = f(k, walk(value, f))
+			out[k] = value
+		}
+		return out
+	case []interface{}:
+		for i := range v {
+			v[i] = walk(v[i], f)
+		}
+		return v
+	}
+	return v
+}
+
+func snake(s string) string {
+	var (
+		b     strings.Builder
+		runes = []rune(s)
+	)
+	for i, r := range runes {
+		if r == '-' || r == ' ' {
+			b.WriteByte('_')
+			continue
+		}
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+func camel(s string) string {
+	var (
+		b     strings.Builder
+		upper bool
+	)
+	for _, r := range s {
+		switch {
+		case (r == '_' || r == '-' || r == ' ') && b.Len() > 0:
+			upper = true
+		case upper:
+			b.WriteRune(unicode.ToUpper(r))
+			upper = false
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,57 @@
+package transform
+
+import (
+	"testing"
+)
+
+func transform(t *testing.T, tr Transformer, in string) string {
+	out, err := tr(nil, []byte(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestRedact(t *testing.T) {
+	got := transform(t, Redact("password", "token"), `{"user":{"name":"a","Password":"secret"},"tokens":[{"token":"t"}],"id":12345678901234567890}`)
+
+	if want := `{"id":12345678901234567890,"tokens":[{"token":"REDACTED"}],"user":{"Password":"REDACTED","name":"a"}}`; want != got {
+		t.Fatalf("want %s, got %s", want, got)
+	}
+}
+
+func TestEnvelope(t *testing.T) {
+	if want, got := `{"data":[1,2]}`, transform(t, Envelope("data"), `[1,2]`); want != got {
+		t.Fatalf("want %s, got %s", want, got)
+	}
+}
+
+func TestCase(t *testing.T) {
+	for in, want := range map[string]string{
+		"userID":     "user_id",
+		"HTTPServer": "http_server",
+		"firstName":  "first_name",
+		"v2Token":    "v2_token",
+		"already_ok": "already_ok",
+		"kebab-case": "kebab_case",
+	} {
+		if got := snake(in); want != got {
+			t.Errorf("snake(%q): want %q, got %q", in, want, got)
+		}
+	}
+
+	for in, want := range map[string]string{
+		"user_id":    "userId",
+		"first_name": "firstName",
+		"_private":   "_private",
+		"camelCase":  "camelCase",
+	} {
+		if got := camel(in); want != got {
+			t.Errorf("camel(%q): want %q, got %q", in, want, got)
+		}
+	}
+
+	if want, got := `{"outer_key":[{"inner_key":1}]}`, transform(t, SnakeCase(), `{"outerKey":[{"innerKey":1}]}`); want != got {
+		t.Fatalf("want %s, got %s", want, got)
+	}
+}
@@ -0,0 +1,201 @@
+/*
+Package transform rewrites response bodies with transformers registered by
+content type, like redacting fields, wrapping in an envelope or converting
+the case of JSON object keys.  Clients repair the responses of misbehaving
+upstreams with the Normalizers of a Transport.
+*/
+package transform
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/streadway/handy/problem"
+)
+
+// Transformer rewrites the body of a response to r.
+type Transformer func(r *http.Request, body []byte) ([]byte, error)
+
+// Chain applies the transformers in order.
+func Chain(ts ...Transformer) Transformer {
+	return func(r *http.Request, body []byte) ([]byte, error) {
+		var err error
+		for _, t := range ts {
+			if body, err = t(r, body); err != nil {
+				return nil, err
+			}
+		}
+		return body, nil
+	}
+}
+
+// JSON adapts a function over a decoded JSON document to a Transformer.
+// Numbers are decoded as json.Number to keep their precision.  Objects are
+// encoded with their keys sorted.
+func JSON(f func(r *http.Request, v interface{}) (interface{}, error)) Transformer {
+	return func(r *http.Request, body []byte) ([]byte, error) {
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.UseNumber()
+
+		var v interface{}
+		if err := dec.Decode(&v); err != nil {
+			return nil, err
+		}
+
+		v, err := f(r, v)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(v)
+	}
+}
+
+// Pipeline applies the transformers registered for the content type of
+// responses, safe for concurrent use.
+type Pipeline struct {
+	mu     sync.RWMutex
+	byType map[string]Transformer
+}
+
+// NewPipeline constructs a Pipeline without transformers.
+func NewPipeline() *Pipeline {
+	return &Pipeline{byType: make(map[string]Transformer)}
+}
+
+// Register appends transformers for a media type like "application/json".
+// Transformers for "application/json" also apply to structured syntax
+// suffixes like "application/vnd.example+json" without their own.
+func (p *Pipeline) Register(mediaType string, ts ...Transformer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	mediaType = strings.ToLower(mediaType)
+	if prev, ok := p.byType[mediaType]; ok {
+		ts = append([]Transformer{prev}, ts...)
+	}
+	p.byType[mediaType] = Chain(ts...)
+}
+
+// lookup returns the transformer for a Content-Type, or nil.
+func (p *Pipeline) lookup(contentType string) Transformer {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if t, ok := p.byType[mediaType]; ok {
+		return t
+	}
+	if i := strings.LastIndexByte(mediaType, '+'); i >= 0 {
+		return p.byType["application/"+mediaType[i+1:]]
+	}
+	return nil
+}
+
+// Middleware produces an http.Handler factory like Handler to be composed.
+func Middleware(p *Pipeline) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return Handler(p, next)
+	}
+}
+
+// Handler produces an http.Handler buffering the responses of next with a
+// registered content type to transform them.  Responses of other types,
+// without a body or with a Content-Encoding, and responses to HEAD requests
+// are passed through.  Failing
+// transformations respond with HTTP 500 written by problem.Write.
+func Handler(p *Pipeline, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bw := &bufferWriter{ResponseWriter: w, pipeline: p, head: r.Method == "HEAD"}
+		next.ServeHTTP(bw, r)
+
+		if bw.transform == nil {
+			if !bw.wroteHeader {
+				bw.WriteHeader(http.StatusOK)
+			}
+			return
+		}
+
+		if bw.buf.Len() == 0 {
+			w.WriteHeader(bw.code)
+			return
+		}
+
+		body, err := bw.transform(r, bw.buf.Bytes())
+		if err != nil {
+			w.Header().Del("Content-Length")
+			problem.Write(w, r, problem.Problem{
+				Status: http.StatusInternalServerError,
+				Detail: "response transformation failed",
+			})
+			return
+		}
+
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(bw.code)
+		w.Write(body)
+	})
+}
+
+// bufferWriter decides on the header whether to buffer the body for a
+// transformation or to pass it through.
+type bufferWriter struct {
+	http.ResponseWriter
+	pipeline    *Pipeline
+	head        bool
+	transform   Transformer
+	wroteHeader bool
+	code        int
+	buf         bytes.Buffer
+}
+
+func (w *bufferWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	// Informational responses precede the final one
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.wroteHeader = true
+	w.code = code
+
+	h := w.Header()
+	bodyless := code < 200 || code == http.StatusNoContent || code == http.StatusNotModified
+	if !bodyless && !w.head && h.Get("Content-Encoding") == "" {
+		w.transform = w.pipeline.lookup(h.Get("Content-Type"))
+	}
+
+	if w.transform == nil {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *bufferWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.transform == nil {
+		return w.ResponseWriter.Write(p)
+	}
+	return w.buf.Write(p)
+}
+
+// Flush implements http.Flusher for responses passed through.
+func (w *bufferWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok && w.transform == nil {
+		if !w.wroteHeader {
+			w.WriteHeader(http.StatusOK)
+		}
+		f.Flush()
+	}
+}
@@ -0,0 +1,150 @@
+package transform
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func respond(contentType, body string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(201)
+		io.WriteString(w, body)
+	})
+}
+
+func TestPipelineTransformsRegisteredTypes(t *testing.T) {
+	p := NewPipeline()
+	p.Register("application/json", Redact("secret"))
+	p.Register("application/json", Envelope("data"))
+
+	for contentType, want := range map[string]string{
+		"application/json; charset=utf-8": `{"data":{"secret":"REDACTED"}}`,
+		"application/vnd.example+json":    `{"data":{"secret":"REDACTED"}}`,
+		"text/plain":                      `{"secret":"s"}`,
+	} {
+		resp := httptest.NewRecorder()
+		Handler(p, respond(contentType, `{"secret":"s"}`)).ServeHTTP(resp, httptest.NewRequest("GET", "/", nil))
+
+		if want, got := 201, resp.Code; want != got {
+			t.Errorf("%s: expected status %d, got %d", contentType, want, got)
+		}
+		if got := resp.Body.String(); want != got {
+			t.Errorf("%s: want body %s, got %s", contentType, want, got)
+		}
+	}
+}
+
+func TestPipelineFailure(t *testing.T) {
+	p := NewPipeline()
+	p.Register("application/json", func(*http.Request, []byte) ([]byte, error) {
+		return nil, errors.New("broken")
+	})
+
+	resp := httptest.NewRecorder()
+	Handler(p, respond("application/json", `{}`)).ServeHTTP(resp, httptest.NewRequest("GET", "/", nil))
+
+	if want, got := http.StatusInternalServerError, resp.Code; want != got {
+		t.Fatalf("expected %d for a failed transformation, got %d", want, got)
+	}
+	if want, got := "application/problem+json", resp.Header().Get("Content-Type"); want != got {
+		t.Fatalf("expected a problem response, got %q", got)
+	}
+}
+
+func TestPipelinePassesHeadRequests(t *testing.T) {
+	p := NewPipeline()
+	p.Register("application/json", Envelope("data"))
+
+	const body = `{"secret":"s"}`
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(body))
+	})
+
+	resp := httptest.NewRecorder()
+	Handler(p, h).ServeHTTP(resp, httptest.NewRequest("HEAD", "/", nil))
+
+	if want, got := http.StatusOK, resp.Code; want != got {
+		t.Fatalf("expected status %d, got %d", want, got)
+	}
+	if want, got := strconv.Itoa(len(body)), resp.Header().Get("Content-Length"); want != got {
+		t.Fatalf("expected Content-Length %s of the untransformed body, got %s", want, got)
+	}
+}
+
+func TestPipelinePassesEmptyBodies(t *testing.T) {
+	p := NewPipeline()
+	p.Register("application/json", Envelope("data"))
+
+	resp := httptest.NewRecorder()
+	Handler(p, respond("application/json", "")).ServeHTTP(resp, httptest.NewRequest("GET", "/", nil))
+
+	if want, got := 201, resp.Code; want != got {
+		t.Fatalf("expected status %d, got %d", want, got)
+	}
+	if want, got := "application/json", resp.Header().Get("Content-Type"); want != got {
+		t.Fatalf("expected the response to be passed through, got %q", got)
+	}
+}
+
+func TestPipelinePassesThroughEncodedResponses(t *testing.T) {
+	p := NewPipeline()
+	p.Register("application/json", Envelope("data"))
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		io.WriteString(w, "compressed")
+	})
+
+	resp := httptest.NewRecorder()
+	Handler(p, h).ServeHTTP(resp, httptest.NewRequest("GET", "/", nil))
+
+	if want, got := "compressed", resp.Body.String(); want != got {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+}
+
+// statusRecorder records every status code written.
+type statusRecorder struct {
+	*httptest.ResponseRecorder
+	codes []int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.codes = append(r.codes, code)
+	if code >= 200 {
+		r.ResponseRecorder.WriteHeader(code)
+	}
+}
+
+func TestPipelinePassesEarlyHints(t *testing.T) {
+	p := NewPipeline()
+	p.Register("application/json", Envelope("data"))
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", "</style.css>; rel=preload")
+		w.WriteHeader(http.StatusEarlyHints)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, `{"id":1}`)
+	})
+
+	resp := &statusRecorder{ResponseRecorder: httptest.NewRecorder()}
+	Handler(p, next).ServeHTTP(resp, httptest.NewRequest("GET", "/", nil))
+
+	if want, got := "[103 404]", fmt.Sprint(resp.codes); want != got {
+		t.Fatalf("expected status codes %s, got %s", want, got)
+	}
+	if want, got := `{"data":{"id":1}}`, resp.Body.String(); want != got {
+		t.Fatalf("expected the final response to be transformed to %s, got %s", want, got)
+	}
+}
@@ -0,0 +1,124 @@
+package transform
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// Normalizer repairs a response of a misbehaving upstream before it is
+// returned to the caller.
+type Normalizer func(*http.Response) error
+
+// Transport is an http.RoundTripper applying the Normalizers configured for
+// the host of each request to its response.
+type Transport struct {
+	// Normalizers are applied in order by host.  Normalizers for the empty
+	// host apply to hosts without their own.
+	Normalizers map[string][]Normalizer
+
+	// Next is the http.RoundTripper to which requests are forwarded.  If Next
+	// is nil, http.DefaultTransport is used.
+	Next http.RoundTripper
+}
+
+// RoundTrip implements the RoundTripper interface.
+func (t Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	normalizers, ok := t.Normalizers[req.URL.Host]
+	if !ok {
+		normalizers = t.Normalizers[""]
+	}
+
+	for _, n := range normalizers {
+		if err := n(resp); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
+// peekBody is a response body that can be inspected before it is read.
+type peekBody struct {
+	*bufio.Reader
+	io.Closer
+}
+
+func peekable(resp *http.Response) *peekBody {
+	if b, ok := resp.Body.(*peekBody); ok {
+		return b
+	}
+	b := &peekBody{bufio.NewReader(resp.Body), resp.Body}
+	resp.Body = b
+	return b
+}
+
+var bom = []byte{0xef, 0xbb, 0xbf}
+
+// StripBOM removes a UTF-8 byte order mark from the start of the body.
+func StripBOM() Normalizer {
+	return func(resp *http.Response) error {
+		b := peekable(resp)
+		if prefix, _ := b.Peek(len(bom)); bytes.Equal(prefix, bom) {
+			b.Discard(len(bom))
+			if resp.ContentLength > 0 {
+				resp.ContentLength -= int64(len(bom))
+				resp.Header.Del("Content-Length")
+			}
+		}
+		return nil
+	}
+}
+
+// Charset sets the charset parameter of the Content-Type, for upstreams
+// announcing a different encoding than the one they send.
+func Charset(charset string) Normalizer {
+	return func(resp *http.Response) error {
+		mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+		if err != nil {
+			return nil
+		}
+		params["charset"] = charset
+		resp.Header.Set("Content-Type", mime.FormatMediaType(mediaType, params))
+		return nil
+	}
+}
+
+// CoerceJSON sets the Content-Type to application/json for text/plain and
+// text/html responses whose body starts like a JSON object or array.
+func CoerceJSON() Normalizer {
+	return func(resp *http.Response) error {
+		mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+		if err != nil || (mediaType != "text/plain" && mediaType != "text/html") {
+			return nil
+		}
+
+		b := peekable(resp)
+		for i := 1; ; i++ {
+			prefix, err := b.Peek(i)
+			if len(prefix) < i || err != nil {
+				return nil
+			}
+			switch prefix[i-1] {
+			case ' ', '\t', '\r', '\n':
+				continue
+			case '{', '[':
+				resp.Header.Set("Content-Type", mime.FormatMediaType("application/json", params))
+			}
+			return nil
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package transform
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTransportNormalizesPerHost(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=iso-8859-1")
+		io.WriteString(w, "\xef\xbb\xbf \n{\"ok\":true}")
+	}))
+	defer s.Close()
+
+	req, _ := http.NewRequest("GET", s.URL, nil)
+
+	trans := Transport{Normalizers: map[string][]Normalizer{
+		req.URL.Host: {StripBOM(), Charset("utf-8"), CoerceJSON()},
+	}}
+
+	resp, err := trans.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if want, got := "application/json; charset=utf-8", resp.Header.Get("Content-Type"); want != got {
+		t.Fatalf("want Content-Type %q, got %q", want, got)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	if want, got := " \n{\"ok\":true}", string(body); want != got {
+		t.Fatalf("want body %q, got %q", want, got)
+	}
+	if want, got := int64(len(body)), resp.ContentLength; want != got {
+		t.Fatalf("expected the content length %d, got %d", want, got)
+	}
+
+	other := Transport{Normalizers: map[string][]Normalizer{"elsewhere": {StripBOM()}}}
+	resp, err = other.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if body, _ := io.ReadAll(resp.Body); body[0] != 0xef {
+		t.Fatalf("expected responses of other hosts to pass unchanged")
+	}
+}
+
+func TestCoerceJSONIgnoresText(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, "plain {text}")
+	}))
+	defer s.Close()
+
+	resp, err := (&http.Client{Transport: Transport{Normalizers: map[string][]Normalizer{"": {CoerceJSON()}}}}).Get(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if want, got := "text/plain", resp.Header.Get("Content-Type"); want != got {
+		t.Fatalf("want Content-Type %q, got %q", want, got)
+	}
+}
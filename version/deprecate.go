@@ -0,0 +1,159 @@
+package version
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/streadway/handy/redact"
+)
+
+// Deprecation describes the lifecycle of a deprecated route.
+type Deprecation struct {
+	// Since is when the route was or will be deprecated.  When zero, the
+	// route is announced as deprecated without a date.
+	Since time.Time
+
+	// Sunset is when the route will become unavailable, zero when unknown.
+	Sunset time.Time
+
+	// Links are announced with their relation, like "deprecation" for the
+	// documentation or "successor-version" for the replacement.
+	Links map[string]string
+}
+
+// header sets the Deprecation, Sunset and Link headers announcing d.
+func (d Deprecation) header(h http.Header) {
+	if d.Since.IsZero() {
+		h.Set("Deprecation", "true")
+	} else {
+		h.Set("Deprecation", fmt.Sprintf("@%d", d.Since.Unix()))
+	}
+
+	if !d.Sunset.IsZero() {
+		h.Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+	}
+
+	rels := make([]string, 0, len(d.Links))
+	for rel := range d.Links {
+		rels = append(rels, rel)
+	}
+	sort.Strings(rels)
+
+	for _, rel := range rels {
+		h.Add("Link", fmt.Sprintf(`<%s>; rel="%s"`, d.Links[rel], rel))
+	}
+}
+
+// Registry holds the Deprecations of routes, safe for concurrent use.
+type Registry struct {
+	mu     sync.RWMutex
+	routes map[string]Deprecation
+}
+
+// NewRegistry constructs an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{routes: make(map[string]Deprecation)}
+}
+
+// Deprecate registers d for the route.  Routes are a path optionally
+// preceded by a method like "GET /v1/users".  Paths ending in a slash match
+// all paths below them.
+func (r *Registry) Deprecate(route string, d Deprecation) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes[route] = d
+}
+
+// Lookup returns the Deprecation of the most specific route matching req,
+// and whether there is one.  Routes with a method take precedence over
+// routes without one.
+func (r *Registry) Lookup(req *http.Request) (Deprecation, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	path := req.URL.Path
+	for {
+		if d, ok := r.routes[req.Method+" "+path]; ok {
+			return d, true
+		}
+		if d, ok := r.routes[path]; ok {
+			return d, true
+		}
+		if path == "" || path == "/" {
+			return Deprecation{}, false
+		}
+
+		// Continue with the parent, first as a subtree then without the slash
+		if strings.HasSuffix(path, "/") {
+			path = path[:len(path)-1]
+		}
+		path = path[:strings.LastIndex(path, "/")+1]
+	}
+}
+
+// Middleware announces the Deprecation registered for the route of each
+// request in the response headers.
+func Middleware(registry *Registry) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if d, ok := registry.Lookup(r); ok {
+				d.header(w.Header())
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Logger is satisfied by *log.Logger.
+type Logger interface {
+	Printf(string, ...interface{})
+}
+
+// maxNotices bounds the endpoints remembered by LogNotices, which start over
+// once exceeded.
+const maxNotices = 1024
+
+// LogNotices returns a Transport.OnNotice logging each Notice to logger,
+// once per method, host and path.  URLs are logged redacted, see
+// redact.Query.
+func LogNotices(logger Logger) func(Notice) {
+	var (
+		mu   sync.Mutex
+		seen = make(map[string]bool)
+	)
+
+	return func(n Notice) {
+		u := n.Request.URL
+		key := n.Request.Method + " " + u.Host + u.Path
+
+		mu.Lock()
+		logged := seen[key]
+		if !logged {
+			if len(seen) >= maxNotices {
+				seen = make(map[string]bool)
+			}
+			seen[key] = true
+		}
+		mu.Unlock()
+
+		if logged {
+			return
+		}
+
+		msg := n.Request.Method + " " + redact.URL(u, redact.Query) + " is deprecated"
+		if !n.Deprecation.IsZero() {
+			msg += " since " + n.Deprecation.Format(time.RFC3339)
+		}
+		if !n.Sunset.IsZero() {
+			msg += ", sunset at " + n.Sunset.Format(time.RFC3339)
+		}
+		if succ, ok := n.Links["successor-version"]; ok {
+			msg += ", successor: " + succ
+		}
+		logger.Printf("[WARN] %s", msg)
+	}
+}
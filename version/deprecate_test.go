@@ -0,0 +1,89 @@
+package version
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type logs []string
+
+func (l *logs) Printf(format string, v ...interface{}) {
+	*l = append(*l, fmt.Sprintf(format, v...))
+}
+
+func TestRegistryLookup(t *testing.T) {
+	reg := NewRegistry()
+	reg.Deprecate("/v1/", Deprecation{Links: map[string]string{"successor-version": "/v2/"}})
+	reg.Deprecate("DELETE /v1/users", Deprecation{Sunset: time.Unix(1, 0)})
+	reg.Deprecate("/v2/legacy", Deprecation{})
+
+	for route, want := range map[string]string{
+		"GET /v1/users/42":  "/v2/",
+		"GET /v1/users":     "/v2/",
+		"DELETE /v1/users":  "sunset",
+		"GET /v2/legacy":    "deprecated",
+		"GET /v2/legacy/42": "",
+		"GET /v2/users":     "",
+	} {
+		parts := strings.SplitN(route, " ", 2)
+		req := httptest.NewRequest(parts[0], parts[1], nil)
+
+		d, ok := reg.Lookup(req)
+		var got string
+		switch {
+		case !ok:
+		case d.Links != nil:
+			got = d.Links["successor-version"]
+		case !d.Sunset.IsZero():
+			got = "sunset"
+		default:
+			got = "deprecated"
+		}
+
+		if want != got {
+			t.Errorf("%s: want %q, got %q", route, want, got)
+		}
+	}
+}
+
+func TestMiddlewareAnnouncesToTransport(t *testing.T) {
+	reg := NewRegistry()
+	reg.Deprecate("/v1/", Deprecation{
+		Since:  time.Unix(1688169599, 0),
+		Sunset: time.Date(2026, 11, 11, 23, 59, 59, 0, time.UTC),
+		Links:  map[string]string{"successor-version": "https://example.org/v2"},
+	})
+
+	s := httptest.NewServer(Middleware(reg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	defer s.Close()
+
+	var l logs
+	client := &http.Client{Transport: &Transport{OnNotice: LogNotices(&l)}}
+
+	for _, path := range []string{"/v1/users?token=s3cret", "/v1/users?page=2", "/v2/users"} {
+		resp, err := client.Get(s.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	if len(l) != 1 {
+		t.Fatalf("expected one log line for the repeated deprecated route, got: %q", l)
+	}
+
+	for _, want := range []string{
+		"GET " + s.URL + "/v1/users?token=REDACTED is deprecated",
+		"since 2023-06-30T23:59:59Z",
+		"sunset at 2026-11-11T23:59:59Z",
+		"successor: https://example.org/v2",
+	} {
+		if !strings.Contains(l[0], want) {
+			t.Errorf("expected log %q to contain %q", l[0], want)
+		}
+	}
+}
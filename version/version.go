@@ -0,0 +1,162 @@
+/*
+Package version negotiates API versions with upstreams and surfaces their
+Deprecation and Sunset announcements.  Servers announce the lifecycle of
+their own deprecated routes with a Registry and Middleware.
+*/
+package version
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/streadway/handy/atomic"
+)
+
+// Notice is an announcement by an upstream that a resource is deprecated or
+// will be removed.
+type Notice struct {
+	// Request the announcement was made for.
+	Request *http.Request
+
+	// Deprecated is true when the Deprecation header is present.
+	Deprecated bool
+
+	// Deprecation is when the resource was or will be deprecated, zero when
+	// not announced.
+	Deprecation time.Time
+
+	// Sunset is when the resource will become unavailable, zero when not
+	// announced.
+	Sunset time.Time
+
+	// Links are the targets of Link headers with the relations
+	// "deprecation", "sunset" and "successor-version", by relation.
+	Links map[string]string
+}
+
+// ParseNotice returns the Notice announced by the response headers, and
+// whether there is one.
+func ParseNotice(req *http.Request, h http.Header) (Notice, bool) {
+	n := Notice{Request: req}
+
+	if v := strings.TrimSpace(h.Get("Deprecation")); v != "" {
+		n.Deprecated = true
+		n.Deprecation = parseDate(v)
+	}
+
+	if v := strings.TrimSpace(h.Get("Sunset")); v != "" {
+		n.Sunset = parseDate(v)
+	}
+
+	for _, link := range h["Link"] {
+		for _, l := range strings.Split(link, ",") {
+			target, rel := parseLink(l)
+			switch rel {
+			case "deprecation", "sunset", "successor-version":
+				if n.Links == nil {
+					n.Links = make(map[string]string)
+				}
+				n.Links[rel] = target
+			}
+		}
+	}
+
+	return n, n.Deprecated || !n.Sunset.IsZero()
+}
+
+// parseDate parses structured field dates like "@1688169599" and HTTP dates,
+// returning the zero time for other values like the legacy "true".
+func parseDate(v string) time.Time {
+	if strings.HasPrefix(v, "@") {
+		if s, err := strconv.ParseInt(v[1:], 10, 64); err == nil {
+			return time.Unix(s, 0).UTC()
+		}
+		return time.Time{}
+	}
+	t, _ := http.ParseTime(v)
+	return t
+}
+
+func parseLink(link string) (target, rel string) {
+	parts := strings.Split(link, ";")
+	target = strings.Trim(strings.TrimSpace(parts[0]), "<>")
+	for _, p := range parts[1:] {
+		kv := strings.SplitN(strings.TrimSpace(p), "=", 2)
+		if len(kv) == 2 && strings.EqualFold(kv[0], "rel") {
+			rel = strings.ToLower(strings.Trim(kv[1], `"`))
+		}
+	}
+	return target, rel
+}
+
+// Transport is an http.RoundTripper setting the version headers configured
+// for each upstream host and reporting the Notices in responses.
+type Transport struct {
+	// Headers are set on requests by host.  Headers for the empty host apply
+	// to hosts without their own headers.  Headers already present on a
+	// request are kept.
+	Headers map[string]http.Header
+
+	// OnNotice is called with each Notice.
+	OnNotice func(Notice)
+
+	// Next is the http.RoundTripper to which requests are forwarded.  If Next
+	// is nil, http.DefaultTransport is used.
+	Next http.RoundTripper
+
+	deprecated atomic.Int
+	sunset     atomic.Int
+}
+
+// RoundTrip implements the RoundTripper interface.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	headers, ok := t.Headers[req.URL.Host]
+	if !ok {
+		headers = t.Headers[""]
+	}
+
+	if len(headers) > 0 {
+		req = req.Clone(req.Context())
+		for k, v := range headers {
+			if req.Header.Get(k) == "" {
+				req.Header[http.CanonicalHeaderKey(k)] = append([]string(nil), v...)
+			}
+		}
+	}
+
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	if n, ok := ParseNotice(req, resp.Header); ok {
+		if n.Deprecated {
+			t.deprecated.Add(1)
+		}
+		if !n.Sunset.IsZero() {
+			t.sunset.Add(1)
+		}
+		if t.OnNotice != nil {
+			t.OnNotice(n)
+		}
+	}
+
+	return resp, nil
+}
+
+// Deprecated returns the number of responses announcing a deprecation.
+func (t *Transport) Deprecated() int64 {
+	return t.deprecated.Get()
+}
+
+// Sunsets returns the number of responses announcing a sunset.
+func (t *Transport) Sunsets() int64 {
+	return t.sunset.Get()
+}
@@ -0,0 +1,82 @@
+package version
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTransportSetsHeadersAndReportsNotices(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Accept"); got != "application/vnd.example.v2+json" {
+			t.Errorf("expected versioned media type, got %q", got)
+		}
+		w.Header().Set("Deprecation", "@1688169599")
+		w.Header().Set("Sunset", "Wed, 11 Nov 2026 23:59:59 GMT")
+		w.Header().Add("Link", `<https://example.org/v3>; rel="successor-version", <https://example.org/docs>; rel="deprecation"`)
+	}))
+	defer s.Close()
+
+	var notices []Notice
+	trans := &Transport{
+		Headers:  map[string]http.Header{"": {"Accept": {"application/vnd.example.v2+json"}}},
+		OnNotice: func(n Notice) { notices = append(notices, n) },
+	}
+
+	resp, err := (&http.Client{Transport: trans}).Get(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if len(notices) != 1 {
+		t.Fatalf("expected one notice, got %d", len(notices))
+	}
+
+	n := notices[0]
+	if want := time.Unix(1688169599, 0).UTC(); !n.Deprecated || !n.Deprecation.Equal(want) {
+		t.Errorf("expected deprecation at %v, got %v", want, n.Deprecation)
+	}
+	if want := time.Date(2026, 11, 11, 23, 59, 59, 0, time.UTC); !n.Sunset.Equal(want) {
+		t.Errorf("expected sunset at %v, got %v", want, n.Sunset)
+	}
+	if want, got := "https://example.org/v3", n.Links["successor-version"]; want != got {
+		t.Errorf("expected successor %q, got %q", want, got)
+	}
+	if trans.Deprecated() != 1 || trans.Sunsets() != 1 {
+		t.Errorf("expected counters to record the notice, got %d deprecated and %d sunsets", trans.Deprecated(), trans.Sunsets())
+	}
+}
+
+func TestParseNoticeWithoutAnnouncement(t *testing.T) {
+	if _, ok := ParseNotice(nil, http.Header{"Link": {`<https://example.org>; rel="next"`}}); ok {
+		t.Fatalf("expected no notice without Deprecation or Sunset")
+	}
+}
+
+func TestTransportCopiesHeaders(t *testing.T) {
+	headers := http.Header{"Accept": {"application/vnd.example.v2+json"}}
+	trans := &Transport{
+		Headers: map[string]http.Header{"": headers},
+		Next: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			req.Header.Add("Accept", "application/json")
+			return &http.Response{StatusCode: 200, Header: http.Header{}, Body: http.NoBody}, nil
+		}),
+	}
+
+	req, _ := http.NewRequest("GET", "http://example/", nil)
+	if _, err := trans.RoundTrip(req); err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := 1, len(headers["Accept"]); want != got {
+		t.Fatalf("expected the configured headers to be left alone with %d value, got %d", want, got)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,153 @@
+package warmup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Prewarm establishes n connections to each host through
+// http.DefaultTransport, see Prewarmer.
+func Prewarm(ctx context.Context, hosts []string, n int) error {
+	return Prewarmer{}.Prewarm(ctx, hosts, n)
+}
+
+// PrewarmTask returns a Task running Prewarm, to hold a Gate until the
+// connections are established.
+func PrewarmTask(hosts []string, n int) Task {
+	return Prewarmer{}.Task(hosts, n)
+}
+
+// Prewarmer establishes connections and parks them idle in a Transport, so
+// that the first requests after a deploy do not pay for dialing and TLS
+// handshakes.
+type Prewarmer struct {
+	// Transport dials the connections with its dialer and TLS settings, and
+	// parks at most its MaxIdleConnsPerHost connections per host; raise it
+	// for an http.Transport.  If Transport is nil, http.DefaultTransport is
+	// used.
+	Transport http.RoundTripper
+}
+
+// Prewarm establishes n connections to each host.  Hosts are URLs like
+// "https://api.example.com", or host names and ports dialed with https.
+// Each connection is opened with a HEAD request to the root path, whose
+// response status is ignored.
+func (p Prewarmer) Prewarm(ctx context.Context, hosts []string, n int) error {
+	if n < 0 {
+		return fmt.Errorf("prewarm: negative number of connections %d", n)
+	}
+	if n == 0 {
+		return nil
+	}
+
+	rt := p.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+
+	for _, host := range hosts {
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			if err := prewarm(ctx, rt, host, n); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("prewarm %s: %w", host, err))
+				mu.Unlock()
+			}
+		}(host)
+	}
+
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Task returns a Task running Prewarm, to hold a Gate until the connections
+// are established.
+func (p Prewarmer) Task(hosts []string, n int) Task {
+	return func(ctx context.Context) error {
+		return p.Prewarm(ctx, hosts, n)
+	}
+}
+
+func prewarm(ctx context.Context, rt http.RoundTripper, host string, n int) error {
+	if !strings.Contains(host, "://") {
+		host = "https://" + host
+	}
+	root, err := url.Parse(host)
+	if err != nil {
+		return err
+	}
+	root.Path, root.RawPath = "/", ""
+
+	// Hold every connection until all are established, so that the
+	// requests cannot share a connection
+	var (
+		arrived sync.WaitGroup
+		all     = make(chan struct{})
+		wg      sync.WaitGroup
+		errs    = make([]error, n)
+	)
+	arrived.Add(n)
+	go func() {
+		arrived.Wait()
+		close(all)
+	}()
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			var once sync.Once
+			arrive := func() { once.Do(arrived.Done) }
+			defer arrive()
+
+			trace := &httptrace.ClientTrace{
+				GotConn: func(httptrace.GotConnInfo) {
+					arrive()
+					select {
+					case <-all:
+					case <-ctx.Done():
+					}
+				},
+			}
+
+			req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), "HEAD", root.String(), nil)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+
+			resp, err := rt.RoundTrip(req)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}(i)
+	}
+
+	wg.Wait()
+
+	// Report one error per host, most likely the same for all connections
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
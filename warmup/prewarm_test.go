@@ -0,0 +1,83 @@
+package warmup
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPrewarmParksConnections(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		conns = make(map[string]bool)
+	)
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "HEAD" && r.URL.Path != "/" {
+			t.Errorf("expected the root path, got %q", r.URL.Path)
+		}
+	}))
+	s.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			mu.Lock()
+			conns[c.RemoteAddr().String()] = true
+			mu.Unlock()
+		}
+	}
+	s.StartTLS()
+	defer s.Close()
+
+	trans := s.Client().Transport.(*http.Transport)
+	trans.MaxIdleConnsPerHost = 4
+
+	if err := (Prewarmer{Transport: trans}).Prewarm(context.Background(), []string{s.URL + "/"}, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	dialed := len(conns)
+	mu.Unlock()
+
+	if want, got := 3, dialed; want != got {
+		t.Fatalf("expected %d connections established, got %d", want, got)
+	}
+
+	// Requests after prewarming reuse the parked connections
+	var reused int32
+	trace := &httptrace.ClientTrace{GotConn: func(info httptrace.GotConnInfo) {
+		if info.Reused {
+			atomic.AddInt32(&reused, 1)
+		}
+	}}
+	req, _ := http.NewRequestWithContext(httptrace.WithClientTrace(context.Background(), trace), "GET", s.URL, nil)
+	resp, err := trans.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if reused != 1 {
+		t.Fatalf("expected the request to reuse a prewarmed connection")
+	}
+}
+
+func TestPrewarmReportsHosts(t *testing.T) {
+	err := Prewarm(context.Background(), []string{"http://127.0.0.1:1"}, 2)
+	if err == nil || !strings.Contains(err.Error(), "prewarm http://127.0.0.1:1") {
+		t.Fatalf("expected the unreachable host to be reported, got: %v", err)
+	}
+}
+
+func TestPrewarmCounts(t *testing.T) {
+	if err := Prewarm(context.Background(), []string{"http://127.0.0.1:1"}, 0); err != nil {
+		t.Fatalf("expected no connections to be a no-op, got: %v", err)
+	}
+	if err := Prewarm(context.Background(), []string{"http://127.0.0.1:1"}, -1); err == nil {
+		t.Fatalf("expected a negative number of connections to fail")
+	}
+}
@@ -0,0 +1,177 @@
+/*
+Package warmup gates outbound requests until registered warmup tasks, like
+fetching tokens or warming caches, have completed.
+*/
+package warmup
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Task performs one warmup step.
+type Task func(context.Context) error
+
+// NotReadyError is returned by a fail fast Transport while the Gate has
+// pending tasks.
+type NotReadyError struct {
+	Pending []string
+}
+
+func (e NotReadyError) Error() string {
+	return fmt.Sprintf("warmup pending: %s", strings.Join(e.Pending, ", "))
+}
+
+// Temporary reports the error as temporary, so retry.Temporary retries it.
+func (e NotReadyError) Temporary() bool {
+	return true
+}
+
+// Gate opens once all registered tasks have completed successfully.
+type Gate struct {
+	mu      sync.Mutex
+	pending map[string]Task
+	ready   chan struct{}
+}
+
+// NewGate constructs a Gate without tasks.  A Gate without tasks opens on
+// the first call to Start.
+func NewGate() *Gate {
+	return &Gate{
+		pending: make(map[string]Task),
+		ready:   make(chan struct{}),
+	}
+}
+
+// Register adds a named task to complete before the Gate opens.  Tasks
+// registered after the Gate opened are ignored.
+func (g *Gate) Register(name string, task Task) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	select {
+	case <-g.ready:
+		return
+	default:
+	}
+
+	g.pending[name] = task
+}
+
+// Start runs the pending tasks concurrently and waits for them.  The Gate
+// opens when all tasks succeed.  Failed tasks stay pending and are run again
+// by the next call to Start; the first failure is returned.
+func (g *Gate) Start(ctx context.Context) error {
+	g.mu.Lock()
+	tasks := make(map[string]Task, len(g.pending))
+	for name, task := range g.pending {
+		tasks[name] = task
+	}
+	g.mu.Unlock()
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		first error
+	)
+
+	for name, task := range tasks {
+		wg.Add(1)
+		go func(name string, task Task) {
+			defer wg.Done()
+
+			if err := task(ctx); err != nil {
+				mu.Lock()
+				if first == nil {
+					first = fmt.Errorf("warmup %s: %w", name, err)
+				}
+				mu.Unlock()
+				return
+			}
+
+			g.mu.Lock()
+			delete(g.pending, name)
+			g.mu.Unlock()
+		}(name, task)
+	}
+
+	wg.Wait()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if len(g.pending) == 0 {
+		select {
+		case <-g.ready:
+		default:
+			close(g.ready)
+		}
+	}
+
+	return first
+}
+
+// Ready returns a channel that is closed when the Gate opens.
+func (g *Gate) Ready() <-chan struct{} {
+	return g.ready
+}
+
+// Wait blocks until the Gate opens or the context is done.
+func (g *Gate) Wait(ctx context.Context) error {
+	select {
+	case <-g.ready:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Pending returns the sorted names of the tasks that have not completed.
+func (g *Gate) Pending() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	names := make([]string, 0, len(g.pending))
+	for name := range g.pending {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Transport is an http.RoundTripper that holds requests until the Gate
+// opens, or fails them fast with a NotReadyError.
+type Transport struct {
+	// Gate guards the requests.
+	Gate *Gate
+
+	// FailFast returns a NotReadyError instead of waiting for the Gate.
+	FailFast bool
+
+	// Next is the http.RoundTripper to which requests are forwarded.  If Next
+	// is nil, http.DefaultTransport is used.
+	Next http.RoundTripper
+}
+
+// RoundTrip implements the RoundTripper interface.
+func (t Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	select {
+	case <-t.Gate.Ready():
+	default:
+		if t.FailFast {
+			return nil, NotReadyError{Pending: t.Gate.Pending()}
+		}
+		if err := t.Gate.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+	}
+
+	if t.Next != nil {
+		return t.Next.RoundTrip(req)
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
@@ -0,0 +1,88 @@
+package warmup
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGateOpensAfterTasksSucceed(t *testing.T) {
+	g := NewGate()
+
+	fail := true
+	g.Register("token", func(context.Context) error { return nil })
+	g.Register("cache", func(context.Context) error {
+		if fail {
+			return errors.New("cold")
+		}
+		return nil
+	})
+
+	if err := g.Start(context.Background()); err == nil {
+		t.Fatalf("expected the failing task to be reported")
+	}
+
+	if want, got := []string{"cache"}, g.Pending(); len(got) != 1 || got[0] != want[0] {
+		t.Fatalf("expected pending tasks %v, got %v", want, got)
+	}
+
+	fail = false
+	if err := g.Start(context.Background()); err != nil {
+		t.Fatalf("expected tasks to succeed, got: %v", err)
+	}
+
+	select {
+	case <-g.Ready():
+	default:
+		t.Fatalf("expected gate to be open")
+	}
+}
+
+func TestTransportFailsFastBeforeReady(t *testing.T) {
+	g := NewGate()
+	g.Register("token", func(context.Context) error { return nil })
+
+	req, _ := http.NewRequest("GET", "http://example/test", nil)
+	_, err := Transport{Gate: g, FailFast: true}.RoundTrip(req)
+
+	notReady, ok := err.(NotReadyError)
+	if !ok {
+		t.Fatalf("expected a NotReadyError, got: %v", err)
+	}
+	if len(notReady.Pending) != 1 || notReady.Pending[0] != "token" {
+		t.Fatalf("expected the pending task to be reported, got: %v", notReady.Pending)
+	}
+}
+
+func TestTransportHoldsUntilReady(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer s.Close()
+
+	g := NewGate()
+	g.Register("token", func(context.Context) error { return nil })
+
+	done := make(chan error)
+	go func() {
+		req, _ := http.NewRequest("GET", s.URL, nil)
+		resp, err := Transport{Gate: g}.RoundTrip(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		done <- err
+	}()
+
+	select {
+	case <-done:
+		t.Fatalf("expected request to be held before the gate opens")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	g.Start(context.Background())
+
+	if err := <-done; err != nil {
+		t.Fatalf("expected request to pass after the gate opened, got: %v", err)
+	}
+}
@@ -0,0 +1,304 @@
+/*
+Package webhook verifies the signatures of incoming webhook deliveries and
+protects against replayed deliveries.
+*/
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrMissingSignature is returned when a delivery carries no signature.
+	ErrMissingSignature = errors.New("webhook: missing signature")
+	// ErrInvalidSignature is returned when no signature matches the payload.
+	ErrInvalidSignature = errors.New("webhook: invalid signature")
+	// ErrExpired is returned when a timestamped delivery falls outside of
+	// the tolerance window.
+	ErrExpired = errors.New("webhook: timestamp outside of tolerance")
+	// ErrReplayed is returned when a delivery has been seen before.
+	ErrReplayed = errors.New("webhook: replayed delivery")
+	// ErrTooLarge is returned when a payload exceeds the MaxBytes limit.
+	ErrTooLarge = errors.New("webhook: payload too large")
+)
+
+// DefaultTolerance is the accepted age of timestamped deliveries when not
+// configured.
+const DefaultTolerance = 5 * time.Minute
+
+// Scheme verifies the signature of a delivery and returns a nonce
+// identifying it for replay protection.  An empty nonce disables replay
+// protection for the delivery.
+type Scheme interface {
+	Verify(r *http.Request, payload []byte) (nonce string, err error)
+}
+
+// GitHub verifies the X-Hub-Signature-256 header of GitHub deliveries.  The
+// nonce is a digest of the signed payload, as the X-GitHub-Delivery header is
+// not signed.  GitHub signs no timestamp either, so the NonceTTL must cover
+// the whole window in which deliveries are accepted.
+type GitHub struct {
+	Secret []byte
+}
+
+// Verify implements Scheme.
+func (s GitHub) Verify(r *http.Request, payload []byte) (string, error) {
+	sig := r.Header.Get("X-Hub-Signature-256")
+	if sig == "" {
+		return "", ErrMissingSignature
+	}
+	if !strings.HasPrefix(sig, "sha256=") || !validMAC(sha256.New, s.Secret, payload, sig[len("sha256="):]) {
+		return "", ErrInvalidSignature
+	}
+	return digest(payload), nil
+}
+
+// Stripe verifies the Stripe-Signature header of Stripe deliveries, which
+// signs a timestamp along with the payload.  The nonce is the timestamp and
+// signature.
+type Stripe struct {
+	Secret []byte
+
+	// Tolerance is the accepted age of a delivery, DefaultTolerance when
+	// zero.
+	Tolerance time.Duration
+
+	// Now returns the current time, time.Now when nil.
+	Now func() time.Time
+}
+
+// Verify implements Scheme.
+func (s Stripe) Verify(r *http.Request, payload []byte) (string, error) {
+	header := r.Header.Get("Stripe-Signature")
+	if header == "" {
+		return "", ErrMissingSignature
+	}
+
+	var (
+		timestamp string
+		sigs      []string
+	)
+	for _, pair := range strings.Split(header, ",") {
+		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "t":
+			timestamp = kv[1]
+		case "v1":
+			sigs = append(sigs, kv[1])
+		}
+	}
+
+	if timestamp == "" || len(sigs) == 0 {
+		return "", ErrMissingSignature
+	}
+
+	signed := append([]byte(timestamp+"."), payload...)
+	for _, sig := range sigs {
+		if !validMAC(sha256.New, s.Secret, signed, sig) {
+			continue
+		}
+
+		t, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return "", ErrInvalidSignature
+		}
+		if !withinTolerance(time.Unix(t, 0), s.Tolerance, s.Now) {
+			return "", ErrExpired
+		}
+		return timestamp + "." + strings.ToLower(sig), nil
+	}
+
+	return "", ErrInvalidSignature
+}
+
+// HMAC verifies a hex encoded HMAC of the payload in Header, optionally
+// after a Prefix like "sha256=".  The nonce is a digest of the signed
+// payload, so the NonceTTL must cover the whole window in which deliveries
+// are accepted.
+type HMAC struct {
+	Secret []byte
+
+	// Hash is the HMAC hash function, sha256.New when nil.
+	Hash func() hash.Hash
+
+	// Header carries the signature.
+	Header string
+
+	// Prefix is stripped from the signature before comparison.
+	Prefix string
+}
+
+// Verify implements Scheme.
+func (s HMAC) Verify(r *http.Request, payload []byte) (string, error) {
+	sig := r.Header.Get(s.Header)
+	if sig == "" {
+		return "", ErrMissingSignature
+	}
+
+	h := s.Hash
+	if h == nil {
+		h = sha256.New
+	}
+
+	if !strings.HasPrefix(sig, s.Prefix) || !validMAC(h, s.Secret, payload, sig[len(s.Prefix):]) {
+		return "", ErrInvalidSignature
+	}
+	return digest(payload), nil
+}
+
+// digest identifies a payload by its SHA-256.
+func digest(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+func validMAC(h func() hash.Hash, secret, payload []byte, sig string) bool {
+	want, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(h, secret)
+	mac.Write(payload)
+	return hmac.Equal(want, mac.Sum(nil))
+}
+
+func withinTolerance(t time.Time, tolerance time.Duration, now func() time.Time) bool {
+	if tolerance == 0 {
+		tolerance = DefaultTolerance
+	}
+	if now == nil {
+		now = time.Now
+	}
+	age := now().Sub(t)
+	return age <= tolerance && age >= -tolerance
+}
+
+// NonceStore remembers the nonces of verified deliveries.
+type NonceStore interface {
+	// Seen records the nonce until expiry and reports whether it was
+	// already recorded.
+	Seen(nonce string, expiry time.Time) bool
+}
+
+// MemoryNonceStore is a NonceStore kept in memory, safe for concurrent use.
+type MemoryNonceStore struct {
+	mu     sync.Mutex
+	nonces map[string]time.Time
+
+	// Now returns the current time, time.Now when nil.
+	Now func() time.Time
+}
+
+// Seen implements NonceStore.  Expired nonces are pruned on each call.
+func (s *MemoryNonceStore) Seen(nonce string, expiry time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.Now != nil {
+		now = s.Now()
+	}
+
+	if s.nonces == nil {
+		s.nonces = make(map[string]time.Time)
+	}
+
+	for n, e := range s.nonces {
+		if !now.Before(e) {
+			delete(s.nonces, n)
+		}
+	}
+
+	if _, ok := s.nonces[nonce]; ok {
+		return true
+	}
+	s.nonces[nonce] = expiry
+	return false
+}
+
+// Config parameterizes the webhook Middleware.
+type Config struct {
+	// Scheme verifies deliveries.
+	Scheme Scheme
+
+	// Nonces protects against replays when set.
+	Nonces NonceStore
+
+	// NonceTTL is how long nonces are remembered, DefaultTolerance when zero.
+	NonceTTL time.Duration
+
+	// MaxBytes limits the payload size, 1MB when zero.  Larger payloads fail
+	// with ErrTooLarge.
+	MaxBytes int64
+}
+
+// Verify reads the payload of r, verifies it with cfg and restores the body
+// for the next handler.
+func Verify(cfg Config, r *http.Request) error {
+	limit := cfg.MaxBytes
+	if limit == 0 {
+		limit = 1 << 20
+	}
+
+	payload, err := ioutil.ReadAll(io.LimitReader(r.Body, limit+1))
+	r.Body.Close()
+	if err != nil {
+		return err
+	}
+	if int64(len(payload)) > limit {
+		return ErrTooLarge
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(payload))
+
+	nonce, err := cfg.Scheme.Verify(r, payload)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Nonces != nil && nonce != "" {
+		ttl := cfg.NonceTTL
+		if ttl == 0 {
+			ttl = DefaultTolerance
+		}
+		if cfg.Nonces.Seen(nonce, time.Now().Add(ttl)) {
+			return ErrReplayed
+		}
+	}
+
+	return nil
+}
+
+// Middleware returns a composable middleware rejecting unverified deliveries
+// with "401 Unauthorized", replayed deliveries with "409 Conflict" and too
+// large payloads with "413 Request Entity Too Large".
+func Middleware(cfg Config) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch err := Verify(cfg, r); err {
+			case nil:
+				next.ServeHTTP(w, r)
+			case ErrReplayed:
+				http.Error(w, err.Error(), http.StatusConflict)
+			case ErrTooLarge:
+				http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			default:
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+			}
+		})
+	}
+}
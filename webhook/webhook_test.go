@@ -0,0 +1,130 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var secret = []byte("secret")
+
+func sign(payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func delivery(payload string, header http.Header) *http.Request {
+	r := httptest.NewRequest("POST", "http://example.org/hook", strings.NewReader(payload))
+	for k, v := range header {
+		r.Header[k] = v
+	}
+	return r
+}
+
+func TestGitHubWithReplayProtection(t *testing.T) {
+	const payload = `{"action":"opened"}`
+
+	var body string
+	h := Middleware(Config{Scheme: GitHub{Secret: secret}, Nonces: &MemoryNonceStore{}})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := ioutil.ReadAll(r.Body)
+			body = string(b)
+		}),
+	)
+
+	header := http.Header{
+		"X-Hub-Signature-256": {"sha256=" + sign(payload)},
+		"X-Github-Delivery":   {"1"},
+	}
+
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, delivery(payload, header))
+	if want, got := http.StatusOK, resp.Code; want != got {
+		t.Fatalf("expected %d for a valid delivery, got %d", want, got)
+	}
+	if body != payload {
+		t.Fatalf("expected the payload to be restored, got %q", body)
+	}
+
+	// The delivery header is not signed and may be changed by replays
+	header.Set("X-Github-Delivery", "2")
+
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, delivery(payload, header))
+	if want, got := http.StatusConflict, resp.Code; want != got {
+		t.Fatalf("expected %d for a replayed delivery, got %d", want, got)
+	}
+
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, delivery(`{"action":"closed"}`, http.Header{
+		"X-Hub-Signature-256": {"sha256=" + sign(payload)},
+		"X-Github-Delivery":   {"3"},
+	}))
+	if want, got := http.StatusUnauthorized, resp.Code; want != got {
+		t.Fatalf("expected %d for a tampered delivery, got %d", want, got)
+	}
+}
+
+func TestStripeTolerance(t *testing.T) {
+	const payload = `{"type":"charge.succeeded"}`
+
+	now := time.Unix(10000, 0)
+	scheme := Stripe{Secret: secret, Tolerance: time.Minute, Now: func() time.Time { return now }}
+
+	for name, it := range map[string]struct {
+		timestamp time.Time
+		want      error
+	}{
+		"fresh":   {now.Add(-30 * time.Second), nil},
+		"expired": {now.Add(-2 * time.Minute), ErrExpired},
+	} {
+		ts := strconv.FormatInt(it.timestamp.Unix(), 10)
+		r := delivery(payload, http.Header{
+			"Stripe-Signature": {"t=" + ts + ",v1=deadbeef,v1=" + sign(ts+"."+payload)},
+		})
+
+		if _, got := scheme.Verify(r, []byte(payload)); it.want != got {
+			t.Errorf("%s: want %v, got %v", name, it.want, got)
+		}
+	}
+}
+
+func TestHMAC(t *testing.T) {
+	const payload = "hello"
+
+	scheme := HMAC{Secret: secret, Header: "X-Signature"}
+
+	if _, err := scheme.Verify(delivery(payload, http.Header{"X-Signature": {sign(payload)}}), []byte(payload)); err != nil {
+		t.Fatalf("expected a valid signature, got %v", err)
+	}
+	if _, err := scheme.Verify(delivery(payload, nil), []byte(payload)); err != ErrMissingSignature {
+		t.Fatalf("expected %v, got %v", ErrMissingSignature, err)
+	}
+}
+
+func TestMaxBytes(t *testing.T) {
+	const payload = "hello"
+
+	h := Middleware(Config{Scheme: HMAC{Secret: secret, Header: "X-Signature"}, MaxBytes: int64(len(payload))})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+	)
+
+	for it, want := range map[string]int{
+		payload:       http.StatusOK,
+		payload + "!": http.StatusRequestEntityTooLarge,
+	} {
+		resp := httptest.NewRecorder()
+		h.ServeHTTP(resp, delivery(it, http.Header{"X-Signature": {sign(it)}}))
+		if got := resp.Code; want != got {
+			t.Errorf("%q: want %d, got %d", it, want, got)
+		}
+	}
+}